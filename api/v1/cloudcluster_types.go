@@ -0,0 +1,87 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// CloudClusterSpec identifies a cluster of a cloud provider.
+// CloudClusters are created and updated by kubedeck from the provider API.
+type CloudClusterSpec struct {
+	// Provider is the cloud provider name (timeweb, yandex, openstack, selectel, fake, capi).
+	Provider string `json:"provider"`
+	// ClusterID is the cluster ID in the provider.
+	ClusterID string `json:"clusterID"`
+}
+
+// CloudClusterStatus is the cluster state last reported by the provider.
+type CloudClusterStatus struct {
+	// DisplayName is the cluster name in the provider.
+	// +optional
+	DisplayName string `json:"displayName,omitempty"`
+
+	// Phase is the cluster status reported by the provider.
+	// +optional
+	Phase string `json:"phase,omitempty"`
+
+	// Preset is the control plane preset ID, if the provider publishes presets.
+	// +optional
+	Preset string `json:"preset,omitempty"`
+
+	// HourlyCost is the hourly cost of the control plane, if known.
+	// +optional
+	HourlyCost string `json:"hourlyCost,omitempty"`
+
+	// NodeGroups is the number of node groups in the cluster.
+	// +optional
+	NodeGroups int32 `json:"nodeGroups,omitempty"`
+
+	// LastSyncTime is the time the status was last synced from the provider.
+	// +optional
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Provider",type=string,JSONPath=`.spec.provider`
+// +kubebuilder:printcolumn:name="Cluster ID",type=string,JSONPath=`.spec.clusterID`
+// +kubebuilder:printcolumn:name="Name",type=string,JSONPath=`.status.displayName`
+// +kubebuilder:printcolumn:name="Phase",type=string,JSONPath=`.status.phase`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// CloudCluster is the Schema for the cloudclusters API.
+type CloudCluster struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   CloudClusterSpec   `json:"spec,omitempty"`
+	Status CloudClusterStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// CloudClusterList contains a list of CloudCluster.
+type CloudClusterList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []CloudCluster `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&CloudCluster{}, &CloudClusterList{})
+}
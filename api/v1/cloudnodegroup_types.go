@@ -0,0 +1,107 @@
+/*
+Copyright 2025.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package v1
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// CloudNodeGroupSpec identifies a node group of a cloud provider and its desired size.
+// CloudNodeGroups are created by kubedeck from the provider API; only DesiredNodeCount is meant to be edited.
+type CloudNodeGroupSpec struct {
+	NodeGroupReference `json:",inline"`
+
+	// DesiredNodeCount is the node group size kubedeck keeps through the provider.
+	// The group is not resized while it is unset.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	DesiredNodeCount *int32 `json:"desiredNodeCount,omitempty"`
+}
+
+// CloudNodeGroupStatus is the node group state last reported by the provider.
+type CloudNodeGroupStatus struct {
+	// DisplayName is the node group name in the provider.
+	// +optional
+	DisplayName string `json:"displayName,omitempty"`
+
+	// NodeCount is the current size of the node group.
+	// +optional
+	NodeCount int32 `json:"nodeCount"`
+
+	// Preset is the node preset ID, if the provider publishes presets.
+	// +optional
+	Preset string `json:"preset,omitempty"`
+
+	// HourlyCost is the hourly cost of the node group, if known.
+	// +optional
+	HourlyCost string `json:"hourlyCost,omitempty"`
+
+	// NodeLabels are the labels of Nodes that belong to the group.
+	// +optional
+	NodeLabels map[string]string `json:"nodeLabels,omitempty"`
+
+	// LastSyncTime is the time the status was last synced from the provider.
+	// +optional
+	LastSyncTime *metav1.Time `json:"lastSyncTime,omitempty"`
+
+	// AppliedNodeCount is the desired size kubedeck last tried to apply.
+	// +optional
+	AppliedNodeCount *int32 `json:"appliedNodeCount,omitempty"`
+
+	// LastScaleTime is the time the node group was last resized to DesiredNodeCount.
+	// +optional
+	LastScaleTime *metav1.Time `json:"lastScaleTime,omitempty"`
+
+	// LastResult describes the outcome of the last resize.
+	// +optional
+	LastResult string `json:"lastResult,omitempty"`
+
+	// LastError is the error of the last resize.
+	// +optional
+	LastError string `json:"lastError,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="Provider",type=string,JSONPath=`.spec.provider`
+// +kubebuilder:printcolumn:name="Cluster ID",type=string,JSONPath=`.spec.clusterID`
+// +kubebuilder:printcolumn:name="Name",type=string,JSONPath=`.status.displayName`
+// +kubebuilder:printcolumn:name="Desired",type=integer,JSONPath=`.spec.desiredNodeCount`
+// +kubebuilder:printcolumn:name="Nodes",type=integer,JSONPath=`.status.nodeCount`
+// +kubebuilder:printcolumn:name="Age",type=date,JSONPath=`.metadata.creationTimestamp`
+
+// CloudNodeGroup is the Schema for the cloudnodegroups API.
+type CloudNodeGroup struct {
+	metav1.TypeMeta   `json:",inline"`
+	metav1.ObjectMeta `json:"metadata,omitempty"`
+
+	Spec   CloudNodeGroupSpec   `json:"spec,omitempty"`
+	Status CloudNodeGroupStatus `json:"status,omitempty"`
+}
+
+// +kubebuilder:object:root=true
+
+// CloudNodeGroupList contains a list of CloudNodeGroup.
+type CloudNodeGroupList struct {
+	metav1.TypeMeta `json:",inline"`
+	metav1.ListMeta `json:"metadata,omitempty"`
+	Items           []CloudNodeGroup `json:"items"`
+}
+
+func init() {
+	SchemeBuilder.Register(&CloudNodeGroup{}, &CloudNodeGroupList{})
+}
@@ -30,12 +30,104 @@ type KubedeckSpec struct {
 
 	// Foo is an example field of Kubedeck. Edit kubedeck_types.go to remove/update
 	Foo string `json:"foo,omitempty"`
+
+	// ScalingSchedules resize cloud node groups on a cron schedule.
+	// +optional
+	ScalingSchedules []ScalingSchedule `json:"scalingSchedules,omitempty"`
+}
+
+// ScalingScheduleAction is what a scaling schedule does when it fires.
+// +kubebuilder:validation:Enum=Scale;Restore
+type ScalingScheduleAction string
+
+const (
+	// ScalingScheduleActionScale sets the node group size to NodeCount.
+	ScalingScheduleActionScale ScalingScheduleAction = "Scale"
+	// ScalingScheduleActionRestore returns the node group to its size before the first scheduled Scale.
+	ScalingScheduleActionRestore ScalingScheduleAction = "Restore"
+)
+
+// NodeGroupReference identifies a node group of a cloud provider.
+type NodeGroupReference struct {
+	// Provider is the cloud provider name (timeweb, yandex, openstack, selectel, fake, capi).
+	Provider string `json:"provider"`
+	// ClusterID is the cluster ID in the provider.
+	ClusterID string `json:"clusterID"`
+	// NodeGroupID is the node group ID in the provider.
+	NodeGroupID string `json:"nodeGroupID"`
+}
+
+// ScalingSchedule resizes a node group on a cron schedule.
+type ScalingSchedule struct {
+	// Name identifies the schedule in status and notifications.
+	Name string `json:"name"`
+
+	// Schedule is a standard five-field cron expression or a descriptor such as @daily.
+	Schedule string `json:"schedule"`
+
+	// TimeZone is the IANA time zone the schedule is evaluated in. Defaults to UTC.
+	// +optional
+	TimeZone string `json:"timeZone,omitempty"`
+
+	// NodeGroup is the node group to resize.
+	NodeGroup NodeGroupReference `json:"nodeGroup"`
+
+	// Action is Scale or Restore.
+	// +kubebuilder:default=Scale
+	// +optional
+	Action ScalingScheduleAction `json:"action,omitempty"`
+
+	// NodeCount is the target size for the Scale action.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	NodeCount *int32 `json:"nodeCount,omitempty"`
+
+	// Suspend disables the schedule without removing it.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
 }
 
 // KubedeckStatus defines the observed state of Kubedeck.
 type KubedeckStatus struct {
-	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
-	// Important: Run "make" to regenerate code after modifying this file
+	// Schedules is the last execution of each scaling schedule.
+	// +optional
+	Schedules []ScalingScheduleStatus `json:"schedules,omitempty"`
+
+	// ScaledNodeGroups are node groups resized by a schedule and not restored yet.
+	// +optional
+	ScaledNodeGroups []ScaledNodeGroup `json:"scaledNodeGroups,omitempty"`
+}
+
+// ScalingScheduleStatus is the observed state of a scaling schedule.
+type ScalingScheduleStatus struct {
+	// Name of the schedule.
+	Name string `json:"name"`
+
+	// LastScheduleTime is the time the schedule last fired.
+	// +optional
+	LastScheduleTime *metav1.Time `json:"lastScheduleTime,omitempty"`
+
+	// LastResult describes the outcome of the last run.
+	// +optional
+	LastResult string `json:"lastResult,omitempty"`
+
+	// LastError is the error of the last run or of parsing the schedule.
+	// +optional
+	LastError string `json:"lastError,omitempty"`
+}
+
+// ScaledNodeGroup remembers the size of a node group before a scheduled Scale.
+type ScaledNodeGroup struct {
+	NodeGroupReference `json:",inline"`
+
+	// PreviousNodeCount is the size to restore.
+	PreviousNodeCount int32 `json:"previousNodeCount"`
+
+	// Schedule is the name of the schedule that resized the group first.
+	Schedule string `json:"schedule"`
+
+	// ScaledAt is the time of that resize.
+	ScaledAt metav1.Time `json:"scaledAt"`
 }
 
 // +kubebuilder:object:root=true
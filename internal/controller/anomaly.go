@@ -0,0 +1,455 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Виды аномалий использования ресурсов
+const (
+	anomalySpike      = "spike"
+	anomalyMemoryLeak = "memory_leak"
+	anomalyIdle       = "idle"
+)
+
+// Уровни важности находок, как у статусов рекомендаций
+const (
+	severityCritical = "critical"
+	severityWarning  = "warning"
+	severityInfo     = "info"
+)
+
+// severityRank упорядочивает уровни важности
+var severityRank = map[string]int{severityInfo: 0, severityWarning: 1, severityCritical: 2}
+
+const (
+	// Нижние границы стандартного отклонения базовой линии: на ровном ряду z-оценка иначе бесконечна
+	minSpikeStdCPU    = 0.01
+	minSpikeStdMemory = 8 * mebibyte
+	// Всплеск меньше этих значений не считается аномалией при любой z-оценке
+	minSpikeDeltaCPU    = 0.05
+	minSpikeDeltaMemory = 64 * mebibyte
+	// leakSegments - на сколько интервалов делится ряд памяти для проверки монотонного роста
+	leakSegments = 6
+	// leakMinR2 - минимальный коэффициент детерминации линейного тренда утечки
+	leakMinR2 = 0.8
+	// leakMinGrowth и leakMinGrowthMi - минимальный рост памяти за окно: доля и абсолютное значение
+	leakMinGrowth   = 0.1
+	leakMinGrowthMi = 32
+	// idleMinCoverage - доля окна простоя, которую должна покрывать история нагрузки
+	idleMinCoverage = 0.9
+)
+
+// AnomalyFinding - аномалия использования ресурсов контейнера или рабочей нагрузки.
+// CPU в ядрах, память в байтах.
+type AnomalyFinding struct {
+	Kind         string    `json:"kind"`
+	Severity     string    `json:"severity"`
+	Namespace    string    `json:"namespace"`
+	WorkloadKind string    `json:"workloadKind,omitempty"`
+	Workload     string    `json:"workload,omitempty"`
+	Pod          string    `json:"pod,omitempty"`
+	Container    string    `json:"container,omitempty"`
+	Resource     string    `json:"resource"`
+	Value        float64   `json:"value"`
+	Baseline     float64   `json:"baseline"`
+	Score        float64   `json:"score"`
+	Since        time.Time `json:"since"`
+	Message      string    `json:"message"`
+}
+
+// AnomalyReport - ответ /analyze/anomalies
+type AnomalyReport struct {
+	Message  string           `json:"message"`
+	From     time.Time        `json:"from"`
+	To       time.Time        `json:"to"`
+	Findings []AnomalyFinding `json:"findings"`
+}
+
+// anomalySettings - параметры детектора аномалий
+type anomalySettings struct {
+	// window - период истории для базовой линии всплесков и тренда утечек
+	window time.Duration
+	// spikeWindow - последние точки, которые сравниваются с базовой линией
+	spikeWindow time.Duration
+	// zScore - z-оценка всплеска для warning, удвоенная - для critical
+	zScore float64
+	// leakHorizon - утечка critical, если по тренду память достигнет лимита раньше
+	leakHorizon time.Duration
+	// idleWindow и idleCPU - нагрузка простаивает, если за idleWindow ее CPU не превышал idleCPU ядер
+	idleWindow time.Duration
+	idleCPU    float64
+	// minSamples - минимум точек ряда для анализа
+	minSamples int
+}
+
+func anomalySettingsFromEnv() anomalySettings {
+	return anomalySettings{
+		window:      getEnvDuration("KUBEDECK_ANOMALY_WINDOW", 24*time.Hour),
+		spikeWindow: getEnvDuration("KUBEDECK_ANOMALY_SPIKE_WINDOW", 15*time.Minute),
+		zScore:      getEnvFloat("KUBEDECK_ANOMALY_ZSCORE", 4),
+		leakHorizon: getEnvDuration("KUBEDECK_ANOMALY_LEAK_HORIZON", 24*time.Hour),
+		idleWindow:  getEnvDuration("KUBEDECK_ANOMALY_IDLE_WINDOW", 72*time.Hour),
+		idleCPU:     getEnvFloat("KUBEDECK_ANOMALY_IDLE_CPU", 0.005),
+		minSamples:  int(getEnvFloat("KUBEDECK_ANOMALY_MIN_SAMPLES", 30)),
+	}
+}
+
+// containerSeries - ряд истории контейнера пода
+type containerSeries struct {
+	namespace, pod, container string
+	points                    []HistoryPoint
+}
+
+// meanStd возвращает среднее и стандартное отклонение
+func meanStd(values []float64) (float64, float64) {
+	if len(values) == 0 {
+		return 0, 0
+	}
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	mean := sum / float64(len(values))
+	var variance float64
+	for _, v := range values {
+		variance += (v - mean) * (v - mean)
+	}
+	return mean, math.Sqrt(variance / float64(len(values)))
+}
+
+// linearTrend возвращает наклон, сдвиг и коэффициент детерминации R² линейной регрессии y по x
+func linearTrend(x, y []float64) (slope, intercept, r2 float64) {
+	meanX, _ := meanStd(x)
+	meanY, _ := meanStd(y)
+	var sxx, sxy, syy float64
+	for i := range x {
+		dx, dy := x[i]-meanX, y[i]-meanY
+		sxx += dx * dx
+		sxy += dx * dy
+		syy += dy * dy
+	}
+	if sxx == 0 {
+		return 0, meanY, 0
+	}
+	slope = sxy / sxx
+	intercept = meanY - slope*meanX
+	if syy == 0 {
+		return slope, intercept, 0
+	}
+	return slope, intercept, sxy * sxy / (sxx * syy)
+}
+
+// anomalies находит аномалии использования ресурсов по истории метрик без обращения к LLM
+func (r *KubedeckReconciler) anomalies(ctx context.Context, settings anomalySettings, now time.Time) (*AnomalyReport, error) {
+	if r.history == nil {
+		return nil, errors.New("metrics history is disabled, anomaly detection needs KUBEDECK_METRICS_HISTORY")
+	}
+
+	pods, err := r.podsSnapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	replicaSets, err := r.replicaSetsByName(ctx, corev1.NamespaceAll)
+	if err != nil {
+		return nil, err
+	}
+
+	series, err := r.containerSeries(now.Add(-settings.window), now)
+	if err != nil {
+		return nil, err
+	}
+	workloads, err := r.workloadUsage(pods, replicaSets, now.Add(-settings.idleWindow), now)
+	if err != nil {
+		return nil, err
+	}
+	return settings.detect(series, workloads, pods, replicaSets, now), nil
+}
+
+// containerSeries возвращает ряды контейнеров за [from, to], упорядоченные по ключу и времени
+func (r *KubedeckReconciler) containerSeries(from, to time.Time) ([]*containerSeries, error) {
+	tier := r.history.selectTier(from, 0, to)
+	prefix := historyKindContainer + "/"
+	byKey := map[string]*containerSeries{}
+	err := r.history.scan(tier, from, to, func(key string) bool { return strings.HasPrefix(key, prefix) },
+		func(key string, p HistoryPoint) {
+			s, ok := byKey[key]
+			if !ok {
+				parts := strings.SplitN(strings.TrimPrefix(key, prefix), "/", 3)
+				if len(parts) != 3 {
+					return
+				}
+				s = &containerSeries{namespace: parts[0], pod: parts[1], container: parts[2]}
+				byKey[key] = s
+			}
+			s.points = append(s.points, p)
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(byKey))
+	for key := range byKey {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	result := make([]*containerSeries, 0, len(keys))
+	for _, key := range keys {
+		s := byKey[key]
+		sort.Slice(s.points, func(i, j int) bool { return s.points[i].Time.Before(s.points[j].Time) })
+		result = append(result, s)
+	}
+	return result, nil
+}
+
+// detect проверяет ряды контейнеров на всплески и утечки памяти, а нагрузки - на простой.
+// Находки упорядочены по важности, namespace и имени.
+func (s anomalySettings) detect(series []*containerSeries, workloads []*workloadUsage, pods []corev1.Pod,
+	replicaSets map[string]appsv1.ReplicaSet, now time.Time) *AnomalyReport {
+	podsByName := make(map[string]corev1.Pod, len(pods))
+	for _, pod := range pods {
+		podsByName[pod.Namespace+"/"+pod.Name] = pod
+	}
+
+	findings := []AnomalyFinding{}
+	for _, cs := range series {
+		var limits corev1.ResourceList
+		base := AnomalyFinding{Namespace: cs.namespace, Pod: cs.pod, Container: cs.container}
+		if pod, ok := podsByName[cs.namespace+"/"+cs.pod]; ok {
+			base.WorkloadKind, base.Workload = workloadOf(pod, replicaSets)
+			for _, container := range pod.Spec.Containers {
+				if container.Name == cs.container {
+					limits = container.Resources.Limits
+				}
+			}
+		}
+		findings = append(findings, s.spikes(cs, base, limits, now)...)
+		if finding, ok := s.memoryLeak(cs, base, limits); ok {
+			findings = append(findings, finding)
+		}
+	}
+	for _, w := range workloads {
+		if finding, ok := s.idle(w, now); ok {
+			findings = append(findings, finding)
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		a, b := findings[i], findings[j]
+		if severityRank[a.Severity] != severityRank[b.Severity] {
+			return severityRank[a.Severity] > severityRank[b.Severity]
+		}
+		if a.Namespace != b.Namespace {
+			return a.Namespace < b.Namespace
+		}
+		if a.Workload != b.Workload {
+			return a.Workload < b.Workload
+		}
+		return a.Pod < b.Pod
+	})
+
+	counts := map[string]int{}
+	for _, f := range findings {
+		counts[f.Severity]++
+	}
+	return &AnomalyReport{
+		Message: fmt.Sprintf("Анализ истории использования: найдено %d аномалий (критических: %d, предупреждений: %d, "+
+			"информационных: %d).", len(findings), counts[severityCritical], counts[severityWarning], counts[severityInfo]),
+		From:     now.Add(-max(s.window, s.idleWindow)).UTC(),
+		To:       now.UTC(),
+		Findings: findings,
+	}
+}
+
+// spikes сравнивает максимум последних spikeWindow точек ряда со средним и отклонением предыдущих.
+// Всплеск памяти до nearLimitRatio лимита или с удвоенной z-оценкой - critical.
+func (s anomalySettings) spikes(cs *containerSeries, base AnomalyFinding, limits corev1.ResourceList, now time.Time) []AnomalyFinding {
+	recentFrom := now.Add(-s.spikeWindow)
+	split := sort.Search(len(cs.points), func(i int) bool { return cs.points[i].Time.After(recentFrom) })
+	if split < s.minSamples || split == len(cs.points) {
+		return nil
+	}
+
+	var findings []AnomalyFinding
+	for _, resource := range []struct {
+		name            corev1.ResourceName
+		value           func(HistoryPoint) float64
+		minStd, minDiff float64
+	}{
+		{corev1.ResourceCPU, func(p HistoryPoint) float64 { return p.CPU }, minSpikeStdCPU, minSpikeDeltaCPU},
+		{corev1.ResourceMemory, func(p HistoryPoint) float64 { return p.Memory }, minSpikeStdMemory, minSpikeDeltaMemory},
+	} {
+		baseline := make([]float64, split)
+		for i, p := range cs.points[:split] {
+			baseline[i] = resource.value(p)
+		}
+		mean, std := meanStd(baseline)
+
+		peak := cs.points[split]
+		for _, p := range cs.points[split+1:] {
+			if resource.value(p) > resource.value(peak) {
+				peak = p
+			}
+		}
+		value := resource.value(peak)
+		z := (value - mean) / max(std, resource.minStd)
+		if z < s.zScore || value-mean < resource.minDiff {
+			continue
+		}
+
+		finding := base
+		finding.Kind, finding.Resource = anomalySpike, string(resource.name)
+		finding.Value, finding.Baseline, finding.Score, finding.Since = value, mean, z, peak.Time.UTC()
+		finding.Severity = severityWarning
+		limit, hasLimit := limits[resource.name]
+		if z >= 2*s.zScore || (resource.name == corev1.ResourceMemory && hasLimit && value >= nearLimitRatio*limit.AsApproximateFloat64()) {
+			finding.Severity = severityCritical
+		}
+		finding.Message = fmt.Sprintf("Всплеск %s контейнера %s/%s/%s: %s при среднем %s (z-оценка %.1f).",
+			resource.name, cs.namespace, cs.pod, cs.container, formatUsage(resource.name, value),
+			formatUsage(resource.name, mean), z)
+		findings = append(findings, finding)
+	}
+	return findings
+}
+
+// memoryLeak ищет устойчивый рост памяти: средние по leakSegments интервалам строго растут,
+// линейный тренд объясняет ряд (R² не ниже leakMinR2), а рост заметен. Если по тренду память
+// достигнет лимита раньше leakHorizon, утечка critical.
+func (s anomalySettings) memoryLeak(cs *containerSeries, base AnomalyFinding, limits corev1.ResourceList) (AnomalyFinding, bool) {
+	n := len(cs.points)
+	if n < max(s.minSamples, leakSegments*2) {
+		return AnomalyFinding{}, false
+	}
+	start := cs.points[0].Time
+	span := cs.points[n-1].Time.Sub(start)
+	if span < s.window/4 {
+		return AnomalyFinding{}, false
+	}
+
+	segments := make([][]float64, leakSegments)
+	x, y := make([]float64, n), make([]float64, n)
+	for i, p := range cs.points {
+		x[i], y[i] = p.Time.Sub(start).Hours(), p.Memory
+		segment := min(int(p.Time.Sub(start)*leakSegments/(span+1)), leakSegments-1)
+		segments[segment] = append(segments[segment], p.Memory)
+	}
+	var previous float64
+	for i, segment := range segments {
+		mean, _ := meanStd(segment)
+		if len(segment) == 0 || (i > 0 && mean <= previous) {
+			return AnomalyFinding{}, false
+		}
+		previous = mean
+	}
+
+	slope, intercept, r2 := linearTrend(x, y)
+	first, last := intercept, intercept+slope*x[n-1]
+	growth := last - first
+	if slope <= 0 || r2 < leakMinR2 || growth < leakMinGrowthMi*mebibyte || growth < leakMinGrowth*first {
+		return AnomalyFinding{}, false
+	}
+
+	finding := base
+	finding.Kind, finding.Resource, finding.Severity = anomalyMemoryLeak, string(corev1.ResourceMemory), severityWarning
+	finding.Value, finding.Baseline, finding.Score, finding.Since = last, first, r2, start.UTC()
+	finding.Message = fmt.Sprintf("Память контейнера %s/%s/%s растет с %s до %s за %s (%s/ч, R² %.2f).",
+		cs.namespace, cs.pod, cs.container, formatUsage(corev1.ResourceMemory, first), formatUsage(corev1.ResourceMemory, last),
+		span.Round(time.Minute), formatUsage(corev1.ResourceMemory, slope), r2)
+	if limit, ok := limits[corev1.ResourceMemory]; ok {
+		untilLimit := time.Duration((limit.AsApproximateFloat64() - last) / slope * float64(time.Hour))
+		if untilLimit < s.leakHorizon {
+			finding.Severity = severityCritical
+		}
+		finding.Message += fmt.Sprintf(" Лимит %s будет достигнут примерно через %s.",
+			formatUsage(corev1.ResourceMemory, limit.AsApproximateFloat64()), max(untilLimit, 0).Round(time.Minute))
+	}
+	return finding, true
+}
+
+// idle находит Deployment и StatefulSet, которые за idleWindow почти не использовали CPU
+func (s anomalySettings) idle(w *workloadUsage, now time.Time) (AnomalyFinding, bool) {
+	if (w.kind != "Deployment" && w.kind != "StatefulSet") || len(w.totals) < s.minSamples {
+		return AnomalyFinding{}, false
+	}
+	earliest := int64(math.MaxInt64)
+	cpu := make([]float64, 0, len(w.totals))
+	for t, total := range w.totals {
+		earliest = min(earliest, t)
+		cpu = append(cpu, total[0])
+	}
+	since := time.Unix(earliest, 0)
+	if now.Sub(since) < time.Duration(idleMinCoverage*float64(s.idleWindow)) {
+		return AnomalyFinding{}, false
+	}
+	peak := percentile(cpu, 99)
+	if peak > s.idleCPU {
+		return AnomalyFinding{}, false
+	}
+
+	var requested float64
+	for _, pod := range w.pods {
+		for _, container := range pod.Spec.Containers {
+			requested += container.Resources.Requests.Cpu().AsApproximateFloat64()
+		}
+	}
+	return AnomalyFinding{
+		Kind: anomalyIdle, Severity: severityInfo, Namespace: w.namespace, WorkloadKind: w.kind, Workload: w.name,
+		Resource: string(corev1.ResourceCPU), Value: peak, Baseline: requested, Since: since.UTC(),
+		Message: fmt.Sprintf("%s %s/%s не использует CPU с %s: p99 %s при запросах %s на %d подах.",
+			w.kind, w.namespace, w.name, since.UTC().Format(time.RFC3339), formatUsage(corev1.ResourceCPU, peak),
+			formatUsage(corev1.ResourceCPU, requested), len(w.pods)),
+	}, true
+}
+
+// formatUsage форматирует CPU в милликорах, память в Mi
+func formatUsage(name corev1.ResourceName, value float64) string {
+	if name == corev1.ResourceCPU {
+		return fmt.Sprintf("%.0fm", value*1000)
+	}
+	return fmt.Sprintf("%.0fMi", value/mebibyte)
+}
+
+// filter оставляет находки namespace ("" - все) с важностью не ниже severity
+func (report *AnomalyReport) filter(namespace, severity string) {
+	findings := report.Findings[:0]
+	for _, f := range report.Findings {
+		if (namespace == "" || f.Namespace == namespace) && severityRank[f.Severity] >= severityRank[severity] {
+			findings = append(findings, f)
+		}
+	}
+	report.Findings = findings
+}
+
+// handleAnomaliesRequest обрабатывает GET /analyze/anomalies?namespace=&severity=
+func (r *KubedeckReconciler) handleAnomaliesRequest(w http.ResponseWriter, req *http.Request) {
+	if r.history == nil {
+		http.Error(w, "Metrics history is disabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	query := req.URL.Query()
+	severity := query.Get("severity")
+	if severity == "" {
+		severity = severityInfo
+	}
+	if _, ok := severityRank[severity]; !ok {
+		http.Error(w, "severity must be info, warning or critical", http.StatusBadRequest)
+		return
+	}
+
+	report, err := r.anomalies(req.Context(), anomalySettingsFromEnv(), time.Now())
+	if err == nil {
+		report.filter(query.Get("namespace"), severity)
+	}
+	writeJsonResponse(w, report, "anomalies", err)
+}
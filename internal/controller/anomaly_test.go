@@ -0,0 +1,147 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Anomaly detection", func() {
+	settings := anomalySettings{window: 24 * time.Hour, spikeWindow: 15 * time.Minute, zScore: 4,
+		leakHorizon: 24 * time.Hour, idleWindow: 72 * time.Hour, idleCPU: 0.005, minSamples: 30}
+	now := time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)
+
+	// series строит ряд с шагом step, заканчивающийся в now
+	series := func(pod string, step time.Duration, n int, point func(i int) HistoryPoint) *containerSeries {
+		cs := &containerSeries{namespace: "default", pod: pod, container: "app"}
+		for i := range n {
+			p := point(i)
+			p.Time = now.Add(-time.Duration(n-1-i) * step)
+			cs.points = append(cs.points, p)
+		}
+		return cs
+	}
+	podWithLimit := func(name, memoryLimit string) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+			Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Resources: corev1.ResourceRequirements{
+				Limits: corev1.ResourceList{corev1.ResourceMemory: resource.MustParse(memoryLimit)},
+			}}}},
+			Status: corev1.PodStatus{Phase: corev1.PodRunning},
+		}
+	}
+
+	It("should compute mean, deviation and linear trend", func() {
+		mean, std := meanStd([]float64{2, 4, 4, 4, 5, 5, 7, 9})
+		Expect(mean).To(Equal(5.0))
+		Expect(std).To(Equal(2.0))
+
+		slope, intercept, r2 := linearTrend([]float64{0, 1, 2, 3}, []float64{1, 3, 5, 7})
+		Expect(slope).To(BeNumerically("~", 2, 1e-9))
+		Expect(intercept).To(BeNumerically("~", 1, 1e-9))
+		Expect(r2).To(BeNumerically("~", 1, 1e-9))
+	})
+
+	It("should flag a CPU spike against the baseline and ignore noise", func() {
+		spiky := series("web-1", time.Minute, 60, func(i int) HistoryPoint {
+			cpu := 0.1 + 0.01*float64(i%2)
+			if i == 55 {
+				cpu = 0.6
+			}
+			return HistoryPoint{CPU: cpu, Memory: 100 * mebibyte}
+		})
+		noisy := series("web-2", time.Minute, 60, func(i int) HistoryPoint {
+			return HistoryPoint{CPU: 0.1 + 0.02*float64(i%3), Memory: 100 * mebibyte}
+		})
+
+		report := settings.detect([]*containerSeries{spiky, noisy}, nil, nil, nil, now)
+		Expect(report.Findings).To(HaveLen(1))
+		finding := report.Findings[0]
+		Expect(finding.Kind).To(Equal(anomalySpike))
+		Expect(finding.Resource).To(Equal("cpu"))
+		Expect(finding.Pod).To(Equal("web-1"))
+		Expect(finding.Severity).To(Equal(severityCritical))
+		Expect(finding.Value).To(Equal(0.6))
+		Expect(finding.Baseline).To(BeNumerically("~", 0.105, 1e-3))
+		Expect(finding.Since).To(Equal(now.Add(-4 * time.Minute)))
+	})
+
+	It("should flag steady memory growth as a leak and rate it by the time to the limit", func() {
+		growth := func(i int) HistoryPoint {
+			memory := (100 + 2*float64(i) + 3*float64(i%2)) * mebibyte
+			return HistoryPoint{CPU: 0.1, Memory: memory}
+		}
+		leaking := series("worker-0", 15*time.Minute, 96, growth)
+		unlimited := series("worker-1", 15*time.Minute, 96, growth)
+		sawtooth := series("cache-0", 15*time.Minute, 96, func(i int) HistoryPoint {
+			return HistoryPoint{CPU: 0.1, Memory: (100 + 5*float64(i%24)) * mebibyte}
+		})
+
+		report := settings.detect([]*containerSeries{leaking, unlimited, sawtooth}, nil,
+			[]corev1.Pod{podWithLimit("worker-0", "320Mi")}, nil, now)
+		Expect(report.Findings).To(HaveLen(2))
+		Expect(report.Findings[0].Pod).To(Equal("worker-0"))
+		Expect(report.Findings[0].Kind).To(Equal(anomalyMemoryLeak))
+		Expect(report.Findings[0].Severity).To(Equal(severityCritical))
+		Expect(report.Findings[0].Score).To(BeNumerically(">", 0.99))
+		Expect(report.Findings[0].Value).To(BeNumerically("~", 291.5*mebibyte, mebibyte))
+		Expect(report.Findings[0].Message).To(ContainSubstring("Лимит 320Mi"))
+		Expect(report.Findings[1].Pod).To(Equal("worker-1"))
+		Expect(report.Findings[1].Severity).To(Equal(severityWarning))
+	})
+
+	It("should report workloads without CPU usage over the idle window", func() {
+		idleTotals := map[int64]*[2]float64{}
+		busyTotals := map[int64]*[2]float64{}
+		for i := range 72 {
+			t := now.Add(-time.Duration(i) * time.Hour).Unix()
+			idleTotals[t] = &[2]float64{0.001, 50 * mebibyte}
+			busyTotals[t] = &[2]float64{0.2, 50 * mebibyte}
+		}
+		pod := podWithLimit("legacy-0", "64Mi")
+		pod.Spec.Containers[0].Resources.Requests = corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("250m")}
+		workloads := []*workloadUsage{
+			{namespace: "default", kind: "Deployment", name: "legacy", pods: []corev1.Pod{pod}, totals: idleTotals},
+			{namespace: "default", kind: "Deployment", name: "api", totals: busyTotals},
+			{namespace: "default", kind: "Job", name: "migrate", totals: idleTotals},
+			{namespace: "default", kind: "StatefulSet", name: "fresh", totals: map[int64]*[2]float64{now.Unix(): {0, 0}}},
+		}
+
+		report := settings.detect(nil, workloads, nil, nil, now)
+		Expect(report.Findings).To(HaveLen(1))
+		finding := report.Findings[0]
+		Expect(finding.Kind).To(Equal(anomalyIdle))
+		Expect(finding.Severity).To(Equal(severityInfo))
+		Expect(finding.Workload).To(Equal("legacy"))
+		Expect(finding.Baseline).To(Equal(0.25))
+
+		report.filter("", severityWarning)
+		Expect(report.Findings).To(BeEmpty())
+	})
+
+	It("should serve /analyze/anomalies from the metrics history", func() {
+		recorder := httptest.NewRecorder()
+		(&KubedeckReconciler{}).handleAnomaliesRequest(recorder, httptest.NewRequest(http.MethodGet, "/analyze/anomalies", nil))
+		Expect(recorder.Code).To(Equal(http.StatusServiceUnavailable))
+
+		dir, err := os.MkdirTemp("", "kubedeck-anomalies")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(os.RemoveAll, dir)
+		history, err := newMetricsHistory(dir, []historyTier{
+			{Name: "raw", Resolution: time.Minute, Retention: 24 * time.Hour, block: time.Hour},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		recorder = httptest.NewRecorder()
+		(&KubedeckReconciler{history: history}).handleAnomaliesRequest(recorder,
+			httptest.NewRequest(http.MethodGet, "/analyze/anomalies?severity=fatal", nil))
+		Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+	})
+})
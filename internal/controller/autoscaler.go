@@ -0,0 +1,626 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/labels"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	// maxAutoscalerDecisions - сколько последних решений автоскейлера хранится для API
+	maxAutoscalerDecisions = 200
+
+	defaultAutoscalerInterval = 60 // секунд
+
+	autoscalerActionScaleUp   = "scale_up"
+	autoscalerActionScaleDown = "scale_down"
+	// autoscalerActionHold - группа не меняется, но решение попадает в журнал, так как требует внимания
+	autoscalerActionHold = "hold"
+)
+
+// NodeGroupAutoscaling - настройки автомасштабирования одной группы нод
+type NodeGroupAutoscaling struct {
+	Provider    string `json:"provider"`
+	ClusterID   string `json:"clusterID"`
+	NodeGroupID string `json:"nodeGroupID"`
+	MinNodes    int    `json:"minNodes"`
+	MaxNodes    int    `json:"maxNodes"`
+	// Cooldown-ы в секундах после последнего изменения размера группы
+	ScaleUpCooldown   int `json:"scaleUpCooldown,omitempty"`
+	ScaleDownCooldown int `json:"scaleDownCooldown,omitempty"`
+	// Пороги загрузки группы в процентах (CPU или память)
+	ScaleUpUtilization   float64 `json:"scaleUpUtilization,omitempty"`
+	ScaleDownUtilization float64 `json:"scaleDownUtilization,omitempty"`
+}
+
+// key возвращает уникальный ключ группы нод
+func (g NodeGroupAutoscaling) key() string {
+	return g.Provider + "/" + g.ClusterID + "/" + g.NodeGroupID
+}
+
+// withDefaults заполняет незаданные пороги и cooldown-ы
+func (g NodeGroupAutoscaling) withDefaults() NodeGroupAutoscaling {
+	if g.ScaleUpCooldown <= 0 {
+		g.ScaleUpCooldown = 180
+	}
+	if g.ScaleDownCooldown <= 0 {
+		g.ScaleDownCooldown = 600
+	}
+	if g.ScaleUpUtilization <= 0 {
+		g.ScaleUpUtilization = 85
+	}
+	if g.ScaleDownUtilization <= 0 {
+		g.ScaleDownUtilization = 40
+	}
+	return g
+}
+
+// AutoscalerConfig - запрос на изменение настроек автоскейлера (все поля опциональны)
+type AutoscalerConfig struct {
+	Enabled    *bool                  `json:"enabled,omitempty"`
+	DryRun     *bool                  `json:"dryRun,omitempty"`
+	Interval   int                    `json:"interval,omitempty"` // секунды
+	NodeGroups []NodeGroupAutoscaling `json:"nodeGroups,omitempty"`
+}
+
+// AutoscalerDecision - решение автоскейлера по группе нод
+type AutoscalerDecision struct {
+	Time         time.Time `json:"time"`
+	Provider     string    `json:"provider"`
+	ClusterID    string    `json:"clusterID"`
+	NodeGroupID  string    `json:"nodeGroupID"`
+	Action       string    `json:"action"`
+	CurrentNodes int       `json:"currentNodes"`
+	TargetNodes  int       `json:"targetNodes"`
+	Reason       string    `json:"reason"`
+	DryRun       bool      `json:"dryRun"`
+	Applied      bool      `json:"applied"`
+	// Operation - ID операции scale-in, если уменьшение идет через drain нод
+	Operation string `json:"operation,omitempty"`
+	Error     string `json:"error,omitempty"`
+}
+
+// AutoscalerSettings содержит настройки и состояние автоскейлера
+type AutoscalerSettings struct {
+	sync.RWMutex
+	enabled    bool
+	dryRun     bool
+	interval   int
+	nodeGroups []NodeGroupAutoscaling
+	decisions  []AutoscalerDecision
+	// lastScale - время последнего изменения размера группы (включая dry-run)
+	lastScale map[string]time.Time
+}
+
+// NewAutoscalerSettings создает настройки автоскейлера из переменных окружения.
+// По умолчанию автоскейлер выключен и работает в режиме dry-run.
+func NewAutoscalerSettings() *AutoscalerSettings {
+	s := &AutoscalerSettings{
+		enabled:   getEnvBool("KUBEDECK_AUTOSCALER_ENABLED", false),
+		dryRun:    getEnvBool("KUBEDECK_AUTOSCALER_DRY_RUN", true),
+		interval:  int(getEnvDuration("KUBEDECK_AUTOSCALER_INTERVAL", defaultAutoscalerInterval*time.Second).Seconds()),
+		lastScale: make(map[string]time.Time),
+	}
+
+	if raw := os.Getenv("KUBEDECK_AUTOSCALER_NODE_GROUPS"); raw != "" {
+		var groups []NodeGroupAutoscaling
+		if err := json.Unmarshal([]byte(raw), &groups); err != nil {
+			webServerLog.Error(err, "Failed to parse KUBEDECK_AUTOSCALER_NODE_GROUPS")
+		} else if err := validateAutoscalingGroups(groups); err != nil {
+			webServerLog.Error(err, "Invalid KUBEDECK_AUTOSCALER_NODE_GROUPS")
+		} else {
+			s.nodeGroups = groups
+		}
+	}
+	if s.interval <= 0 {
+		s.interval = defaultAutoscalerInterval
+	}
+	return s
+}
+
+// validateAutoscalingGroups проверяет границы размеров групп
+func validateAutoscalingGroups(groups []NodeGroupAutoscaling) error {
+	for _, g := range groups {
+		if g.Provider == "" || g.ClusterID == "" || g.NodeGroupID == "" {
+			return fmt.Errorf("provider, clusterID and nodeGroupID are required")
+		}
+		if g.MinNodes < 0 || g.MaxNodes < g.MinNodes {
+			return fmt.Errorf("node group %s: invalid bounds min=%d max=%d", g.key(), g.MinNodes, g.MaxNodes)
+		}
+	}
+	return nil
+}
+
+// UpdateSettings применяет изменения настроек
+func (s *AutoscalerSettings) UpdateSettings(config *AutoscalerConfig) error {
+	if config.NodeGroups != nil {
+		if err := validateAutoscalingGroups(config.NodeGroups); err != nil {
+			return err
+		}
+	}
+
+	s.Lock()
+	defer s.Unlock()
+
+	if config.Enabled != nil {
+		s.enabled = *config.Enabled
+	}
+	if config.DryRun != nil {
+		s.dryRun = *config.DryRun
+	}
+	if config.Interval > 0 {
+		s.interval = config.Interval
+	}
+	if config.NodeGroups != nil {
+		s.nodeGroups = append([]NodeGroupAutoscaling{}, config.NodeGroups...)
+	}
+	return nil
+}
+
+// snapshot возвращает копию текущих настроек
+func (s *AutoscalerSettings) snapshot() (enabled, dryRun bool, interval int, groups []NodeGroupAutoscaling) {
+	s.RLock()
+	defer s.RUnlock()
+	return s.enabled, s.dryRun, s.interval, append([]NodeGroupAutoscaling{}, s.nodeGroups...)
+}
+
+// GetDecisions возвращает копию последних решений
+func (s *AutoscalerSettings) GetDecisions() []AutoscalerDecision {
+	s.RLock()
+	defer s.RUnlock()
+	return append([]AutoscalerDecision{}, s.decisions...)
+}
+
+func (s *AutoscalerSettings) recordDecision(decision AutoscalerDecision) {
+	s.Lock()
+	defer s.Unlock()
+
+	s.decisions = append(s.decisions, decision)
+	if len(s.decisions) > maxAutoscalerDecisions {
+		s.decisions = s.decisions[len(s.decisions)-maxAutoscalerDecisions:]
+	}
+}
+
+func (s *AutoscalerSettings) lastScaleTime(key string) time.Time {
+	s.RLock()
+	defer s.RUnlock()
+	return s.lastScale[key]
+}
+
+func (s *AutoscalerSettings) markScaled(key string, t time.Time) {
+	s.Lock()
+	defer s.Unlock()
+	s.lastScale[key] = t
+}
+
+// runAutoscaler - цикл автоскейлера, запускается менеджером (только на лидере)
+func (r *KubedeckReconciler) runAutoscaler(ctx context.Context) error {
+	log := webServerLog.WithName("autoscaler")
+	_, _, interval, _ := r.AutoscalerSettings.snapshot()
+	log.Info("Starting node group autoscaler", "interval", interval)
+
+	ticker := time.NewTicker(time.Duration(interval) * time.Second)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+			enabled, dryRun, newInterval, groups := r.AutoscalerSettings.snapshot()
+			if newInterval != interval {
+				interval = newInterval
+				ticker.Reset(time.Duration(interval) * time.Second)
+			}
+			if !enabled {
+				continue
+			}
+			r.autoscaleNodeGroups(ctx, groups, dryRun)
+		}
+	}
+}
+
+// nodeGroupState - данные, на основании которых принимается решение по группе
+type nodeGroupState struct {
+	group   NodeGroup
+	current int
+	// nodes - сколько нод группы видно в кластере по ее меткам
+	nodes       int
+	metrics     *ClusterMetrics
+	pendingPods []corev1.Pod
+	template    *corev1.Node
+}
+
+// nodeGroupEvaluation - группа нод, состояние которой собрано в текущем проходе автоскейлера
+type nodeGroupEvaluation struct {
+	cfg      NodeGroupAutoscaling
+	provider ClusterProvider
+	// ctx указывает на кластер, в котором находятся ноды группы
+	ctx   context.Context
+	state *nodeGroupState
+}
+
+// autoscaleNodeGroups оценивает все группы за один проход: сначала собирает их состояние,
+// затем распределяет ожидающие поды между группами и только после этого меняет размеры.
+func (r *KubedeckReconciler) autoscaleNodeGroups(ctx context.Context, groups []NodeGroupAutoscaling, dryRun bool) {
+	pods := make(map[string][]corev1.Pod)
+	var evaluations []*nodeGroupEvaluation
+	for _, cfg := range groups {
+		if evaluation := r.evaluateNodeGroup(ctx, cfg.withDefaults(), dryRun, pods); evaluation != nil {
+			evaluations = append(evaluations, evaluation)
+		}
+	}
+
+	assignPendingPods(evaluations)
+	for _, evaluation := range evaluations {
+		r.autoscaleNodeGroup(evaluation, dryRun)
+	}
+}
+
+// evaluateNodeGroup собирает состояние группы. Поды кластера запрашиваются один раз за проход
+// и сохраняются в pods, ошибки попадают в журнал решений.
+func (r *KubedeckReconciler) evaluateNodeGroup(ctx context.Context, cfg NodeGroupAutoscaling, dryRun bool,
+	pods map[string][]corev1.Pod) *nodeGroupEvaluation {
+	log := webServerLog.WithName("autoscaler").WithValues("provider", cfg.Provider, "cluster", cfg.ClusterID, "nodeGroup", cfg.NodeGroupID)
+
+	decision := AutoscalerDecision{
+		Time:        time.Now(),
+		Provider:    cfg.Provider,
+		ClusterID:   cfg.ClusterID,
+		NodeGroupID: cfg.NodeGroupID,
+		DryRun:      dryRun,
+	}
+
+	provider, _, err := r.getClusterProvider(cfg.Provider)
+	if err != nil {
+		log.Error(err, "Autoscaling skipped")
+		return nil
+	}
+
+	// Ноды группы находятся в кластере провайдера
+	ctx, err = r.nodeGroupContext(ctx, cfg.Provider, cfg.ClusterID)
+	if err != nil {
+		log.Error(err, "Failed to connect to the node group cluster")
+		decision.Error = err.Error()
+		r.AutoscalerSettings.recordDecision(decision)
+		return nil
+	}
+
+	clusterKey := cfg.Provider + "/" + cfg.ClusterID
+	clusterPods, ok := pods[clusterKey]
+	if !ok {
+		// Ожидающие поды читаются напрямую: снимок метрик может отставать на TTL кэша
+		podList := &corev1.PodList{}
+		if err := r.clientFor(ctx).List(ctx, podList); err != nil {
+			log.Error(err, "Failed to list pods")
+			decision.Error = err.Error()
+			r.AutoscalerSettings.recordDecision(decision)
+			return nil
+		}
+		clusterPods = podList.Items
+		pods[clusterKey] = clusterPods
+	}
+
+	state, err := r.loadNodeGroupState(ctx, provider, cfg, clusterPods)
+	if err != nil {
+		log.Error(err, "Failed to collect node group state")
+		decision.Error = err.Error()
+		r.AutoscalerSettings.recordDecision(decision)
+		return nil
+	}
+	return &nodeGroupEvaluation{cfg: cfg, provider: provider, ctx: ctx, state: state}
+}
+
+// assignPendingPods оставляет каждый ожидающий под только в одной группе, иначе под, подходящий
+// нескольким группам, увеличил бы их все. Как priority expander у cluster-autoscaler, под достается
+// первой по порядку настройки группе, которая еще может расти.
+func assignPendingPods(evaluations []*nodeGroupEvaluation) {
+	podKey := func(e *nodeGroupEvaluation, pod corev1.Pod) string {
+		return e.cfg.Provider + "/" + e.cfg.ClusterID + "/" + pod.Namespace + "/" + pod.Name
+	}
+	canGrow := func(e *nodeGroupEvaluation) bool {
+		return e.state.current < e.cfg.MaxNodes
+	}
+
+	owners := make(map[string]*nodeGroupEvaluation)
+	for _, e := range evaluations {
+		for _, pod := range e.state.pendingPods {
+			key := podKey(e, pod)
+			if owner, ok := owners[key]; !ok || (!canGrow(owner) && canGrow(e)) {
+				owners[key] = e
+			}
+		}
+	}
+
+	for _, e := range evaluations {
+		var assigned []corev1.Pod
+		for _, pod := range e.state.pendingPods {
+			if owners[podKey(e, pod)] == e {
+				assigned = append(assigned, pod)
+			}
+		}
+		e.state.pendingPods = assigned
+	}
+}
+
+// autoscaleNodeGroup принимает решение по группе и при необходимости меняет ее размер
+func (r *KubedeckReconciler) autoscaleNodeGroup(evaluation *nodeGroupEvaluation, dryRun bool) {
+	ctx, cfg, provider, state := evaluation.ctx, evaluation.cfg, evaluation.provider, evaluation.state
+	log := webServerLog.WithName("autoscaler").WithValues("provider", cfg.Provider, "cluster", cfg.ClusterID, "nodeGroup", cfg.NodeGroupID)
+
+	decision := AutoscalerDecision{
+		Time:         time.Now(),
+		Provider:     cfg.Provider,
+		ClusterID:    cfg.ClusterID,
+		NodeGroupID:  cfg.NodeGroupID,
+		CurrentNodes: state.current,
+		DryRun:       dryRun,
+	}
+
+	target, action, reason := decideNodeGroupSize(cfg, state, time.Since(r.AutoscalerSettings.lastScaleTime(cfg.key())))
+	if action == "" {
+		log.V(1).Info("No scaling needed", "nodes", state.current, "reason", reason)
+		return
+	}
+	if action == autoscalerActionHold {
+		log.Info("Scaling held", "nodes", state.current, "reason", reason)
+		decision.Action = action
+		decision.TargetNodes = target
+		decision.Reason = reason
+		r.AutoscalerSettings.recordDecision(decision)
+		return
+	}
+
+	// Цель приводится к ограничениям, чтобы группа двигалась к ней допустимыми шагами
+	change := ScaleChange{Provider: cfg.Provider, ClusterID: cfg.ClusterID, NodeGroupID: cfg.NodeGroupID, Current: state.current}
+	change.Target = target
+	if clamped := r.GuardrailSettings.Clamp(change); clamped != target {
+		reason = fmt.Sprintf("%s (limited by guardrails to %d)", reason, clamped)
+		target = clamped
+		change.Target = clamped
+	}
+	if target == state.current {
+		log.V(1).Info("Scaling prevented by guardrails", "nodes", state.current, "reason", reason)
+		return
+	}
+
+	decision.Action = action
+	decision.TargetNodes = target
+	decision.Reason = reason
+	log.Info("Autoscaler decision", "action", action, "from", state.current, "to", target, "reason", reason, "dryRun", dryRun)
+
+	if err := r.checkAutomatedScaling(ctx, provider, change); err != nil {
+		log.Info("Scaling blocked by guardrails", "error", err.Error())
+		decision.Error = err.Error()
+	} else if !dryRun {
+		if op, err := r.resizeNodeGroup(ctx, cfg.Provider, provider, cfg.ClusterID, state.group, target); err != nil {
+			log.Error(err, "Failed to scale node group")
+			decision.Error = err.Error()
+		} else {
+			decision.Applied = true
+			if op != nil {
+				decision.Operation = op.ID
+			}
+		}
+	}
+	// Cooldown отсчитывается и в dry-run, чтобы журнал решений соответствовал реальному поведению
+	if decision.Error == "" {
+		r.AutoscalerSettings.markScaled(cfg.key(), decision.Time)
+	}
+	r.AutoscalerSettings.recordDecision(decision)
+}
+
+// loadNodeGroupState получает размер группы у провайдера, ее ноды, загрузку и ожидающие поды
+func (r *KubedeckReconciler) loadNodeGroupState(ctx context.Context, provider ClusterProvider, cfg NodeGroupAutoscaling,
+	pods []corev1.Pod) (*nodeGroupState, error) {
+	groups, err := provider.GetNodeGroups(ctx, cfg.ClusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	var group *NodeGroup
+	for i := range groups {
+		if groups[i].ID == cfg.NodeGroupID {
+			group = &groups[i]
+			break
+		}
+	}
+	if group == nil {
+		return nil, fmt.Errorf("node group %s not found", cfg.NodeGroupID)
+	}
+	if len(group.NodeLabels) == 0 {
+		return nil, fmt.Errorf("provider %s does not expose node labels for node group %s", cfg.Provider, cfg.NodeGroupID)
+	}
+
+	nodeList := &corev1.NodeList{}
+	if err := r.clientFor(ctx).List(ctx, nodeList, client.MatchingLabels(group.NodeLabels)); err != nil {
+		return nil, err
+	}
+
+	metrics, err := r.computeClusterMetrics(ctx, nodeList.Items, pods, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	state := &nodeGroupState{group: *group, current: group.NodeCount, nodes: len(nodeList.Items), metrics: metrics}
+	if len(nodeList.Items) > 0 {
+		state.template = &nodeList.Items[0]
+	}
+	for _, pod := range pods {
+		if isPodUnschedulable(pod) && podFitsNodeGroup(pod, group.NodeLabels, state.template) {
+			state.pendingPods = append(state.pendingPods, pod)
+		}
+	}
+	return state, nil
+}
+
+// decideNodeGroupSize возвращает целевой размер группы, действие (пустое, если менять не нужно) и причину
+func decideNodeGroupSize(cfg NodeGroupAutoscaling, state *nodeGroupState, sinceLastScale time.Duration) (int, string, string) {
+	current := state.current
+
+	// Границы min/max применяются без учета cooldown
+	if current < cfg.MinNodes {
+		return cfg.MinNodes, autoscalerActionScaleUp, fmt.Sprintf("node count %d is below minimum %d", current, cfg.MinNodes)
+	}
+	if current > cfg.MaxNodes {
+		return cfg.MaxNodes, autoscalerActionScaleDown, fmt.Sprintf("node count %d is above maximum %d", current, cfg.MaxNodes)
+	}
+
+	utilization := math.Max(state.metrics.CPUUtilization, state.metrics.MemoryUtilization)
+
+	if len(state.pendingPods) > 0 || utilization >= cfg.ScaleUpUtilization {
+		if current >= cfg.MaxNodes {
+			return current, "", "scale up needed but node group is at maximum"
+		}
+		if sinceLastScale < time.Duration(cfg.ScaleUpCooldown)*time.Second {
+			return current, "", "scale up cooldown"
+		}
+
+		add := 1
+		reason := fmt.Sprintf("utilization %.1f%% >= %.1f%%", utilization, cfg.ScaleUpUtilization)
+		if len(state.pendingPods) > 0 {
+			add = nodesForPendingPods(state.pendingPods, state.template)
+			reason = fmt.Sprintf("%d unschedulable pods", len(state.pendingPods))
+		}
+		return min(current+add, cfg.MaxNodes), autoscalerActionScaleUp, reason
+	}
+
+	if current > cfg.MinNodes && utilization < cfg.ScaleDownUtilization {
+		// Без нод в кластере загрузка считается нулевой, и группа уменьшалась бы до minNodes
+		if state.nodes < current {
+			return current, autoscalerActionHold,
+				fmt.Sprintf("no matching nodes: only %d of %d nodes match the node group labels", state.nodes, current)
+		}
+		if sinceLastScale < time.Duration(cfg.ScaleDownCooldown)*time.Second {
+			return current, "", "scale down cooldown"
+		}
+		// Не уменьшаем группу, если после удаления ноды загрузка превысит порог scale up
+		projected := utilization * float64(current) / float64(current-1)
+		if projected >= cfg.ScaleUpUtilization {
+			return current, "", fmt.Sprintf("projected utilization %.1f%% after scale down is too high", projected)
+		}
+		return current - 1, autoscalerActionScaleDown,
+			fmt.Sprintf("utilization %.1f%% < %.1f%%, projected %.1f%%", utilization, cfg.ScaleDownUtilization, projected)
+	}
+
+	return current, "", fmt.Sprintf("utilization %.1f%% within bounds", utilization)
+}
+
+// nodesForPendingPods оценивает, сколько нод нужно для размещения ожидающих подов
+func nodesForPendingPods(pods []corev1.Pod, template *corev1.Node) int {
+	if template == nil {
+		return 1
+	}
+
+	var cpu, memory resource.Quantity
+	for _, pod := range pods {
+		for _, container := range pod.Spec.Containers {
+			cpu.Add(container.Resources.Requests.Cpu().DeepCopy())
+			memory.Add(container.Resources.Requests.Memory().DeepCopy())
+		}
+	}
+
+	allocatable := template.Status.Allocatable
+	nodes := 1.0
+	if allocCPU := allocatable.Cpu().AsApproximateFloat64(); allocCPU > 0 {
+		nodes = math.Max(nodes, math.Ceil(cpu.AsApproximateFloat64()/allocCPU))
+	}
+	if allocMem := allocatable.Memory().AsApproximateFloat64(); allocMem > 0 {
+		nodes = math.Max(nodes, math.Ceil(memory.AsApproximateFloat64()/allocMem))
+	}
+	return int(nodes)
+}
+
+// isPodUnschedulable проверяет, что под не может быть размещен планировщиком
+func isPodUnschedulable(pod corev1.Pod) bool {
+	if pod.Status.Phase != corev1.PodPending || pod.Spec.NodeName != "" {
+		return false
+	}
+	for _, condition := range pod.Status.Conditions {
+		if condition.Type == corev1.PodScheduled &&
+			condition.Status == corev1.ConditionFalse &&
+			condition.Reason == corev1.PodReasonUnschedulable {
+			return true
+		}
+	}
+	return false
+}
+
+// podFitsNodeGroup приблизительно проверяет, может ли под быть размещен на новой ноде группы:
+// nodeSelector должен совпадать с метками ноды-образца, а taint-ы NoSchedule должны допускаться.
+func podFitsNodeGroup(pod corev1.Pod, groupLabels map[string]string, template *corev1.Node) bool {
+	nodeLabels := labels.Set(groupLabels)
+	var taints []corev1.Taint
+	if template != nil {
+		nodeLabels = labels.Merge(template.Labels, groupLabels)
+		taints = template.Spec.Taints
+	}
+
+	if len(pod.Spec.NodeSelector) > 0 && !labels.SelectorFromSet(pod.Spec.NodeSelector).Matches(nodeLabels) {
+		return false
+	}
+
+	for _, taint := range taints {
+		if taint.Effect != corev1.TaintEffectNoSchedule && taint.Effect != corev1.TaintEffectNoExecute {
+			continue
+		}
+		tolerated := false
+		for _, toleration := range pod.Spec.Tolerations {
+			if toleration.ToleratesTaint(&taint) {
+				tolerated = true
+				break
+			}
+		}
+		if !tolerated {
+			return false
+		}
+	}
+	return true
+}
+
+// handleAutoscalerConfigRequest возвращает (GET) или обновляет (POST) настройки автоскейлера
+func (r *KubedeckReconciler) handleAutoscalerConfigRequest(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var config AutoscalerConfig
+		if err := json.NewDecoder(req.Body).Decode(&config); err != nil {
+			http.Error(w, "Invalid JSON request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer req.Body.Close()
+
+		if err := r.AutoscalerSettings.UpdateSettings(&config); err != nil {
+			http.Error(w, "Invalid autoscaler config: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		webServerLog.WithName("autoscaler").Info("Autoscaler settings updated")
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	enabled, dryRun, interval, groups := r.AutoscalerSettings.snapshot()
+	response := map[string]any{
+		"enabled":    enabled,
+		"dryRun":     dryRun,
+		"interval":   interval,
+		"nodeGroups": groups,
+	}
+	writeJsonResponse(w, response, "autoscaler config", nil)
+}
+
+// handleAutoscalerDecisionsRequest возвращает последние решения автоскейлера
+func (r *KubedeckReconciler) handleAutoscalerDecisionsRequest(w http.ResponseWriter, req *http.Request) {
+	writeJsonResponse(w, r.AutoscalerSettings.GetDecisions(), "autoscaler decisions", nil)
+}
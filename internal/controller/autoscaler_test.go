@@ -0,0 +1,111 @@
+package controller
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Node group autoscaler", func() {
+	cfg := NodeGroupAutoscaling{Provider: "fake", ClusterID: "c", NodeGroupID: "g", MinNodes: 1, MaxNodes: 5}.withDefaults()
+
+	template := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Labels: map[string]string{"pool": "general"}},
+		Status: corev1.NodeStatus{Allocatable: corev1.ResourceList{
+			corev1.ResourceCPU:    resource.MustParse("2"),
+			corev1.ResourceMemory: resource.MustParse("4Gi"),
+		}},
+	}
+
+	pendingPod := func(cpu string) corev1.Pod {
+		return corev1.Pod{Spec: corev1.PodSpec{Containers: []corev1.Container{{
+			Resources: corev1.ResourceRequirements{Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(cpu)}},
+		}}}}
+	}
+
+	It("should add nodes for unschedulable pods within max bounds", func() {
+		state := &nodeGroupState{current: 2, metrics: &ClusterMetrics{}, template: template,
+			pendingPods: []corev1.Pod{pendingPod("3"), pendingPod("2")}}
+
+		target, action, _ := decideNodeGroupSize(cfg, state, time.Hour)
+		Expect(action).To(Equal(autoscalerActionScaleUp))
+		Expect(target).To(Equal(5))
+
+		_, action, reason := decideNodeGroupSize(cfg, state, time.Second)
+		Expect(action).To(BeEmpty())
+		Expect(reason).To(ContainSubstring("cooldown"))
+	})
+
+	It("should remove a node only when projected utilization stays low", func() {
+		state := &nodeGroupState{current: 3, nodes: 3, metrics: &ClusterMetrics{CPUUtilization: 30, MemoryUtilization: 20}}
+		target, action, _ := decideNodeGroupSize(cfg, state, time.Hour)
+		Expect(action).To(Equal(autoscalerActionScaleDown))
+		Expect(target).To(Equal(2))
+
+		strict := cfg
+		strict.ScaleUpUtilization = 70
+		state = &nodeGroupState{current: 2, nodes: 2, metrics: &ClusterMetrics{CPUUtilization: 39}}
+		_, action, reason := decideNodeGroupSize(strict, state, time.Hour)
+		Expect(action).To(BeEmpty())
+		Expect(reason).To(ContainSubstring("projected"))
+	})
+
+	It("should hold scale down while the node group labels match fewer nodes than the group has", func() {
+		state := &nodeGroupState{current: 3, nodes: 0, metrics: &ClusterMetrics{}}
+		target, action, reason := decideNodeGroupSize(cfg, state, time.Hour)
+		Expect(action).To(Equal(autoscalerActionHold))
+		Expect(target).To(Equal(3))
+		Expect(reason).To(ContainSubstring("no matching nodes"))
+
+		state.nodes = 2
+		_, action, _ = decideNodeGroupSize(cfg, state, time.Hour)
+		Expect(action).To(Equal(autoscalerActionHold))
+	})
+
+	It("should assign every pending pod to a single node group", func() {
+		pod := func(name string) corev1.Pod {
+			p := pendingPod("1")
+			p.Namespace, p.Name = "default", name
+			return p
+		}
+		evaluation := func(cluster, group string, current, maxNodes int, pods ...corev1.Pod) *nodeGroupEvaluation {
+			return &nodeGroupEvaluation{
+				cfg:   NodeGroupAutoscaling{Provider: "fake", ClusterID: cluster, NodeGroupID: group, MaxNodes: maxNodes},
+				state: &nodeGroupState{current: current, pendingPods: pods},
+			}
+		}
+
+		full := evaluation("c", "full", 3, 3, pod("web-0"))
+		general := evaluation("c", "general", 1, 5, pod("web-0"), pod("web-1"))
+		spare := evaluation("c", "spare", 1, 5, pod("web-1"), pod("web-2"))
+		other := evaluation("other", "general", 1, 5, pod("web-1"))
+		assignPendingPods([]*nodeGroupEvaluation{full, general, spare, other})
+
+		Expect(full.state.pendingPods).To(BeEmpty())
+		Expect(general.state.pendingPods).To(HaveLen(2))
+		Expect(spare.state.pendingPods).To(HaveLen(1))
+		Expect(spare.state.pendingPods[0].Name).To(Equal("web-2"))
+		// Одноименный под в другом кластере - другой под
+		Expect(other.state.pendingPods).To(HaveLen(1))
+	})
+
+	It("should match pods to the node group by nodeSelector and taints", func() {
+		tainted := template.DeepCopy()
+		tainted.Spec.Taints = []corev1.Taint{{Key: "dedicated", Value: "gpu", Effect: corev1.TaintEffectNoSchedule}}
+
+		pod := pendingPod("1")
+		pod.Spec.NodeSelector = map[string]string{"pool": "general"}
+		Expect(podFitsNodeGroup(pod, nil, template)).To(BeTrue())
+		Expect(podFitsNodeGroup(pod, nil, tainted)).To(BeFalse())
+
+		pod.Spec.Tolerations = []corev1.Toleration{{Key: "dedicated", Operator: corev1.TolerationOpExists}}
+		Expect(podFitsNodeGroup(pod, nil, tainted)).To(BeTrue())
+
+		pod.Spec.NodeSelector = map[string]string{"pool": "highmem"}
+		Expect(podFitsNodeGroup(pod, nil, template)).To(BeFalse())
+	})
+})
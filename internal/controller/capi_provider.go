@@ -0,0 +1,286 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// Cluster API объекты читаются как unstructured, чтобы не тянуть зависимость от CAPI
+var (
+	capiClusterGVK           = schema.GroupVersionKind{Group: "cluster.x-k8s.io", Version: "v1beta1", Kind: "Cluster"}
+	capiMachineDeploymentGVK = schema.GroupVersionKind{Group: "cluster.x-k8s.io", Version: "v1beta1", Kind: "MachineDeployment"}
+	capiMachinePoolGVK       = schema.GroupVersionKind{Group: "cluster.x-k8s.io", Version: "v1beta1", Kind: "MachinePool"}
+	capiMachineGVK           = schema.GroupVersionKind{Group: "cluster.x-k8s.io", Version: "v1beta1", Kind: "Machine"}
+)
+
+const (
+	// capiClusterNameLabel - метка CAPI, связывающая MachineDeployment/MachinePool с кластером
+	capiClusterNameLabel = "cluster.x-k8s.io/cluster-name"
+
+	// capiNodeLabelPrefix - префикс меток, которые CAPI переносит с Machine на Node
+	capiNodeLabelPrefix = "node.cluster.x-k8s.io/"
+
+	// capiDeleteMachineAnnotation - машины с этой аннотацией удаляются первыми при уменьшении replicas
+	capiDeleteMachineAnnotation = "cluster.x-k8s.io/delete-machine"
+	// capiDeploymentNameLabel - метка Machine с именем MachineDeployment
+	capiDeploymentNameLabel = "cluster.x-k8s.io/deployment-name"
+
+	capiMachineDeploymentPrefix = "machinedeployment:"
+	capiMachinePoolPrefix       = "machinepool:"
+)
+
+// CAPIProvider работает с кластерами, созданными через Cluster API, в management-кластере.
+// ID кластера - "namespace/name", ID группы нод - "machinedeployment:<name>" или "machinepool:<name>".
+type CAPIProvider struct {
+	client client.Client
+	// namespace ограничивает поиск кластеров, пустое значение - все namespace
+	namespace string
+}
+
+// NewCAPIProvider создает провайдер Cluster API
+func NewCAPIProvider(c client.Client, namespace string) *CAPIProvider {
+	return &CAPIProvider{client: c, namespace: namespace}
+}
+
+func (p *CAPIProvider) ListClusters(ctx context.Context) ([]Cluster, error) {
+	list := &unstructured.UnstructuredList{}
+	list.SetGroupVersionKind(capiClusterGVK.GroupVersion().WithKind(capiClusterGVK.Kind + "List"))
+
+	if err := p.client.List(ctx, list, client.InNamespace(p.namespace)); err != nil {
+		return nil, capiError(err)
+	}
+
+	clusters := make([]Cluster, 0, len(list.Items))
+	for _, item := range list.Items {
+		phase, _, _ := unstructured.NestedString(item.Object, "status", "phase")
+		clusters = append(clusters, Cluster{
+			ID:       item.GetNamespace() + "/" + item.GetName(),
+			Name:     item.GetName(),
+			Status:   phase,
+			Provider: "capi",
+		})
+	}
+	return clusters, nil
+}
+
+func (p *CAPIProvider) GetNodeGroups(ctx context.Context, clusterID string) ([]NodeGroup, error) {
+	key, err := parseCAPIClusterID(clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	// Проверяем, что кластер существует, чтобы не возвращать пустой список для опечатки в ID
+	cluster := &unstructured.Unstructured{}
+	cluster.SetGroupVersionKind(capiClusterGVK)
+	if err := p.client.Get(ctx, key, cluster); err != nil {
+		return nil, capiError(err)
+	}
+
+	groups := []NodeGroup{}
+	sources := []struct {
+		gvk    schema.GroupVersionKind
+		prefix string
+	}{
+		{capiMachineDeploymentGVK, capiMachineDeploymentPrefix},
+		{capiMachinePoolGVK, capiMachinePoolPrefix},
+	}
+
+	for _, source := range sources {
+		list := &unstructured.UnstructuredList{}
+		list.SetGroupVersionKind(source.gvk.GroupVersion().WithKind(source.gvk.Kind + "List"))
+
+		err := p.client.List(ctx, list,
+			client.InNamespace(key.Namespace),
+			client.MatchingLabels{capiClusterNameLabel: key.Name})
+		if err != nil {
+			// MachinePool - экспериментальная возможность CAPI, CRD может отсутствовать
+			if meta.IsNoMatchError(err) {
+				continue
+			}
+			return nil, capiError(err)
+		}
+
+		for _, item := range list.Items {
+			replicas, _, _ := unstructured.NestedInt64(item.Object, "spec", "replicas")
+			groups = append(groups, NodeGroup{
+				ID:         source.prefix + item.GetName(),
+				Name:       item.GetName(),
+				NodeCount:  int(replicas),
+				NodeLabels: capiNodeLabels(item),
+			})
+		}
+	}
+
+	return groups, nil
+}
+
+// GetKubeconfig читает kubeconfig workload-кластера из Secret <cluster>-kubeconfig, который создает CAPI
+func (p *CAPIProvider) GetKubeconfig(ctx context.Context, clusterID string) ([]byte, error) {
+	key, err := parseCAPIClusterID(clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	secret := &corev1.Secret{}
+	if err := p.client.Get(ctx, types.NamespacedName{Namespace: key.Namespace, Name: key.Name + "-kubeconfig"}, secret); err != nil {
+		return nil, capiError(err)
+	}
+	kubeconfig, ok := secret.Data["value"]
+	if !ok {
+		return nil, fmt.Errorf("%w: secret %s-kubeconfig has no value key", ErrProviderNotFound, key.Name)
+	}
+	return kubeconfig, nil
+}
+
+// ScaleNodeGroup патчит spec.replicas у MachineDeployment или MachinePool
+func (p *CAPIProvider) ScaleNodeGroup(ctx context.Context, clusterID, groupID string, nodeCount int) error {
+	key, err := parseCAPIClusterID(clusterID)
+	if err != nil {
+		return err
+	}
+	if nodeCount < 0 {
+		return fmt.Errorf("%w: node count must not be negative, got %d", ErrProviderBadRequest, nodeCount)
+	}
+
+	obj, err := p.getNodeGroupObject(ctx, key, groupID)
+	if err != nil {
+		return err
+	}
+
+	patch := []byte(fmt.Sprintf(`{"spec":{"replicas":%d}}`, nodeCount))
+	if err := p.client.Patch(ctx, obj, client.RawPatch(types.MergePatchType, patch)); err != nil {
+		return capiError(err)
+	}
+	return nil
+}
+
+// DeleteNodes помечает Machine выбранных нод аннотацией delete-machine и уменьшает replicas
+// MachineDeployment, после чего CAPI удаляет именно эти машины. MachinePool не поддерживается.
+func (p *CAPIProvider) DeleteNodes(ctx context.Context, clusterID, groupID string, nodes []corev1.Node) error {
+	key, err := parseCAPIClusterID(clusterID)
+	if err != nil {
+		return err
+	}
+	if !strings.HasPrefix(groupID, capiMachineDeploymentPrefix) {
+		return fmt.Errorf("%w: deleting specific nodes is supported only for MachineDeployments", ErrProviderBadRequest)
+	}
+
+	obj, err := p.getNodeGroupObject(ctx, key, groupID)
+	if err != nil {
+		return err
+	}
+
+	machines := &unstructured.UnstructuredList{}
+	machines.SetGroupVersionKind(capiMachineGVK.GroupVersion().WithKind(capiMachineGVK.Kind + "List"))
+	err = p.client.List(ctx, machines,
+		client.InNamespace(key.Namespace),
+		client.MatchingLabels{capiClusterNameLabel: key.Name, capiDeploymentNameLabel: obj.GetName()})
+	if err != nil {
+		return capiError(err)
+	}
+
+	byNode := make(map[string]*unstructured.Unstructured, len(machines.Items))
+	for i := range machines.Items {
+		nodeName, _, _ := unstructured.NestedString(machines.Items[i].Object, "status", "nodeRef", "name")
+		byNode[nodeName] = &machines.Items[i]
+	}
+
+	for _, node := range nodes {
+		machine, ok := byNode[node.Name]
+		if !ok {
+			return fmt.Errorf("%w: machine for node %s not found in %s", ErrProviderNotFound, node.Name, groupID)
+		}
+		patch := []byte(fmt.Sprintf(`{"metadata":{"annotations":{%q:"true"}}}`, capiDeleteMachineAnnotation))
+		if err := p.client.Patch(ctx, machine, client.RawPatch(types.MergePatchType, patch)); err != nil {
+			return capiError(err)
+		}
+	}
+
+	replicas, _, _ := unstructured.NestedInt64(obj.Object, "spec", "replicas")
+	patch := []byte(fmt.Sprintf(`{"spec":{"replicas":%d}}`, max(replicas-int64(len(nodes)), 0)))
+	if err := p.client.Patch(ctx, obj, client.RawPatch(types.MergePatchType, patch)); err != nil {
+		return capiError(err)
+	}
+	return nil
+}
+
+// getNodeGroupObject находит MachineDeployment или MachinePool по ID группы и проверяет принадлежность кластеру
+func (p *CAPIProvider) getNodeGroupObject(ctx context.Context, cluster types.NamespacedName, groupID string) (*unstructured.Unstructured, error) {
+	obj := &unstructured.Unstructured{}
+	var name string
+	switch {
+	case strings.HasPrefix(groupID, capiMachineDeploymentPrefix):
+		obj.SetGroupVersionKind(capiMachineDeploymentGVK)
+		name = strings.TrimPrefix(groupID, capiMachineDeploymentPrefix)
+	case strings.HasPrefix(groupID, capiMachinePoolPrefix):
+		obj.SetGroupVersionKind(capiMachinePoolGVK)
+		name = strings.TrimPrefix(groupID, capiMachinePoolPrefix)
+	default:
+		return nil, fmt.Errorf("%w: invalid node group id %q, expected machinedeployment:<name> or machinepool:<name>",
+			ErrProviderBadRequest, groupID)
+	}
+
+	if err := p.client.Get(ctx, types.NamespacedName{Namespace: cluster.Namespace, Name: name}, obj); err != nil {
+		return nil, capiError(err)
+	}
+	if obj.GetLabels()[capiClusterNameLabel] != cluster.Name {
+		return nil, fmt.Errorf("%w: node group %s does not belong to cluster %s", ErrProviderNotFound, groupID, cluster)
+	}
+	return obj, nil
+}
+
+// capiNodeLabels возвращает метки шаблона машин, которые CAPI синхронизирует на Node.
+// Синхронизируются только метки с префиксом node.cluster.x-k8s.io/ (и node-role/node-restriction),
+// поэтому для сопоставления нод группе в шаблоне нужна такая метка.
+func capiNodeLabels(obj unstructured.Unstructured) map[string]string {
+	labels, _, _ := unstructured.NestedStringMap(obj.Object, "spec", "template", "metadata", "labels")
+
+	result := make(map[string]string)
+	for key, value := range labels {
+		if strings.HasPrefix(key, capiNodeLabelPrefix) {
+			result[key] = value
+		}
+	}
+	if len(result) == 0 {
+		return nil
+	}
+	return result
+}
+
+// parseCAPIClusterID разбирает ID кластера вида namespace/name
+func parseCAPIClusterID(clusterID string) (types.NamespacedName, error) {
+	namespace, name, ok := strings.Cut(clusterID, "/")
+	if !ok || namespace == "" || name == "" {
+		return types.NamespacedName{}, fmt.Errorf("%w: invalid cluster id %q, expected namespace/name", ErrProviderBadRequest, clusterID)
+	}
+	return types.NamespacedName{Namespace: namespace, Name: name}, nil
+}
+
+// capiError переводит ошибки API сервера в типизированные ошибки провайдера
+func capiError(err error) error {
+	switch {
+	case apierrors.IsNotFound(err), meta.IsNoMatchError(err):
+		return fmt.Errorf("%w: %v", ErrProviderNotFound, err)
+	case apierrors.IsUnauthorized(err), apierrors.IsForbidden(err):
+		return fmt.Errorf("%w: %v", ErrProviderUnauthorized, err)
+	case apierrors.IsConflict(err):
+		return fmt.Errorf("%w: %v", ErrProviderConflict, err)
+	case apierrors.IsTooManyRequests(err):
+		return fmt.Errorf("%w: %v", ErrProviderRateLimited, err)
+	case apierrors.IsBadRequest(err), apierrors.IsInvalid(err):
+		return fmt.Errorf("%w: %v", ErrProviderBadRequest, err)
+	case apierrors.IsInternalError(err), apierrors.IsServiceUnavailable(err),
+		apierrors.IsServerTimeout(err), apierrors.IsTimeout(err):
+		return fmt.Errorf("%w: %v", ErrProviderUnavailable, err)
+	}
+	return err
+}
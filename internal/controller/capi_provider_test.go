@@ -0,0 +1,76 @@
+package controller
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+var _ = Describe("Cluster API provider", func() {
+	const namespace = "capi-test"
+
+	newObject := func(gvk schema.GroupVersionKind, name string, spec map[string]interface{}) *unstructured.Unstructured {
+		obj := &unstructured.Unstructured{Object: map[string]interface{}{"spec": spec}}
+		obj.SetGroupVersionKind(gvk)
+		obj.SetNamespace(namespace)
+		obj.SetName(name)
+		if gvk != capiClusterGVK {
+			obj.SetLabels(map[string]string{capiClusterNameLabel: "workload"})
+		}
+		return obj
+	}
+
+	BeforeEach(func() {
+		ns := &corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: namespace}}
+		Expect(k8sClient.Create(ctx, ns)).To(Or(Succeed(), MatchError(ContainSubstring("already exists"))))
+
+		Expect(k8sClient.Create(ctx, newObject(capiClusterGVK, "workload", map[string]interface{}{}))).To(Succeed())
+		Expect(k8sClient.Create(ctx, newObject(capiMachineDeploymentGVK, "workload-md-0",
+			map[string]interface{}{"clusterName": "workload", "replicas": int64(2)}))).To(Succeed())
+		Expect(k8sClient.Create(ctx, newObject(capiMachinePoolGVK, "workload-mp-0",
+			map[string]interface{}{"clusterName": "workload", "replicas": int64(1)}))).To(Succeed())
+	})
+
+	AfterEach(func() {
+		Expect(k8sClient.Delete(ctx, newObject(capiMachinePoolGVK, "workload-mp-0", nil))).To(Succeed())
+		Expect(k8sClient.Delete(ctx, newObject(capiMachineDeploymentGVK, "workload-md-0", nil))).To(Succeed())
+		Expect(k8sClient.Delete(ctx, newObject(capiClusterGVK, "workload", nil))).To(Succeed())
+	})
+
+	It("should list clusters and map MachineDeployments and MachinePools to node groups", func() {
+		provider := NewCAPIProvider(k8sClient, namespace)
+
+		clusters, err := provider.ListClusters(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(clusters).To(ConsistOf(Cluster{ID: namespace + "/workload", Name: "workload", Provider: "capi"}))
+
+		groups, err := provider.GetNodeGroups(ctx, namespace+"/workload")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(groups).To(ConsistOf(
+			NodeGroup{ID: "machinedeployment:workload-md-0", Name: "workload-md-0", NodeCount: 2},
+			NodeGroup{ID: "machinepool:workload-mp-0", Name: "workload-mp-0", NodeCount: 1},
+		))
+	})
+
+	It("should scale by patching spec.replicas", func() {
+		provider := NewCAPIProvider(k8sClient, namespace)
+		Expect(provider.ScaleNodeGroup(ctx, namespace+"/workload", "machinedeployment:workload-md-0", 5)).To(Succeed())
+
+		md := newObject(capiMachineDeploymentGVK, "workload-md-0", nil)
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: namespace, Name: "workload-md-0"}, md)).To(Succeed())
+		replicas, _, _ := unstructured.NestedInt64(md.Object, "spec", "replicas")
+		Expect(replicas).To(BeEquivalentTo(5))
+
+		err := provider.ScaleNodeGroup(ctx, namespace+"/workload", "machinedeployment:missing", 1)
+		Expect(errors.Is(err, ErrProviderNotFound)).To(BeTrue())
+
+		err = provider.ScaleNodeGroup(ctx, "workload", "machinedeployment:workload-md-0", 1)
+		Expect(errors.Is(err, ErrProviderBadRequest)).To(BeTrue())
+	})
+})
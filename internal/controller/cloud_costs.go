@@ -0,0 +1,266 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	// hoursPerMonth - среднее число часов в месяце, по которому месячные цены пересчитываются в почасовые
+	hoursPerMonth = 730
+
+	// presetCacheTTL - как долго хранится каталог пресетов провайдера
+	presetCacheTTL = time.Hour
+)
+
+// Preset - конфигурация ноды (пресет TimeWeb, flavor) с ценой
+type Preset struct {
+	ID   string `json:"id"`
+	Name string `json:"name"`
+	// Type - назначение пресета у провайдера (master, worker), если различается
+	Type string `json:"type,omitempty"`
+	CPU  int    `json:"cpu"`
+	// RAM в мегабайтах, Disk в гигабайтах
+	RAM          int     `json:"ram"`
+	Disk         int     `json:"disk,omitempty"`
+	HourlyPrice  float64 `json:"hourly_price"`
+	MonthlyPrice float64 `json:"monthly_price"`
+	Currency     string  `json:"currency"`
+}
+
+// PresetCatalog - провайдер, публикующий каталог пресетов с ценами
+type PresetCatalog interface {
+	ListPresets(ctx context.Context) ([]Preset, error)
+}
+
+// presetCache кеширует каталог пресетов, чтобы не запрашивать его при каждом листинге групп
+type presetCache struct {
+	sync.Mutex
+	presets   []Preset
+	fetchedAt time.Time
+}
+
+func (c *presetCache) get(ctx context.Context, fetch func(ctx context.Context) ([]Preset, error)) ([]Preset, error) {
+	c.Lock()
+	defer c.Unlock()
+
+	if c.presets != nil && time.Since(c.fetchedAt) < presetCacheTTL {
+		return c.presets, nil
+	}
+
+	presets, err := fetch(ctx)
+	if err != nil {
+		return nil, err
+	}
+	c.presets = presets
+	c.fetchedAt = time.Now()
+	return presets, nil
+}
+
+// findPreset ищет пресет по ID
+func findPreset(presets []Preset, id string) *Preset {
+	for i := range presets {
+		if presets[i].ID == id {
+			preset := presets[i]
+			return &preset
+		}
+	}
+	return nil
+}
+
+// NodeGroupCost - стоимость группы нод
+type NodeGroupCost struct {
+	ID          string  `json:"id"`
+	Name        string  `json:"name"`
+	NodeCount   int     `json:"node_count"`
+	Preset      *Preset `json:"preset,omitempty"`
+	HourlyCost  float64 `json:"hourly_cost"`
+	MonthlyCost float64 `json:"monthly_cost"`
+	// Priced - false, если цена пресета группы неизвестна и группа не учтена в сумме
+	Priced bool `json:"priced"`
+}
+
+// ClusterCost - стоимость кластера: control plane и группы нод
+type ClusterCost struct {
+	ID                     string          `json:"id"`
+	Name                   string          `json:"name"`
+	ControlPlaneHourlyCost float64         `json:"control_plane_hourly_cost"`
+	NodeGroups             []NodeGroupCost `json:"node_groups"`
+	HourlyCost             float64         `json:"hourly_cost"`
+	MonthlyCost            float64         `json:"monthly_cost"`
+}
+
+// CostReport - текущие расходы провайдера по кластерам
+type CostReport struct {
+	Provider    string        `json:"provider"`
+	Currency    string        `json:"currency,omitempty"`
+	Clusters    []ClusterCost `json:"clusters"`
+	HourlyCost  float64       `json:"hourly_cost"`
+	MonthlyCost float64       `json:"monthly_cost"`
+}
+
+// ScaleCostImpact - изменение расходов при изменении размера группы нод
+type ScaleCostImpact struct {
+	Provider                    string  `json:"provider"`
+	ClusterID                   string  `json:"cluster_id"`
+	NodeGroupID                 string  `json:"group_id"`
+	CurrentNodes                int     `json:"current_nodes"`
+	TargetNodes                 int     `json:"target_nodes"`
+	NodeHourlyCost              float64 `json:"node_hourly_cost"`
+	CurrentMonthlyCost          float64 `json:"current_monthly_cost"`
+	ProjectedMonthlyCost        float64 `json:"projected_monthly_cost"`
+	DeltaMonthlyCost            float64 `json:"delta_monthly_cost"`
+	ClusterCurrentMonthlyCost   float64 `json:"cluster_current_monthly_cost"`
+	ClusterProjectedMonthlyCost float64 `json:"cluster_projected_monthly_cost"`
+	Currency                    string  `json:"currency,omitempty"`
+}
+
+// roundCost округляет сумму до копеек/центов
+func roundCost(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+// buildCostReport собирает стоимость кластеров провайдера (или одного кластера, если clusterID задан)
+func buildCostReport(ctx context.Context, providerName string, provider ClusterProvider, clusterID string) (*CostReport, error) {
+	clusters, err := provider.ListClusters(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &CostReport{Provider: providerName, Clusters: []ClusterCost{}}
+	found := false
+	for _, cluster := range clusters {
+		if clusterID != "" && cluster.ID != clusterID {
+			continue
+		}
+		found = true
+
+		groups, err := provider.GetNodeGroups(ctx, cluster.ID)
+		if err != nil {
+			return nil, err
+		}
+
+		clusterCost := ClusterCost{
+			ID:                     cluster.ID,
+			Name:                   cluster.Name,
+			ControlPlaneHourlyCost: cluster.HourlyCost,
+			NodeGroups:             []NodeGroupCost{},
+			HourlyCost:             cluster.HourlyCost,
+		}
+		if cluster.Preset != nil && report.Currency == "" {
+			report.Currency = cluster.Preset.Currency
+		}
+
+		for _, group := range groups {
+			groupCost := NodeGroupCost{
+				ID:         group.ID,
+				Name:       group.Name,
+				NodeCount:  group.NodeCount,
+				Preset:     group.Preset,
+				HourlyCost: group.HourlyCost,
+				Priced:     group.Preset != nil,
+			}
+			groupCost.MonthlyCost = roundCost(groupCost.HourlyCost * hoursPerMonth)
+			if group.Preset != nil && report.Currency == "" {
+				report.Currency = group.Preset.Currency
+			}
+
+			clusterCost.HourlyCost += group.HourlyCost
+			clusterCost.NodeGroups = append(clusterCost.NodeGroups, groupCost)
+		}
+		clusterCost.MonthlyCost = roundCost(clusterCost.HourlyCost * hoursPerMonth)
+
+		report.HourlyCost += clusterCost.HourlyCost
+		report.Clusters = append(report.Clusters, clusterCost)
+	}
+
+	if clusterID != "" && !found {
+		return nil, fmt.Errorf("%w: cluster %s not found", ErrProviderNotFound, clusterID)
+	}
+	report.MonthlyCost = roundCost(report.HourlyCost * hoursPerMonth)
+	return report, nil
+}
+
+// estimateScaleCost считает изменение расходов при изменении размера группы до nodeCount
+func estimateScaleCost(ctx context.Context, providerName string, provider ClusterProvider, clusterID, groupID string, nodeCount int) (*ScaleCostImpact, error) {
+	report, err := buildCostReport(ctx, providerName, provider, clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	cluster := report.Clusters[0]
+	for _, group := range cluster.NodeGroups {
+		if group.ID != groupID {
+			continue
+		}
+
+		impact := &ScaleCostImpact{
+			Provider:     providerName,
+			ClusterID:    clusterID,
+			NodeGroupID:  groupID,
+			CurrentNodes: group.NodeCount,
+			TargetNodes:  nodeCount,
+			Currency:     report.Currency,
+		}
+		if group.Preset != nil {
+			impact.NodeHourlyCost = group.Preset.HourlyPrice
+		}
+
+		projectedHourly := impact.NodeHourlyCost * float64(nodeCount)
+		impact.CurrentMonthlyCost = group.MonthlyCost
+		impact.ProjectedMonthlyCost = roundCost(projectedHourly * hoursPerMonth)
+		impact.DeltaMonthlyCost = roundCost(impact.ProjectedMonthlyCost - impact.CurrentMonthlyCost)
+		impact.ClusterCurrentMonthlyCost = cluster.MonthlyCost
+		impact.ClusterProjectedMonthlyCost = roundCost((cluster.HourlyCost - group.HourlyCost + projectedHourly) * hoursPerMonth)
+		return impact, nil
+	}
+	return nil, fmt.Errorf("%w: node group %s not found in cluster %s", ErrProviderNotFound, groupID, clusterID)
+}
+
+// handleCloudCostsRequest обрабатывает /cloud/costs.
+// С group_id и node_count возвращает изменение расходов при масштабировании группы.
+func (r *KubedeckReconciler) handleCloudCostsRequest(w http.ResponseWriter, req *http.Request) {
+	query := req.URL.Query()
+	provider := query.Get("provider")
+	clusterID := query.Get("cluster_id")
+	groupID := query.Get("group_id")
+
+	if provider == "" {
+		http.Error(w, "provider parameter is required", http.StatusBadRequest)
+		return
+	}
+
+	clusterProvider, status, err := r.getClusterProvider(provider)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	if groupID != "" {
+		nodeCount, err := strconv.Atoi(query.Get("node_count"))
+		if err != nil || clusterID == "" {
+			http.Error(w, "cluster_id and a numeric node_count are required with group_id", http.StatusBadRequest)
+			return
+		}
+
+		impact, err := estimateScaleCost(req.Context(), provider, clusterProvider, clusterID, groupID, nodeCount)
+		if err != nil {
+			writeProviderError(w, "Failed to estimate scaling cost", err)
+			return
+		}
+		writeJsonResponse(w, impact, "cost impact", nil)
+		return
+	}
+
+	report, err := buildCostReport(req.Context(), provider, clusterProvider, clusterID)
+	if err != nil {
+		writeProviderError(w, "Failed to calculate costs", err)
+		return
+	}
+	writeJsonResponse(w, report, "costs", nil)
+}
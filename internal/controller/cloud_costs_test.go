@@ -0,0 +1,42 @@
+package controller
+
+import (
+	"errors"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Cloud costs", func() {
+	It("should sum control plane and node group costs", func() {
+		provider := NewFakeProvider(FakeProviderOptions{})
+
+		report, err := buildCostReport(ctx, "fake", provider, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.Currency).To(Equal("RUB"))
+		Expect(report.Clusters).To(HaveLen(1))
+
+		cluster := report.Clusters[0]
+		Expect(cluster.ControlPlaneHourlyCost).To(BeNumerically("==", 2))
+		Expect(cluster.NodeGroups[0].HourlyCost).To(BeNumerically("==", 3))
+		Expect(cluster.NodeGroups[0].MonthlyCost).To(BeNumerically("==", 2190))
+		Expect(report.HourlyCost).To(BeNumerically("==", 9))
+		Expect(report.MonthlyCost).To(BeNumerically("==", 6570))
+
+		_, err = buildCostReport(ctx, "fake", provider, "missing")
+		Expect(errors.Is(err, ErrProviderNotFound)).To(BeTrue())
+	})
+
+	It("should estimate the cost impact of scaling a node group", func() {
+		provider := NewFakeProvider(FakeProviderOptions{})
+
+		impact, err := estimateScaleCost(ctx, "fake", provider, "fake-cluster-1", "fake-ng-general", 5)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(impact.CurrentNodes).To(Equal(2))
+		Expect(impact.NodeHourlyCost).To(BeNumerically("==", 1.5))
+		Expect(impact.CurrentMonthlyCost).To(BeNumerically("==", 2190))
+		Expect(impact.ProjectedMonthlyCost).To(BeNumerically("==", 5475))
+		Expect(impact.DeltaMonthlyCost).To(BeNumerically("==", 3285))
+		Expect(impact.ClusterProjectedMonthlyCost).To(BeNumerically("==", 9855))
+	})
+})
@@ -0,0 +1,338 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrlv1 "nikcorp.ru/kubedeck/api/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller/controllerutil"
+)
+
+const (
+	// cloudProviderLabel - метка CloudCluster и CloudNodeGroup с именем провайдера
+	cloudProviderLabel = "kubedeck.nikcorp.ru/provider"
+
+	// cloudScaleCooldown - через сколько повторяется запрос размера, если провайдер еще не отразил изменение
+	cloudScaleCooldown = 5 * time.Minute
+
+	// cloudScaleBlocked - результат изменения размера, отклоненного ограничениями масштабирования
+	cloudScaleBlocked = "blocked by guardrails"
+)
+
+// cloudSyncInterval - период синхронизации CloudCluster и CloudNodeGroup с провайдерами
+var cloudSyncInterval = getEnvDuration("KUBEDECK_CLOUD_SYNC_INTERVAL", time.Minute)
+
+var invalidResourceNameChars = regexp.MustCompile(`[^a-z0-9-]+`)
+
+// cloudResourceName строит имя объекта из идентификаторов провайдера.
+// Если идентификаторы пришлось изменить, к имени добавляется хеш, чтобы разные группы не совпали.
+func cloudResourceName(parts ...string) string {
+	raw := strings.ToLower(strings.Join(parts, "-"))
+	name := strings.Trim(invalidResourceNameChars.ReplaceAllString(raw, "-"), "-")
+	if name == raw && len(name) <= 63 {
+		return name
+	}
+
+	hash := fnv.New32a()
+	_, _ = hash.Write([]byte(strings.Join(parts, "/")))
+	if len(name) > 54 {
+		name = strings.TrimRight(name[:54], "-")
+	}
+	return fmt.Sprintf("%s-%08x", name, hash.Sum32())
+}
+
+// formatHourlyCost форматирует стоимость для статуса; неизвестная стоимость не выводится
+func formatHourlyCost(cost float64) string {
+	if cost == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(cost, 'f', 2, 64)
+}
+
+// runCloudResourceSync периодически синхронизирует CloudCluster и CloudNodeGroup с провайдерами
+func (r *KubedeckReconciler) runCloudResourceSync(ctx context.Context) error {
+	log := webServerLog.WithName("cloud-sync")
+	namespace := getEnvString("KUBEDECK_CLOUD_RESOURCES_NAMESPACE", "kubedeck-system")
+
+	ticker := time.NewTicker(cloudSyncInterval)
+	defer ticker.Stop()
+	for {
+		if err := r.syncCloudResources(ctx, namespace); err != nil {
+			log.Error(err, "Failed to sync cloud resources")
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// syncCloudResources обновляет объекты всех провайдеров. Ошибка одного провайдера не мешает остальным.
+func (r *KubedeckReconciler) syncCloudResources(ctx context.Context, namespace string) error {
+	names := make([]string, 0, len(r.providers))
+	for name := range r.providers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var errs []error
+	for _, name := range names {
+		if r.providers[name] == nil {
+			continue
+		}
+		if err := r.syncProviderResources(ctx, namespace, name, r.providers[name]); err != nil {
+			errs = append(errs, fmt.Errorf("provider %s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// syncProviderResources создает и обновляет объекты кластеров и групп нод провайдера
+// и удаляет объекты тех, которых у провайдера больше нет
+func (r *KubedeckReconciler) syncProviderResources(ctx context.Context, namespace, providerName string, provider ClusterProvider) error {
+	clusters, err := provider.ListClusters(ctx)
+	if err != nil {
+		return err
+	}
+
+	now := metav1.Now()
+	seen := make(map[string]bool, len(clusters))
+	var errs []error
+	for _, cluster := range clusters {
+		// Кластеры без групп нод тоже отражаются, поэтому ошибка групп не прерывает синхронизацию кластера
+		groups, groupsErr := provider.GetNodeGroups(ctx, cluster.ID)
+
+		cloudCluster, err := r.syncCloudCluster(ctx, namespace, providerName, cluster, len(groups), now)
+		seen[cloudCluster.Name] = true
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if groupsErr != nil {
+			errs = append(errs, fmt.Errorf("cluster %s: %w", cluster.ID, groupsErr))
+			continue
+		}
+		if err := r.syncCloudNodeGroups(ctx, cloudCluster, groups, now); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	existing := &ctrlv1.CloudClusterList{}
+	if err := r.List(ctx, existing, client.InNamespace(namespace), client.MatchingLabels{cloudProviderLabel: providerName}); err != nil {
+		return errors.Join(append(errs, err)...)
+	}
+	for i := range existing.Items {
+		// Группы нод удаляются сборщиком мусора по ownerReference
+		if !seen[existing.Items[i].Name] {
+			if err := r.Delete(ctx, &existing.Items[i]); client.IgnoreNotFound(err) != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// syncCloudCluster создает или обновляет CloudCluster
+func (r *KubedeckReconciler) syncCloudCluster(ctx context.Context, namespace, providerName string, cluster Cluster, nodeGroups int, now metav1.Time) (*ctrlv1.CloudCluster, error) {
+	obj := &ctrlv1.CloudCluster{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      cloudResourceName(providerName, cluster.ID),
+			Namespace: namespace,
+			Labels:    map[string]string{cloudProviderLabel: providerName},
+		},
+		Spec: ctrlv1.CloudClusterSpec{Provider: providerName, ClusterID: cluster.ID},
+	}
+	if err := r.createIfNotExists(ctx, obj); err != nil {
+		return obj, err
+	}
+
+	obj.Status = ctrlv1.CloudClusterStatus{
+		DisplayName:  cluster.Name,
+		Phase:        cluster.Status,
+		HourlyCost:   formatHourlyCost(cluster.HourlyCost),
+		NodeGroups:   int32(nodeGroups),
+		LastSyncTime: &now,
+	}
+	if cluster.Preset != nil {
+		obj.Status.Preset = cluster.Preset.ID
+	}
+	return obj, r.Status().Update(ctx, obj)
+}
+
+// syncCloudNodeGroups создает или обновляет CloudNodeGroup кластера. Spec существующих объектов не изменяется:
+// desiredNodeCount задает пользователь.
+func (r *KubedeckReconciler) syncCloudNodeGroups(ctx context.Context, cloudCluster *ctrlv1.CloudCluster, groups []NodeGroup, now metav1.Time) error {
+	providerName, clusterID := cloudCluster.Spec.Provider, cloudCluster.Spec.ClusterID
+
+	var errs []error
+	seen := make(map[string]bool, len(groups))
+	for _, group := range groups {
+		obj := &ctrlv1.CloudNodeGroup{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      cloudResourceName(providerName, clusterID, group.ID),
+				Namespace: cloudCluster.Namespace,
+				Labels:    map[string]string{cloudProviderLabel: providerName},
+			},
+			Spec: ctrlv1.CloudNodeGroupSpec{
+				NodeGroupReference: ctrlv1.NodeGroupReference{Provider: providerName, ClusterID: clusterID, NodeGroupID: group.ID},
+			},
+		}
+		seen[obj.Name] = true
+		if err := controllerutil.SetControllerReference(cloudCluster, obj, r.Scheme); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		if err := r.createIfNotExists(ctx, obj); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		// Поля результата масштабирования принадлежат reconcileCloudNodeGroup и сохраняются
+		status := obj.Status
+		status.DisplayName = group.Name
+		status.NodeCount = int32(group.NodeCount)
+		status.HourlyCost = formatHourlyCost(group.HourlyCost)
+		status.NodeLabels = group.NodeLabels
+		status.LastSyncTime = &now
+		status.Preset = ""
+		if group.Preset != nil {
+			status.Preset = group.Preset.ID
+		}
+		obj.Status = status
+		if err := r.Status().Update(ctx, obj); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	existing := &ctrlv1.CloudNodeGroupList{}
+	if err := r.List(ctx, existing, client.InNamespace(cloudCluster.Namespace), client.MatchingLabels{cloudProviderLabel: providerName}); err != nil {
+		return errors.Join(append(errs, err)...)
+	}
+	for i := range existing.Items {
+		item := &existing.Items[i]
+		if item.Spec.ClusterID == clusterID && !seen[item.Name] {
+			if err := r.Delete(ctx, item); client.IgnoreNotFound(err) != nil {
+				errs = append(errs, err)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// createIfNotExists создает объект или загружает существующий в obj
+func (r *KubedeckReconciler) createIfNotExists(ctx context.Context, obj client.Object) error {
+	err := r.Create(ctx, obj)
+	if apierrors.IsAlreadyExists(err) {
+		return r.Get(ctx, client.ObjectKeyFromObject(obj), obj)
+	}
+	return err
+}
+
+// +kubebuilder:rbac:groups=ctrl.nikcorp.ru,resources=cloudclusters;cloudnodegroups,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=ctrl.nikcorp.ru,resources=cloudclusters/status;cloudnodegroups/status,verbs=get;update;patch
+
+// reconcileCloudNodeGroup приводит размер группы нод к spec.desiredNodeCount через ScaleNodeGroup
+// с учетом ограничений масштабирования
+func (r *KubedeckReconciler) reconcileCloudNodeGroup(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	group := &ctrlv1.CloudNodeGroup{}
+	if err := r.Get(ctx, req.NamespacedName, group); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	// Размер группы известен только после синхронизации с провайдером
+	if group.Spec.DesiredNodeCount == nil || group.Status.LastSyncTime == nil {
+		return ctrl.Result{}, nil
+	}
+	desired := *group.Spec.DesiredNodeCount
+	current := group.Status.NodeCount
+	if desired == current {
+		return ctrl.Result{}, nil
+	}
+
+	// Провайдер может отражать новый размер с задержкой: повторный запрос того же размера откладывается.
+	// Заблокированный ограничениями размер повторно не запрашивается, пока не изменится desiredNodeCount.
+	now := time.Now()
+	if applied := group.Status.AppliedNodeCount; applied != nil && *applied == desired && group.Status.LastScaleTime != nil {
+		if group.Status.LastResult == cloudScaleBlocked {
+			return ctrl.Result{}, nil
+		}
+		if wait := cloudScaleCooldown - now.Sub(group.Status.LastScaleTime.Time); wait > 0 {
+			return ctrl.Result{RequeueAfter: wait}, nil
+		}
+	}
+
+	ref := group.Spec.NodeGroupReference
+	result, err := r.scaleCloudNodeGroup(ctx, ref, int(current), int(desired))
+
+	group.Status.AppliedNodeCount = &desired
+	group.Status.LastScaleTime = &metav1.Time{Time: now}
+	group.Status.LastResult = result
+	group.Status.LastError = ""
+	if err != nil {
+		group.Status.LastError = err.Error()
+	}
+	if updateErr := r.Status().Update(ctx, group); updateErr != nil {
+		return ctrl.Result{}, updateErr
+	}
+
+	message := fmt.Sprintf("📐 *Размер группы нод* `%s/%s/%s`\nЗапрошено: %d -> %d (CloudNodeGroup `%s/%s`)\nРезультат: %s",
+		ref.Provider, ref.ClusterID, ref.NodeGroupID, current, desired, group.Namespace, group.Name, result)
+	if err != nil {
+		message += fmt.Sprintf("\n❌ Ошибка: `%s`", err.Error())
+	}
+	r.broadcastTelegramMessage(message)
+
+	// Результат проверяется при следующей синхронизации статуса с провайдером
+	return ctrl.Result{}, nil
+}
+
+// scaleCloudNodeGroup изменяет размер группы, проверяя ограничения масштабирования
+func (r *KubedeckReconciler) scaleCloudNodeGroup(ctx context.Context, ref ctrlv1.NodeGroupReference, current, target int) (string, error) {
+	provider, _, err := r.getClusterProvider(ref.Provider)
+	if err != nil {
+		return "failed", err
+	}
+
+	change := ScaleChange{Provider: ref.Provider, ClusterID: ref.ClusterID, NodeGroupID: ref.NodeGroupID, Current: current, Target: target}
+	if err := r.checkAutomatedScaling(ctx, provider, change); err != nil {
+		return cloudScaleBlocked, err
+	}
+
+	// Уменьшение у провайдеров с NodeDeleter идет через drain выбранных нод, как в /cloud/scale
+	if _, ok := provider.(NodeDeleter); ok && target < current {
+		group, err := findNodeGroup(ctx, provider, ref)
+		if err != nil {
+			return "failed", err
+		}
+		groupCtx, err := r.nodeGroupContext(ctx, ref.Provider, ref.ClusterID)
+		if err != nil {
+			return "failed", err
+		}
+		op, err := r.resizeNodeGroup(groupCtx, ref.Provider, provider, ref.ClusterID, *group, target)
+		if err != nil {
+			return "failed", err
+		}
+		if op != nil {
+			return fmt.Sprintf("scaling in %d -> %d (operation %s)", group.NodeCount, target, op.ID), nil
+		}
+		return fmt.Sprintf("scaled %d -> %d", group.NodeCount, target), nil
+	}
+
+	if err := provider.ScaleNodeGroup(ctx, ref.ClusterID, ref.NodeGroupID, target); err != nil {
+		return "failed", err
+	}
+	return fmt.Sprintf("scaled %d -> %d", current, target), nil
+}
@@ -0,0 +1,136 @@
+package controller
+
+import (
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/kubernetes/scheme"
+	ctrlv1 "nikcorp.ru/kubedeck/api/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Cloud resources", func() {
+	It("should build valid object names from provider ids", func() {
+		Expect(cloudResourceName("fake", "fake-cluster-1")).To(Equal("fake-fake-cluster-1"))
+
+		capi := cloudResourceName("capi", "default/My_Cluster", "md-0")
+		Expect(capi).To(MatchRegexp(`^capi-default-my-cluster-md-0-[0-9a-f]{8}$`))
+		Expect(capi).NotTo(Equal(cloudResourceName("capi", "default/my-cluster", "md-0")))
+
+		long := cloudResourceName("yandex", "cat0123456789abcdefghij", "cat0123456789abcdefghij-group-with-a-long-name")
+		Expect(len(long)).To(BeNumerically("<=", 63))
+	})
+
+	It("should sync provider clusters and reconcile the desired node count", func() {
+		provider := NewFakeProvider(FakeProviderOptions{})
+		reconciler := &KubedeckReconciler{
+			Client:            k8sClient,
+			Scheme:            scheme.Scheme,
+			providers:         map[string]ClusterProvider{"fake": provider},
+			GuardrailSettings: NewGuardrailSettings(),
+		}
+		defer func() {
+			_ = k8sClient.DeleteAllOf(ctx, &ctrlv1.CloudNodeGroup{}, client.InNamespace("default"))
+			_ = k8sClient.DeleteAllOf(ctx, &ctrlv1.CloudCluster{}, client.InNamespace("default"))
+		}()
+
+		Expect(reconciler.syncCloudResources(ctx, "default")).To(Succeed())
+
+		cluster := &ctrlv1.CloudCluster{}
+		Expect(k8sClient.Get(ctx, types.NamespacedName{Namespace: "default", Name: "fake-fake-cluster-1"}, cluster)).To(Succeed())
+		Expect(cluster.Spec.ClusterID).To(Equal("fake-cluster-1"))
+		Expect(cluster.Status.DisplayName).To(Equal("fake-dev"))
+		Expect(cluster.Status.NodeGroups).To(Equal(int32(2)))
+
+		key := types.NamespacedName{Namespace: "default", Name: "fake-fake-cluster-1-fake-ng-general"}
+		group := &ctrlv1.CloudNodeGroup{}
+		Expect(k8sClient.Get(ctx, key, group)).To(Succeed())
+		Expect(group.Status.NodeCount).To(Equal(int32(2)))
+		Expect(group.Status.Preset).To(Equal("fake-general"))
+		Expect(metav1.IsControlledBy(group, cluster)).To(BeTrue())
+
+		// Без desiredNodeCount размер не изменяется
+		_, err := reconciler.reconcileCloudNodeGroup(ctx, ctrl.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+
+		desired := int32(4)
+		group.Spec.DesiredNodeCount = &desired
+		Expect(k8sClient.Update(ctx, group)).To(Succeed())
+		_, err = reconciler.reconcileCloudNodeGroup(ctx, ctrl.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+
+		size, err := nodeGroupSize(ctx, provider, group.Spec.NodeGroupReference)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(size).To(Equal(4))
+
+		// Синхронизация сохраняет spec и результат масштабирования
+		Expect(reconciler.syncCloudResources(ctx, "default")).To(Succeed())
+		Expect(k8sClient.Get(ctx, key, group)).To(Succeed())
+		Expect(*group.Spec.DesiredNodeCount).To(Equal(int32(4)))
+		Expect(group.Status.NodeCount).To(Equal(int32(4)))
+		Expect(group.Status.LastResult).To(Equal("scaled 2 -> 4"))
+
+		// Ограничения масштабирования блокируют изменение, повтор не выполняется
+		maxNodes := 5
+		reconciler.GuardrailSettings.SetRules([]ScalingGuardrail{{MaxNodes: &maxNodes}})
+		desired = 8
+		group.Spec.DesiredNodeCount = &desired
+		Expect(k8sClient.Update(ctx, group)).To(Succeed())
+		for range 2 {
+			result, err := reconciler.reconcileCloudNodeGroup(ctx, ctrl.Request{NamespacedName: key})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(result.RequeueAfter).To(Equal(time.Duration(0)))
+		}
+		Expect(k8sClient.Get(ctx, key, group)).To(Succeed())
+		Expect(group.Status.LastResult).To(Equal(cloudScaleBlocked))
+		Expect(group.Status.LastError).NotTo(BeEmpty())
+		size, _ = nodeGroupSize(ctx, provider, group.Spec.NodeGroupReference)
+		Expect(size).To(Equal(4))
+	})
+	It("should drain nodes when the desired node count decreases", func() {
+		DeferCleanup(func(poll time.Duration) { drainPollInterval = poll }, drainPollInterval)
+		drainPollInterval = 20 * time.Millisecond
+
+		provider := NewFakeProvider(FakeProviderOptions{ScaleDelay: 10 * time.Millisecond, ManageNodes: true, Client: k8sClient})
+		Expect(provider.Start(ctx)).To(Succeed())
+		reconciler := &KubedeckReconciler{
+			Client:            k8sClient,
+			Scheme:            scheme.Scheme,
+			providers:         map[string]ClusterProvider{"fake": provider},
+			GuardrailSettings: NewGuardrailSettings(),
+		}
+		DeferCleanup(func() {
+			_ = k8sClient.DeleteAllOf(ctx, &ctrlv1.CloudNodeGroup{}, client.InNamespace("default"))
+			_ = k8sClient.DeleteAllOf(ctx, &ctrlv1.CloudCluster{}, client.InNamespace("default"))
+			_ = k8sClient.DeleteAllOf(ctx, &corev1.Node{}, client.HasLabels{FakeNodeGroupLabel})
+		})
+
+		Expect(reconciler.syncCloudResources(ctx, "default")).To(Succeed())
+		key := types.NamespacedName{Namespace: "default", Name: "fake-fake-cluster-1-fake-ng-general"}
+		group := &ctrlv1.CloudNodeGroup{}
+		Expect(k8sClient.Get(ctx, key, group)).To(Succeed())
+
+		desired := int32(1)
+		group.Spec.DesiredNodeCount = &desired
+		Expect(k8sClient.Update(ctx, group)).To(Succeed())
+		_, err := reconciler.reconcileCloudNodeGroup(ctx, ctrl.Request{NamespacedName: key})
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(k8sClient.Get(ctx, key, group)).To(Succeed())
+		Expect(group.Status.LastError).To(BeEmpty())
+		Expect(group.Status.LastResult).To(HavePrefix("scaling in 2 -> 1 (operation "))
+		operations := reconciler.scaleIns.list()
+		Expect(operations).To(HaveLen(1))
+		Expect(operations[0].Nodes).To(HaveLen(1))
+		Eventually(func() int {
+			size, err := nodeGroupSize(ctx, provider, group.Spec.NodeGroupReference)
+			Expect(err).NotTo(HaveOccurred())
+			return size
+		}).Should(Equal(1))
+	})
+})
@@ -0,0 +1,500 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Cloud providers", func() {
+	nodeWithIP := func(name, ip string) corev1.Node {
+		return corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name},
+			Status:     corev1.NodeStatus{Addresses: []corev1.NodeAddress{{Type: corev1.NodeInternalIP, Address: ip}}},
+		}
+	}
+	nodeWithProviderID := func(name, providerID string) corev1.Node {
+		return corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}, Spec: corev1.NodeSpec{ProviderID: providerID}}
+	}
+
+	Context("TimeWeb", func() {
+		It("should follow limit/offset pagination until meta.total", func() {
+			const total = 250
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				offset, _ := strconv.Atoi(req.URL.Query().Get("offset"))
+				limit, _ := strconv.Atoi(req.URL.Query().Get("limit"))
+
+				clusters := []map[string]interface{}{}
+				for i := offset; i < total && i < offset+limit; i++ {
+					clusters = append(clusters, map[string]interface{}{"id": i, "name": fmt.Sprintf("cluster-%d", i)})
+				}
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{
+					"meta":     map[string]int{"total": total},
+					"clusters": clusters,
+				})
+			}))
+			defer server.Close()
+
+			provider := &TimeWebProvider{baseURL: server.URL, client: server.Client()}
+			clusters, err := provider.ListClusters(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(clusters).To(HaveLen(total))
+			Expect(clusters[total-1].ID).To(Equal(strconv.Itoa(total - 1)))
+		})
+
+		It("should return a typed error instead of an empty list on 401", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				w.WriteHeader(http.StatusUnauthorized)
+				_, _ = w.Write([]byte(`{"status_code":401,"error_code":"unauthorized","message":"Invalid token"}`))
+			}))
+			defer server.Close()
+
+			provider := &TimeWebProvider{baseURL: server.URL, client: server.Client()}
+			_, err := provider.ListClusters(ctx)
+			Expect(errors.Is(err, ErrProviderUnauthorized)).To(BeTrue())
+			Expect(err.Error()).To(ContainSubstring("Invalid token"))
+			Expect(providerErrorStatus(err)).To(Equal(http.StatusUnauthorized))
+		})
+
+		It("should price node groups from the preset catalog", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				switch req.URL.Path {
+				case "/presets/k8s":
+					_, _ = w.Write([]byte(`{"k8s_presets":[{"id":7,"description_short":"2 CPU, 4 GB","price":1460,"cpu":2,"ram":4096,"disk":40,"type":"worker"}]}`))
+				default:
+					_, _ = w.Write([]byte(`{"meta":{"total":1},"node_groups":[{"id":1,"name":"workers","preset_id":7,"node_count":3}]}`))
+				}
+			}))
+			defer server.Close()
+
+			provider := &TimeWebProvider{baseURL: server.URL, client: server.Client()}
+			groups, err := provider.GetNodeGroups(ctx, "42")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(groups[0].Preset).NotTo(BeNil())
+			Expect(groups[0].Preset.HourlyPrice).To(BeNumerically("==", 2))
+			Expect(groups[0].HourlyCost).To(BeNumerically("==", 6))
+		})
+
+		It("should scale a node group to an absolute size by adding or removing the difference", func() {
+			var calls []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				switch {
+				case req.URL.Path == "/presets/k8s":
+					_, _ = w.Write([]byte(`{"k8s_presets":[]}`))
+				case req.Method == http.MethodGet && req.URL.Path == "/k8s/clusters/42/groups":
+					_, _ = w.Write([]byte(`{"meta":{"total":1},"node_groups":[{"id":7,"name":"workers","node_count":3}]}`))
+				case req.URL.Path == "/k8s/clusters/42/groups/7/nodes":
+					var body map[string]int
+					Expect(json.NewDecoder(req.Body).Decode(&body)).To(Succeed())
+					calls = append(calls, fmt.Sprintf("%s count=%d", req.Method, body["count"]))
+					w.WriteHeader(http.StatusCreated)
+				default:
+					Fail("unexpected request " + req.Method + " " + req.URL.Path)
+				}
+			}))
+			defer server.Close()
+
+			provider := &TimeWebProvider{baseURL: server.URL, client: server.Client()}
+			Expect(provider.ScaleNodeGroup(ctx, "42", "7", 5)).To(Succeed())
+			Expect(provider.ScaleNodeGroup(ctx, "42", "7", 1)).To(Succeed())
+			Expect(provider.ScaleNodeGroup(ctx, "42", "7", 3)).To(Succeed())
+			Expect(calls).To(Equal([]string{"POST count=2", "DELETE count=2"}))
+
+			// Отрицательный размер больше не означает удаление нод
+			Expect(errors.Is(provider.ScaleNodeGroup(ctx, "42", "7", -1), ErrProviderBadRequest)).To(BeTrue())
+			Expect(errors.Is(provider.ScaleNodeGroup(ctx, "42", "8", 2), ErrProviderNotFound)).To(BeTrue())
+			Expect(calls).To(HaveLen(2))
+		})
+
+		It("should delete exactly the drained nodes matched by their internal IP", func() {
+			var deleted []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				switch {
+				case req.Method == http.MethodGet && req.URL.Path == "/k8s/clusters/42/groups/7/nodes":
+					// Вторая страница нужна чтобы убедиться что поиск идет по всей группе
+					if req.URL.Query().Get("offset") == "0" {
+						_, _ = w.Write([]byte(`{"meta":{"total":3},"nodes":[{"id":101,"node_ip":"10.0.0.1"},{"id":102,"node_ip":"10.0.0.2"}]}`))
+						return
+					}
+					_, _ = w.Write([]byte(`{"meta":{"total":3},"nodes":[{"id":103,"node_ip":"10.0.0.3"}]}`))
+				case req.Method == http.MethodDelete:
+					deleted = append(deleted, req.URL.Path)
+					w.WriteHeader(http.StatusNoContent)
+				default:
+					Fail("unexpected request " + req.Method + " " + req.URL.Path)
+				}
+			}))
+			defer server.Close()
+
+			provider := &TimeWebProvider{baseURL: server.URL, client: server.Client()}
+			Expect(provider.DeleteNodes(ctx, "42", "7", []corev1.Node{
+				nodeWithIP("worker-1", "10.0.0.1"), nodeWithIP("worker-3", "10.0.0.3"),
+			})).To(Succeed())
+			Expect(deleted).To(Equal([]string{"/k8s/clusters/42/nodes/101", "/k8s/clusters/42/nodes/103"}))
+
+			// Нода без совпадающего IP не должна приводить к удалению чужой ноды
+			deleted = nil
+			err := provider.DeleteNodes(ctx, "42", "7", []corev1.Node{nodeWithIP("stranger", "10.0.9.9")})
+			Expect(errors.Is(err, ErrProviderNotFound)).To(BeTrue())
+			Expect(err).To(MatchError(ContainSubstring("stranger")))
+			err = provider.DeleteNodes(ctx, "42", "7", []corev1.Node{{ObjectMeta: metav1.ObjectMeta{Name: "no-address"}}})
+			Expect(errors.Is(err, ErrProviderNotFound)).To(BeTrue())
+			Expect(deleted).To(BeEmpty())
+		})
+
+		It("should expose node labels only when the node group label is configured", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				switch req.URL.Path {
+				case "/presets/k8s":
+					_, _ = w.Write([]byte(`{"k8s_presets":[]}`))
+				default:
+					_, _ = w.Write([]byte(`{"meta":{"total":1},"node_groups":[{"id":7,"name":"workers","node_count":3}]}`))
+				}
+			}))
+			defer server.Close()
+
+			provider := &TimeWebProvider{baseURL: server.URL, client: server.Client()}
+			groups, err := provider.GetNodeGroups(ctx, "42")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(groups).To(HaveLen(1))
+			Expect(groups[0].NodeLabels).To(BeEmpty())
+
+			provider.nodeGroupLabel = "example.com/node-group"
+			groups, err = provider.GetNodeGroups(ctx, "42")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(groups[0].NodeLabels).To(Equal(map[string]string{"example.com/node-group": "7"}))
+		})
+
+		It("should fetch the raw kubeconfig of a cluster", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				Expect(req.URL.Path).To(Equal("/k8s/clusters/42/kubeconfig"))
+				_, _ = w.Write([]byte("apiVersion: v1\nkind: Config\n"))
+			}))
+			defer server.Close()
+
+			provider := &TimeWebProvider{baseURL: server.URL, client: server.Client()}
+			kubeconfig, err := provider.GetKubeconfig(ctx, "42")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(kubeconfig)).To(Equal("apiVersion: v1\nkind: Config\n"))
+		})
+
+		It("should create a cluster and wait until it is started", func() {
+			defer func(interval time.Duration) { operationPollInterval = interval }(operationPollInterval)
+			operationPollInterval = time.Millisecond
+
+			var polls int
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				switch {
+				case req.Method == http.MethodPost && req.URL.Path == "/k8s/clusters":
+					var body map[string]interface{}
+					Expect(json.NewDecoder(req.Body).Decode(&body)).To(Succeed())
+					Expect(body).To(HaveKeyWithValue("k8s_version", "v1.30.4+k0s.0"))
+					Expect(body).To(HaveKeyWithValue("network_driver", "flannel"))
+					Expect(body["worker_groups"]).To(HaveLen(1))
+					_, _ = w.Write([]byte(`{"cluster":{"id":77}}`))
+				case req.Method == http.MethodGet && req.URL.Path == "/k8s/clusters/77":
+					polls++
+					status := "installing"
+					if polls == 3 {
+						status = "started"
+					}
+					_, _ = fmt.Fprintf(w, `{"cluster":{"status":%q,"k8s_version":"v1.30.4+k0s.0"}}`, status)
+				default:
+					Fail("unexpected request " + req.Method + " " + req.URL.Path)
+				}
+			}))
+			defer server.Close()
+
+			provider := &TimeWebProvider{baseURL: server.URL, client: server.Client()}
+			var steps []string
+			clusterID, err := provider.CreateCluster(ctx, ClusterSpec{
+				Name: "staging", Version: "v1.30.4+k0s.0", MasterPresetID: "1",
+				NodeGroups: []NodeGroupSpec{{Name: "general", PresetID: "2", NodeCount: 2}},
+			}, func(clusterID, message string) { steps = append(steps, clusterID+": "+message) })
+			Expect(err).NotTo(HaveOccurred())
+			Expect(clusterID).To(Equal("77"))
+			Expect(polls).To(Equal(3))
+			Expect(steps).To(HaveLen(1))
+
+			_, err = provider.CreateCluster(ctx, ClusterSpec{Name: "staging", Version: "v1.30", MasterPresetID: "small"}, func(string, string) {})
+			Expect(errors.Is(err, ErrProviderBadRequest)).To(BeTrue())
+		})
+
+		It("should wait for a deleted cluster to disappear", func() {
+			defer func(interval time.Duration) { operationPollInterval = interval }(operationPollInterval)
+			operationPollInterval = time.Millisecond
+
+			var deleted bool
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				Expect(req.URL.Path).To(Equal("/k8s/clusters/77"))
+				if req.Method == http.MethodDelete {
+					deleted = true
+					w.WriteHeader(http.StatusNoContent)
+					return
+				}
+				http.Error(w, `{"message":"cluster not found"}`, http.StatusNotFound)
+			}))
+			defer server.Close()
+
+			provider := &TimeWebProvider{baseURL: server.URL, client: server.Client()}
+			Expect(provider.DeleteCluster(ctx, "77", func(string, string) {})).To(Succeed())
+			Expect(deleted).To(BeTrue())
+		})
+
+		It("should treat a missing cluster as already deleted", func() {
+			var requests int
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				requests++
+				Expect(req.Method).To(Equal(http.MethodDelete))
+				http.Error(w, `{"message":"cluster not found"}`, http.StatusNotFound)
+			}))
+			defer server.Close()
+
+			provider := &TimeWebProvider{baseURL: server.URL, client: server.Client()}
+			var steps []string
+			Expect(provider.DeleteCluster(ctx, "77", func(clusterID, message string) { steps = append(steps, message) })).To(Succeed())
+			Expect(requests).To(Equal(1))
+			Expect(steps).To(Equal([]string{"cluster is already deleted"}))
+		})
+	})
+
+	Context("Yandex Cloud", func() {
+		It("should treat a missing cluster as already deleted", func() {
+			var requests int
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				requests++
+				Expect(req.Method).To(Equal(http.MethodDelete))
+				Expect(req.URL.Path).To(Equal("/clusters/cat-1"))
+				http.Error(w, `{"code":5,"message":"Cluster cat-1 not found"}`, http.StatusNotFound)
+			}))
+			defer server.Close()
+
+			provider := &YandexCloudProvider{baseURL: server.URL, operationURL: server.URL, client: server.Client()}
+			Expect(provider.DeleteCluster(ctx, "cat-1", func(string, string) {})).To(Succeed())
+			Expect(requests).To(Equal(1))
+		})
+
+		It("should follow nextPageToken", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				response := map[string]interface{}{
+					"nodeGroups": []map[string]interface{}{{"id": "ng-" + req.URL.Query().Get("pageToken")}},
+				}
+				if req.URL.Query().Get("pageToken") == "" {
+					response["nextPageToken"] = "2"
+				}
+				_ = json.NewEncoder(w).Encode(response)
+			}))
+			defer server.Close()
+
+			provider := &YandexCloudProvider{baseURL: server.URL, client: server.Client()}
+			groups, err := provider.GetNodeGroups(ctx, "cluster")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(groups).To(HaveLen(2))
+			Expect(groups[1].ID).To(Equal("ng-2"))
+		})
+
+		It("should price node groups by the rate card", func() {
+			prices := &yandexPriceList{}
+			Expect(json.Unmarshal([]byte(defaultYandexPrices), prices)).To(Succeed())
+
+			preset := prices.preset("standard-v3", yandexResources{Memory: "8589934592", Cores: "2", CoreFraction: "100"})
+			Expect(preset).NotTo(BeNil())
+			Expect(preset.ID).To(Equal("standard-v3/2c/8g/100"))
+			Expect(preset.RAM).To(Equal(8192))
+			Expect(preset.HourlyPrice).To(BeNumerically("~", 2*1.12+8*0.30, 1e-9))
+
+			Expect(prices.preset("gpu-standard-v3", yandexResources{Cores: "8"})).To(BeNil())
+		})
+
+		It("should build a kubeconfig from the master endpoint and the IAM token", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				Expect(req.URL.Path).To(Equal("/clusters/cat123"))
+				_, _ = w.Write([]byte(`{"master":{"endpoints":{"internalV4Endpoint":"https://10.0.0.1","externalV4Endpoint":"https://51.250.0.1"},
+					"masterAuth":{"clusterCaCertificate":"-----BEGIN CERTIFICATE-----\nCA\n-----END CERTIFICATE-----\n"}}}`))
+			}))
+			defer server.Close()
+
+			provider := &YandexCloudProvider{baseURL: server.URL, client: server.Client(), token: "iam-token"}
+			kubeconfig, err := provider.GetKubeconfig(ctx, "cat123")
+			Expect(err).NotTo(HaveOccurred())
+
+			config, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(config.Host).To(Equal("https://51.250.0.1"))
+			Expect(config.BearerToken).To(Equal("iam-token"))
+			Expect(string(config.CAData)).To(ContainSubstring("BEGIN CERTIFICATE"))
+		})
+
+		It("should upgrade the control plane before node groups and wait for operations", func() {
+			defer func(interval time.Duration) { operationPollInterval = interval }(operationPollInterval)
+			operationPollInterval = time.Millisecond
+
+			var calls []string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				calls = append(calls, req.Method+" "+req.URL.Path)
+				switch {
+				case req.Method == http.MethodPatch && req.URL.Path == "/clusters/cat123":
+					var body map[string]interface{}
+					Expect(json.NewDecoder(req.Body).Decode(&body)).To(Succeed())
+					Expect(body).To(HaveKeyWithValue("updateMask", "masterSpec.version.version"))
+					_, _ = w.Write([]byte(`{"id":"op-master","done":false}`))
+				case req.Method == http.MethodGet && req.URL.Path == "/operations/op-master":
+					_, _ = w.Write([]byte(`{"id":"op-master","done":true}`))
+				case req.Method == http.MethodGet && req.URL.Path == "/nodeGroups":
+					_, _ = w.Write([]byte(`{"nodeGroups":[{"id":"ng-1","name":"general"}]}`))
+				case req.Method == http.MethodPatch && req.URL.Path == "/nodeGroups/ng-1":
+					_, _ = w.Write([]byte(`{"id":"op-ng","done":true,"error":{"code":9,"message":"quota exceeded"}}`))
+				default:
+					Fail("unexpected request " + req.Method + " " + req.URL.Path)
+				}
+			}))
+			defer server.Close()
+
+			provider := &YandexCloudProvider{baseURL: server.URL, operationURL: server.URL, client: server.Client()}
+			err := provider.UpgradeCluster(ctx, "cat123", "1.30", func(string, string) {})
+			Expect(err).To(MatchError(ContainSubstring("quota exceeded")))
+			Expect(calls).To(Equal([]string{
+				"PATCH /clusters/cat123", "GET /operations/op-master", "GET /nodeGroups", "PATCH /nodeGroups/ng-1",
+			}))
+		})
+
+		It("should delete managed instances matched by the node provider ID", func() {
+			var requests []string
+			var deleteBody map[string][]string
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				requests = append(requests, req.Method+" "+req.URL.Path)
+				switch {
+				case req.Method == http.MethodGet && req.URL.Path == "/nodeGroups/ng-1":
+					_, _ = w.Write([]byte(`{"id":"ng-1","instanceGroupId":"ig-1"}`))
+				case req.Method == http.MethodGet && req.URL.Path == "/instanceGroups/ig-1/instances":
+					if req.URL.Query().Get("pageToken") == "" {
+						_, _ = w.Write([]byte(`{"instances":[{"id":"mi-1","instanceId":"fhm1"}],"nextPageToken":"2"}`))
+						return
+					}
+					_, _ = w.Write([]byte(`{"instances":[{"id":"mi-2","instanceId":"fhm2"}]}`))
+				case req.Method == http.MethodPost && req.URL.Path == "/instanceGroups/ig-1:deleteInstances":
+					Expect(json.NewDecoder(req.Body).Decode(&deleteBody)).To(Succeed())
+					_, _ = w.Write([]byte(`{"id":"op-1","done":false}`))
+				default:
+					Fail("unexpected request " + req.Method + " " + req.URL.Path)
+				}
+			}))
+			defer server.Close()
+
+			provider := &YandexCloudProvider{baseURL: server.URL, computeURL: server.URL, client: server.Client()}
+			Expect(provider.DeleteNodes(ctx, "cat123", "ng-1", []corev1.Node{
+				nodeWithProviderID("worker-2", "yandex://fhm2"),
+			})).To(Succeed())
+			Expect(deleteBody).To(Equal(map[string][]string{"managedInstanceIds": {"mi-2"}}))
+
+			// Нода из другой группы или без providerID не удаляется вовсе
+			requests = nil
+			err := provider.DeleteNodes(ctx, "cat123", "ng-1", []corev1.Node{
+				nodeWithProviderID("worker-1", "yandex://fhm1"), nodeWithProviderID("foreign", "yandex://fhm9"),
+			})
+			Expect(errors.Is(err, ErrProviderNotFound)).To(BeTrue())
+			Expect(err).To(MatchError(ContainSubstring("foreign")))
+			err = provider.DeleteNodes(ctx, "cat123", "ng-1", []corev1.Node{nodeWithProviderID("unregistered", "")})
+			Expect(errors.Is(err, ErrProviderNotFound)).To(BeTrue())
+			Expect(requests).NotTo(ContainElement(HaveSuffix(":deleteInstances")))
+		})
+
+		It("should map rate limiting and conflicts to typed errors", func() {
+			status := http.StatusTooManyRequests
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				w.Header().Set("Retry-After", "7")
+				w.WriteHeader(status)
+			}))
+			defer server.Close()
+
+			provider := &YandexCloudProvider{baseURL: server.URL, client: server.Client()}
+			err := provider.ScaleNodeGroup(ctx, "cluster", "group", 3)
+			Expect(errors.Is(err, ErrProviderRateLimited)).To(BeTrue())
+
+			var perr *ProviderError
+			Expect(errors.As(err, &perr)).To(BeTrue())
+			Expect(perr.RetryAfter.Seconds()).To(BeNumerically("==", 7))
+
+			status = http.StatusConflict
+			err = provider.ScaleNodeGroup(ctx, "cluster", "group", 3)
+			Expect(providerErrorStatus(err)).To(Equal(http.StatusConflict))
+		})
+	})
+
+	Context("Node group nodes endpoint", func() {
+		groupNode := func(name, groupID string) *corev1.Node {
+			return &corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{FakeNodeGroupLabel: groupID}},
+				Status: corev1.NodeStatus{
+					Capacity:   corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("2"), corev1.ResourceMemory: resource.MustParse("4Gi")},
+					Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}},
+				},
+			}
+		}
+		request := func(reconciler *KubedeckReconciler, groupID, query string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodGet, "/cloud/nodegroups/"+groupID+"/nodes?"+query, nil)
+			req.SetPathValue("id", groupID)
+			recorder := httptest.NewRecorder()
+			reconciler.handleCloudNodeGroupNodesRequest(recorder, req)
+			return recorder
+		}
+
+		It("should join a node group with the nodes carrying its labels", func() {
+			prometheus := newPrometheusStandIn(map[string][]map[string]interface{}{
+				`rate(container_cpu_usage_seconds_total{id="/"}`: {promSample("1", "node", "general-0"), promSample("2", "node", "highmem-0")},
+			})
+			DeferCleanup(prometheus.Close)
+			reconciler := &KubedeckReconciler{
+				Client:    fake.NewClientBuilder().WithObjects(groupNode("general-0", "fake-ng-general"), groupNode("highmem-0", "fake-ng-highmem")).Build(),
+				providers: map[string]ClusterProvider{"fake": NewFakeProvider(FakeProviderOptions{})},
+				metrics:   prometheus.backend(),
+			}
+
+			recorder := request(reconciler, "fake-ng-general", "provider=fake&cluster_id=fake-cluster-1")
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+			var response NodeGroupNodesResponse
+			Expect(json.Unmarshal(recorder.Body.Bytes(), &response)).To(Succeed())
+			Expect(response.NodeGroup.NodeLabels).To(Equal(map[string]string{FakeNodeGroupLabel: "fake-ng-general"}))
+			Expect(response.Metrics.Nodes).To(HaveLen(1))
+			Expect(response.Metrics.Nodes[0].Name).To(Equal("general-0"))
+			Expect(response.Metrics.CPUUtilization).To(BeNumerically("~", 50, 1e-9))
+			// Провайдер заявляет две ноды, в кластере пока одна
+			Expect(response.MissingNodes).To(Equal(1))
+
+			Expect(request(reconciler, "fake-ng-general", "provider=fake").Code).To(Equal(http.StatusBadRequest))
+			Expect(request(reconciler, "fake-ng-missing", "provider=fake&cluster_id=fake-cluster-1").Code).To(Equal(http.StatusNotFound))
+		})
+
+		It("should refuse node groups without node labels", func() {
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+				switch req.URL.Path {
+				case "/presets/k8s":
+					_, _ = w.Write([]byte(`{"k8s_presets":[]}`))
+				default:
+					_, _ = w.Write([]byte(`{"meta":{"total":1},"node_groups":[{"id":7,"name":"workers","node_count":3}]}`))
+				}
+			}))
+			defer server.Close()
+
+			reconciler := &KubedeckReconciler{
+				Client:    fake.NewClientBuilder().Build(),
+				providers: map[string]ClusterProvider{"timeweb": &TimeWebProvider{baseURL: server.URL, client: server.Client()}},
+			}
+			recorder := request(reconciler, "7", "provider=timeweb&cluster_id=42")
+			Expect(recorder.Code).To(Equal(http.StatusUnprocessableEntity), recorder.Body.String())
+			Expect(recorder.Body.String()).To(ContainSubstring("does not expose node labels"))
+		})
+	})
+})
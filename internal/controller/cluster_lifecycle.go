@@ -0,0 +1,432 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/apimachinery/pkg/util/version"
+)
+
+const (
+	clusterOperationCreate  = "create"
+	clusterOperationUpgrade = "upgrade"
+	clusterOperationDelete  = "delete"
+
+	clusterOperationRunning   = "Running"
+	clusterOperationSucceeded = "Succeeded"
+	clusterOperationFailed    = "Failed"
+
+	// clusterOperationTimeout - максимальная длительность создания, обновления или удаления кластера
+	clusterOperationTimeout = 2 * time.Hour
+)
+
+// operationPollInterval - период опроса состояния кластера или операции у провайдера
+var operationPollInterval = 10 * time.Second
+
+// ClusterSpec - параметры нового кластера
+type ClusterSpec struct {
+	Name    string `json:"name"`
+	Version string `json:"version"`
+	// NetworkID и SubnetID - сеть кластера (VPC TimeWeb, сеть и подсеть Yandex Cloud)
+	NetworkID string `json:"network_id,omitempty"`
+	SubnetID  string `json:"subnet_id,omitempty"`
+	// Zone - зона доступности control plane и нод (Yandex Cloud)
+	Zone string `json:"zone,omitempty"`
+	// NetworkDriver - CNI кластера TimeWeb (flannel, calico, cilium)
+	NetworkDriver string `json:"network_driver,omitempty"`
+	// MasterPresetID - пресет control plane TimeWeb
+	MasterPresetID string `json:"master_preset_id,omitempty"`
+	// ServiceAccountID и NodeServiceAccountID - сервисные аккаунты кластера и нод Yandex Cloud
+	ServiceAccountID     string          `json:"service_account_id,omitempty"`
+	NodeServiceAccountID string          `json:"node_service_account_id,omitempty"`
+	NodeGroups           []NodeGroupSpec `json:"node_groups,omitempty"`
+}
+
+// NodeGroupSpec - группа нод нового кластера
+type NodeGroupSpec struct {
+	Name      string `json:"name"`
+	NodeCount int    `json:"node_count"`
+	// PresetID - пресет ноды: ID пресета TimeWeb или "standard-v3/2c/8g/100" для Yandex Cloud
+	PresetID string `json:"preset_id"`
+	// DiskSize - размер загрузочного диска в ГБ (Yandex Cloud)
+	DiskSize int `json:"disk_size,omitempty"`
+}
+
+// validate проверяет обязательные параметры кластера
+func (s ClusterSpec) validate() error {
+	if s.Name == "" || s.Version == "" {
+		return fmt.Errorf("%w: name and version are required", ErrProviderBadRequest)
+	}
+	for _, group := range s.NodeGroups {
+		if group.Name == "" || group.PresetID == "" || group.NodeCount < 0 {
+			return fmt.Errorf("%w: node groups require name, preset_id and a non-negative node_count", ErrProviderBadRequest)
+		}
+	}
+	return nil
+}
+
+// ClusterLifecycle - провайдер, умеющий создавать, обновлять и удалять кластеры.
+// Методы блокируются до завершения операции у провайдера и сообщают о ходе выполнения через progress.
+type ClusterLifecycle interface {
+	// ListVersions возвращает версии Kubernetes, доступные для новых кластеров
+	ListVersions(ctx context.Context) ([]string, error)
+	// GetClusterVersion возвращает текущую версию control plane
+	GetClusterVersion(ctx context.Context, clusterID string) (string, error)
+	CreateCluster(ctx context.Context, spec ClusterSpec, progress OperationProgress) (string, error)
+	// UpgradeCluster обновляет control plane, затем группы нод
+	UpgradeCluster(ctx context.Context, clusterID, version string, progress OperationProgress) error
+	DeleteCluster(ctx context.Context, clusterID string, progress OperationProgress) error
+}
+
+// OperationProgress сообщает о шаге операции; clusterID передается, как только становится известен
+type OperationProgress func(clusterID, message string)
+
+// ClusterOperation - длительная операция с кластером
+type ClusterOperation struct {
+	ID         string     `json:"id"`
+	Type       string     `json:"type"`
+	Provider   string     `json:"provider"`
+	ClusterID  string     `json:"cluster_id,omitempty"`
+	Version    string     `json:"version,omitempty"`
+	Phase      string     `json:"phase"`
+	Message    string     `json:"message,omitempty"`
+	Steps      []string   `json:"steps,omitempty"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+}
+
+func (op ClusterOperation) operationID() string { return op.ID }
+
+func (op ClusterOperation) finished() bool { return op.FinishedAt != nil }
+
+// startClusterOperation запускает run в фоне и возвращает снимок операции
+func (r *KubedeckReconciler) startClusterOperation(providerName, opType, clusterID, version string,
+	run func(ctx context.Context, progress OperationProgress) (string, error)) ClusterOperation {
+	log := webServerLog.WithName("cluster-lifecycle")
+
+	op := &ClusterOperation{
+		ID:        string(uuid.NewUUID()),
+		Type:      opType,
+		Provider:  providerName,
+		ClusterID: clusterID,
+		Version:   version,
+		Phase:     clusterOperationRunning,
+		StartedAt: time.Now(),
+	}
+	r.clusterOps.add(op)
+	snapshot := *op
+
+	progress := func(clusterID, message string) {
+		r.clusterOps.update(op, func(op *ClusterOperation) {
+			if clusterID != "" {
+				op.ClusterID = clusterID
+			}
+			op.Message = message
+			op.Steps = append(op.Steps, message)
+		})
+		log.Info("Cluster operation progress", "operation", op.ID, "type", opType, "cluster", clusterID, "message", message)
+	}
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), clusterOperationTimeout)
+		defer cancel()
+
+		clusterID, err := run(ctx, progress)
+		r.clusterOps.update(op, func(op *ClusterOperation) {
+			now := time.Now()
+			op.FinishedAt = &now
+			if clusterID != "" {
+				op.ClusterID = clusterID
+			}
+			if err != nil {
+				op.Phase = clusterOperationFailed
+				op.Message = err.Error()
+				return
+			}
+			op.Phase = clusterOperationSucceeded
+			op.Message = fmt.Sprintf("cluster %s %s completed", op.ClusterID, opType)
+		})
+
+		final, _ := r.clusterOps.get(op.ID)
+		if err != nil {
+			log.Error(err, "Cluster operation failed", "operation", op.ID, "type", opType, "cluster", final.ClusterID)
+		}
+		r.broadcastTelegramMessage(fmt.Sprintf("*Кластер %s (%s)*: %s — %s", final.ClusterID, providerName, opType, final.Message))
+	}()
+	return snapshot
+}
+
+// upgradeCandidates возвращает версии новее текущей, на которые можно обновиться за один шаг:
+// Kubernetes поддерживает обновление control plane не более чем на одну минорную версию.
+func upgradeCandidates(current string, versions []string) []string {
+	currentVersion, err := version.ParseGeneric(current)
+	if err != nil {
+		return nil
+	}
+
+	type candidate struct {
+		raw    string
+		parsed *version.Version
+	}
+	var candidates []candidate
+	for _, raw := range versions {
+		parsed, err := version.ParseGeneric(raw)
+		if err != nil || parsed.Major() != currentVersion.Major() ||
+			parsed.Minor() > currentVersion.Minor()+1 || !currentVersion.LessThan(parsed) {
+			continue
+		}
+		candidates = append(candidates, candidate{raw: raw, parsed: parsed})
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i].parsed.LessThan(candidates[j].parsed) })
+
+	result := make([]string, 0, len(candidates))
+	for _, c := range candidates {
+		result = append(result, c.raw)
+	}
+	return result
+}
+
+// getClusterLifecycle возвращает провайдера с управлением жизненным циклом кластеров
+func (r *KubedeckReconciler) getClusterLifecycle(providerName string) (ClusterLifecycle, int, error) {
+	provider, status, err := r.getClusterProvider(providerName)
+	if err != nil {
+		return nil, status, err
+	}
+	lifecycle, ok := provider.(ClusterLifecycle)
+	if !ok {
+		return nil, http.StatusNotImplemented, fmt.Errorf("provider %s does not support cluster lifecycle operations", providerName)
+	}
+	return lifecycle, http.StatusOK, nil
+}
+
+// writeClusterOperation отвечает 202 со ссылкой на операцию
+func writeClusterOperation(w http.ResponseWriter, op ClusterOperation) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Location", "/cloud/operations/"+op.ID)
+	w.WriteHeader(http.StatusAccepted)
+	if err := json.NewEncoder(w).Encode(op); err != nil {
+		webServerLog.Error(err, "Failed to write cluster operation response")
+	}
+}
+
+// handleCreateClusterRequest обрабатывает POST /cloud/clusters?provider= с ClusterSpec в теле
+func (r *KubedeckReconciler) handleCreateClusterRequest(w http.ResponseWriter, req *http.Request) {
+	providerName := req.URL.Query().Get("provider")
+	lifecycle, status, err := r.getClusterLifecycle(providerName)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	var spec ClusterSpec
+	if err := json.NewDecoder(req.Body).Decode(&spec); err != nil {
+		http.Error(w, "Invalid JSON request: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer req.Body.Close()
+	if err := spec.validate(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	op := r.startClusterOperation(providerName, clusterOperationCreate, "", spec.Version,
+		func(ctx context.Context, progress OperationProgress) (string, error) {
+			return lifecycle.CreateCluster(ctx, spec, progress)
+		})
+	writeClusterOperation(w, op)
+}
+
+// handleCloudVersionsRequest возвращает версии Kubernetes для новых кластеров
+func (r *KubedeckReconciler) handleCloudVersionsRequest(w http.ResponseWriter, req *http.Request) {
+	lifecycle, status, err := r.getClusterLifecycle(req.URL.Query().Get("provider"))
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	versions, err := lifecycle.ListVersions(req.Context())
+	if err != nil {
+		writeProviderError(w, "Failed to list Kubernetes versions", err)
+		return
+	}
+	writeJsonResponse(w, versions, "versions", nil)
+}
+
+// handleClusterUpgradesRequest возвращает текущую версию кластера и версии, доступные для обновления
+func (r *KubedeckReconciler) handleClusterUpgradesRequest(w http.ResponseWriter, req *http.Request) {
+	clusterID := req.PathValue("id")
+	lifecycle, status, err := r.getClusterLifecycle(req.URL.Query().Get("provider"))
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	current, err := lifecycle.GetClusterVersion(req.Context(), clusterID)
+	if err != nil {
+		writeProviderError(w, "Failed to get cluster version", err)
+		return
+	}
+	versions, err := lifecycle.ListVersions(req.Context())
+	if err != nil {
+		writeProviderError(w, "Failed to list Kubernetes versions", err)
+		return
+	}
+
+	writeJsonResponse(w, map[string]interface{}{
+		"cluster_id":      clusterID,
+		"current_version": current,
+		"available":       upgradeCandidates(current, versions),
+	}, "cluster upgrades", nil)
+}
+
+// handleClusterUpgradeRequest обрабатывает POST /cloud/clusters/{id}/upgrade?provider=&version=
+func (r *KubedeckReconciler) handleClusterUpgradeRequest(w http.ResponseWriter, req *http.Request) {
+	if req.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	providerName := req.URL.Query().Get("provider")
+	target := req.URL.Query().Get("version")
+	clusterID := req.PathValue("id")
+	if target == "" {
+		http.Error(w, "version parameter is required", http.StatusBadRequest)
+		return
+	}
+	lifecycle, status, err := r.getClusterLifecycle(providerName)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	// Версию проверяем до запуска операции, чтобы сразу вернуть ошибку на недопустимый шаг
+	current, err := lifecycle.GetClusterVersion(req.Context(), clusterID)
+	if err != nil {
+		writeProviderError(w, "Failed to get cluster version", err)
+		return
+	}
+	versions, err := lifecycle.ListVersions(req.Context())
+	if err != nil {
+		writeProviderError(w, "Failed to list Kubernetes versions", err)
+		return
+	}
+	available := upgradeCandidates(current, versions)
+	allowed := false
+	for _, v := range available {
+		allowed = allowed || v == target
+	}
+	if !allowed {
+		http.Error(w, fmt.Sprintf("cannot upgrade from %s to %s, available: %s", current, target, strings.Join(available, ", ")),
+			http.StatusUnprocessableEntity)
+		return
+	}
+
+	op := r.startClusterOperation(providerName, clusterOperationUpgrade, clusterID, target,
+		func(ctx context.Context, progress OperationProgress) (string, error) {
+			return clusterID, lifecycle.UpgradeCluster(ctx, clusterID, target, progress)
+		})
+	writeClusterOperation(w, op)
+}
+
+// handleDeleteClusterRequest обрабатывает DELETE /cloud/clusters/{id}?provider=.
+// Первый запрос возвращает 428 с токеном, удаление начинается после повтора с confirm=<token>.
+func (r *KubedeckReconciler) handleDeleteClusterRequest(w http.ResponseWriter, req *http.Request) {
+	providerName := req.URL.Query().Get("provider")
+	clusterID := req.PathValue("id")
+	lifecycle, status, err := r.getClusterLifecycle(providerName)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	subject := "delete " + targetClusterName(providerName, clusterID)
+	if !r.clusterConfirmations.consume(req.URL.Query().Get("confirm"), subject) {
+		token, expires := r.clusterConfirmations.issue(subject)
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusPreconditionRequired)
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"error":              "deleting a cluster requires confirmation",
+			"provider":           providerName,
+			"cluster_id":         clusterID,
+			"confirmation_token": token,
+			"expires_at":         expires,
+		})
+		return
+	}
+
+	op := r.startClusterOperation(providerName, clusterOperationDelete, clusterID, "",
+		func(ctx context.Context, progress OperationProgress) (string, error) {
+			return clusterID, lifecycle.DeleteCluster(ctx, clusterID, progress)
+		})
+	writeClusterOperation(w, op)
+}
+
+// handleCloudClusterRequest направляет запросы к /cloud/clusters/{id}
+func (r *KubedeckReconciler) handleCloudClusterRequest(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodDelete:
+		r.handleDeleteClusterRequest(w, req)
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+// handleCloudOperationsRequest возвращает операции с кластерами или одну операцию по id
+func (r *KubedeckReconciler) handleCloudOperationsRequest(w http.ResponseWriter, req *http.Request) {
+	if id := req.PathValue("id"); id != "" {
+		op, ok := r.clusterOps.get(id)
+		if !ok {
+			http.Error(w, "Operation not found", http.StatusNotFound)
+			return
+		}
+		writeJsonResponse(w, op, "cluster operation", nil)
+		return
+	}
+	writeJsonResponse(w, r.clusterOps.list(), "cluster operations", nil)
+}
+
+// waitFor опрашивает check до завершения, ошибки или отмены ctx
+func waitFor(ctx context.Context, check func(ctx context.Context) (bool, error)) error {
+	ticker := time.NewTicker(operationPollInterval)
+	defer ticker.Stop()
+	for {
+		done, err := check(ctx)
+		if err != nil || done {
+			return err
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+// parseYandexPresetID разбирает ID пресета Yandex Cloud вида "standard-v3/2c/8g/100"
+func parseYandexPresetID(id string) (platformID string, resources yandexResources, err error) {
+	parts := strings.Split(id, "/")
+	if len(parts) != 4 || !strings.HasSuffix(parts[1], "c") || !strings.HasSuffix(parts[2], "g") {
+		return "", yandexResources{}, fmt.Errorf("%w: invalid preset %q, expected <platform>/<cores>c/<memory>g/<core fraction>",
+			ErrProviderBadRequest, id)
+	}
+	cores, err := strconv.Atoi(strings.TrimSuffix(parts[1], "c"))
+	if err != nil {
+		return "", yandexResources{}, fmt.Errorf("%w: invalid cores in preset %q", ErrProviderBadRequest, id)
+	}
+	memoryGB, err := strconv.ParseFloat(strings.TrimSuffix(parts[2], "g"), 64)
+	if err != nil {
+		return "", yandexResources{}, fmt.Errorf("%w: invalid memory in preset %q", ErrProviderBadRequest, id)
+	}
+	return parts[0], yandexResources{
+		Memory:       strconv.FormatInt(int64(memoryGB*(1<<30)), 10),
+		Cores:        strconv.Itoa(cores),
+		CoreFraction: parts[3],
+	}, nil
+}
@@ -0,0 +1,139 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// lifecycleFakeProvider - fake провайдер с управлением жизненным циклом кластеров
+type lifecycleFakeProvider struct {
+	*FakeProvider
+	version string
+	deleted chan string
+}
+
+func (p *lifecycleFakeProvider) ListVersions(ctx context.Context) ([]string, error) {
+	return []string{"1.28", "1.29", "1.30", "1.31"}, nil
+}
+
+func (p *lifecycleFakeProvider) GetClusterVersion(ctx context.Context, clusterID string) (string, error) {
+	return p.version, nil
+}
+
+func (p *lifecycleFakeProvider) CreateCluster(ctx context.Context, spec ClusterSpec, progress OperationProgress) (string, error) {
+	progress("new-cluster", "creating")
+	return "new-cluster", nil
+}
+
+func (p *lifecycleFakeProvider) UpgradeCluster(ctx context.Context, clusterID, version string, progress OperationProgress) error {
+	progress(clusterID, "upgrading to "+version)
+	return nil
+}
+
+func (p *lifecycleFakeProvider) DeleteCluster(ctx context.Context, clusterID string, progress OperationProgress) error {
+	p.deleted <- clusterID
+	return nil
+}
+
+var _ = Describe("Cluster lifecycle", func() {
+	newReconciler := func() (*KubedeckReconciler, *lifecycleFakeProvider) {
+		provider := &lifecycleFakeProvider{FakeProvider: NewFakeProvider(FakeProviderOptions{}), version: "1.29", deleted: make(chan string, 1)}
+		return &KubedeckReconciler{providers: map[string]ClusterProvider{"fake": provider, "plain": NewFakeProvider(FakeProviderOptions{})}}, provider
+	}
+
+	It("should offer only the next minor versions for upgrade", func() {
+		Expect(upgradeCandidates("1.29.3", []string{"1.31.0", "1.29.5", "1.30.1", "1.28.9", "1.29.3"})).To(Equal([]string{"1.29.5", "1.30.1"}))
+		Expect(upgradeCandidates("v1.30.4+k0s.0", []string{"v1.30.4+k0s.0", "v1.31.1+k0s.0"})).To(Equal([]string{"v1.31.1+k0s.0"}))
+		Expect(upgradeCandidates("unknown", []string{"1.30"})).To(BeEmpty())
+	})
+
+	It("should keep running operations when the store is full", func() {
+		store := &operationStore[ClusterOperation]{}
+		running := &ClusterOperation{ID: "create", Type: clusterOperationCreate, Phase: clusterOperationRunning}
+		store.add(running)
+		finished := time.Now()
+		for i := range maxStoredOperations + 1 {
+			store.add(&ClusterOperation{ID: fmt.Sprintf("delete-%d", i), Type: clusterOperationDelete,
+				Phase: clusterOperationSucceeded, FinishedAt: &finished})
+		}
+
+		operations := store.list()
+		Expect(operations).To(HaveLen(maxStoredOperations))
+		Expect(operations[0].ID).To(Equal("create"))
+		Expect(operations[1].ID).To(Equal("delete-2"))
+		op, ok := store.get("create")
+		Expect(ok).To(BeTrue())
+		Expect(op.Phase).To(Equal(clusterOperationRunning))
+	})
+
+	It("should parse Yandex Cloud presets", func() {
+		platform, resources, err := parseYandexPresetID("standard-v3/2c/8g/100")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(platform).To(Equal("standard-v3"))
+		Expect(resources).To(Equal(yandexResources{Memory: "8589934592", Cores: "2", CoreFraction: "100"}))
+
+		_, _, err = parseYandexPresetID("standard-v3/2/8")
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("should reject upgrades that skip minor versions", func() {
+		reconciler, _ := newReconciler()
+		mux := http.NewServeMux()
+		mux.HandleFunc("/cloud/clusters/{id}/upgrade", reconciler.handleClusterUpgradeRequest)
+		mux.HandleFunc("/cloud/operations/{id}", reconciler.handleCloudOperationsRequest)
+
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/cloud/clusters/c1/upgrade?provider=fake&version=1.31", nil))
+		Expect(recorder.Code).To(Equal(http.StatusUnprocessableEntity))
+
+		recorder = httptest.NewRecorder()
+		mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/cloud/clusters/c1/upgrade?provider=plain&version=1.30", nil))
+		Expect(recorder.Code).To(Equal(http.StatusNotImplemented))
+
+		recorder = httptest.NewRecorder()
+		mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodPost, "/cloud/clusters/c1/upgrade?provider=fake&version=1.30", nil))
+		Expect(recorder.Code).To(Equal(http.StatusAccepted))
+		var op ClusterOperation
+		Expect(json.Unmarshal(recorder.Body.Bytes(), &op)).To(Succeed())
+		Expect(recorder.Header().Get("Location")).To(Equal("/cloud/operations/" + op.ID))
+
+		Eventually(func() string {
+			current, _ := reconciler.clusterOps.get(op.ID)
+			return current.Phase
+		}).Should(Equal(clusterOperationSucceeded))
+		current, _ := reconciler.clusterOps.get(op.ID)
+		Expect(current.Steps).To(Equal([]string{"upgrading to 1.30"}))
+	})
+
+	It("should delete a cluster only with a confirmation token", func() {
+		reconciler, provider := newReconciler()
+		mux := http.NewServeMux()
+		mux.HandleFunc("/cloud/clusters/{id}", reconciler.handleCloudClusterRequest)
+
+		recorder := httptest.NewRecorder()
+		mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodDelete, "/cloud/clusters/c1?provider=fake", nil))
+		Expect(recorder.Code).To(Equal(http.StatusPreconditionRequired))
+		var confirmation struct {
+			Token string `json:"confirmation_token"`
+		}
+		Expect(json.Unmarshal(recorder.Body.Bytes(), &confirmation)).To(Succeed())
+
+		// Токен привязан к кластеру
+		recorder = httptest.NewRecorder()
+		mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodDelete, "/cloud/clusters/c2?provider=fake&confirm="+confirmation.Token, nil))
+		Expect(recorder.Code).To(Equal(http.StatusPreconditionRequired))
+		Consistently(provider.deleted, 50*time.Millisecond).ShouldNot(Receive())
+
+		recorder = httptest.NewRecorder()
+		mux.ServeHTTP(recorder, httptest.NewRequest(http.MethodDelete, "/cloud/clusters/c1?provider=fake&confirm="+confirmation.Token, nil))
+		Expect(recorder.Code).To(Equal(http.StatusAccepted))
+		Eventually(provider.deleted).Should(Receive(Equal("c1")))
+	})
+})
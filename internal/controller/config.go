@@ -0,0 +1,66 @@
+package controller
+
+import (
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Настройки kubedeck задаются переменными окружения контейнера менеджера
+// (см. controllerManager.container.env в helm chart).
+
+// getEnvString возвращает значение переменной окружения или значение по умолчанию
+func getEnvString(name, def string) string {
+	if value, ok := os.LookupEnv(name); ok {
+		return value
+	}
+	return def
+}
+
+// getEnvBool разбирает булеву переменную окружения
+func getEnvBool(name string, def bool) bool {
+	value, err := strconv.ParseBool(os.Getenv(name))
+	if err != nil {
+		return def
+	}
+	return value
+}
+
+// getEnvFloat разбирает вещественную переменную окружения
+func getEnvFloat(name string, def float64) float64 {
+	value, err := strconv.ParseFloat(os.Getenv(name), 64)
+	if err != nil {
+		return def
+	}
+	return value
+}
+
+// getEnvDuration разбирает длительность в формате time.ParseDuration (например, "30s")
+func getEnvDuration(name string, def time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(name))
+	if err != nil {
+		return def
+	}
+	return value
+}
+
+// getEnvList разбирает список значений, разделенных запятыми
+func getEnvList(name string, def []string) []string {
+	value := os.Getenv(name)
+	if value == "" {
+		return def
+	}
+	return splitList(value)
+}
+
+// splitList разбирает список значений, разделенных запятыми, пропуская пустые
+func splitList(value string) []string {
+	var result []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			result = append(result, item)
+		}
+	}
+	return result
+}
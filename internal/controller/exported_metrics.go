@@ -0,0 +1,185 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// Метрики кластеров и анализа ресурсов, отдаются на /metrics менеджера
+var (
+	clusterCPUUtilization = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kubedeck_cluster_cpu_utilization_percent",
+		Help: "CPU usage of schedulable nodes as a percentage of their capacity.",
+	}, []string{"cluster"})
+
+	clusterMemoryUtilization = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kubedeck_cluster_memory_utilization_percent",
+		Help: "Memory usage of schedulable nodes as a percentage of their capacity.",
+	}, []string{"cluster"})
+
+	clusterNodes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kubedeck_cluster_nodes",
+		Help: "Number of nodes in the cluster.",
+	}, []string{"cluster"})
+
+	clusterReadyNodes = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kubedeck_cluster_ready_nodes",
+		Help: "Number of Ready nodes in the cluster.",
+	}, []string{"cluster"})
+
+	clusterUp = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kubedeck_cluster_up",
+		Help: "Whether the last collection of cluster metrics succeeded: 1 - yes, 0 - no.",
+	}, []string{"cluster"})
+
+	nodeCPUUtilization = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kubedeck_node_cpu_utilization_percent",
+		Help: "CPU usage of a schedulable node as a percentage of its capacity.",
+	}, []string{"cluster", "node"})
+
+	nodeMemoryUtilization = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kubedeck_node_memory_utilization_percent",
+		Help: "Memory usage of a schedulable node as a percentage of its capacity.",
+	}, []string{"cluster", "node"})
+
+	problematicPods = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kubedeck_problematic_pods",
+		Help: "Pods reported by the last LLM resource analysis by namespace and severity.",
+	}, []string{"namespace", "severity"})
+
+	lastAnalysisTimestamp = prometheus.NewGauge(prometheus.GaugeOpts{
+		Name: "kubedeck_llm_analysis_last_success_timestamp_seconds",
+		Help: "Unix time of the last successful LLM resource analysis.",
+	})
+
+	llmRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "kubedeck_llm_requests_total",
+		Help: "Number of LLM resource analysis requests by result.",
+	}, []string{"result"})
+
+	llmRequestDuration = prometheus.NewHistogram(prometheus.HistogramOpts{
+		Name:    "kubedeck_llm_request_duration_seconds",
+		Help:    "Duration of LLM resource analysis requests including retries.",
+		Buckets: []float64{1, 2, 5, 10, 20, 30, 45, 60, 90, 120},
+	})
+
+	telegramMessagesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "kubedeck_telegram_messages_total",
+		Help: "Number of Telegram messages sent by type (alert, notification) and result.",
+	}, []string{"type", "result"})
+
+	providerRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "kubedeck_provider_requests_total",
+		Help: "Number of cloud provider API requests by provider and outcome.",
+	}, []string{"provider", "outcome"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(clusterCPUUtilization, clusterMemoryUtilization, clusterNodes, clusterReadyNodes,
+		clusterUp, nodeCPUUtilization, nodeMemoryUtilization, problematicPods, lastAnalysisTimestamp,
+		llmRequestsTotal, llmRequestDuration, telegramMessagesTotal, providerRequestsTotal)
+}
+
+// resultLabel возвращает значение метки result для ошибки
+func resultLabel(err error) string {
+	if err != nil {
+		return "failure"
+	}
+	return "success"
+}
+
+// providerOutcome возвращает значение метки outcome для результата запроса к провайдеру
+func providerOutcome(err error) string {
+	switch {
+	case err == nil:
+		return "success"
+	case errors.Is(err, ErrProviderUnauthorized):
+		return "unauthorized"
+	case errors.Is(err, ErrProviderNotFound):
+		return "not_found"
+	case errors.Is(err, ErrProviderRateLimited):
+		return "rate_limited"
+	case errors.Is(err, ErrProviderConflict):
+		return "conflict"
+	case errors.Is(err, ErrProviderBadRequest):
+		return "bad_request"
+	case errors.Is(err, ErrProviderUnavailable):
+		return "unavailable"
+	case errors.Is(err, ErrCircuitOpen):
+		return "circuit_open"
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	default:
+		return "error"
+	}
+}
+
+// recordProblematicPods заменяет значения kubedeck_problematic_pods результатом анализа
+func recordProblematicPods(recommendation *ResourceRecommendationResponse) {
+	problematicPods.Reset()
+	for namespace, pods := range recommendation.Namespaces {
+		for _, pod := range pods {
+			severity := pod.Status
+			if severity != "critical" && severity != "warning" {
+				severity = "info"
+			}
+			problematicPods.WithLabelValues(namespace, severity).Inc()
+		}
+	}
+	lastAnalysisTimestamp.SetToCurrentTime()
+}
+
+// recordClusterMetrics заменяет метрики кластеров результатами опроса.
+// Кластеры и ноды, пропавшие с прошлого опроса, удаляются из метрик.
+func recordClusterMetrics(results []ClusterResult) {
+	for _, vec := range []*prometheus.GaugeVec{clusterCPUUtilization, clusterMemoryUtilization, clusterNodes,
+		clusterReadyNodes, clusterUp, nodeCPUUtilization, nodeMemoryUtilization} {
+		vec.Reset()
+	}
+
+	for _, result := range results {
+		metrics, ok := result.Data.(*ClusterMetrics)
+		if result.Error != "" || !ok {
+			clusterUp.WithLabelValues(result.Cluster).Set(0)
+			continue
+		}
+		clusterUp.WithLabelValues(result.Cluster).Set(1)
+		clusterCPUUtilization.WithLabelValues(result.Cluster).Set(metrics.CPUUtilization)
+		clusterMemoryUtilization.WithLabelValues(result.Cluster).Set(metrics.MemoryUtilization)
+		clusterNodes.WithLabelValues(result.Cluster).Set(float64(metrics.NodeCount))
+		clusterReadyNodes.WithLabelValues(result.Cluster).Set(float64(metrics.ReadyNodeCount))
+		for _, node := range metrics.Nodes {
+			nodeCPUUtilization.WithLabelValues(result.Cluster, node.Name).Set(node.CPUPercentage)
+			nodeMemoryUtilization.WithLabelValues(result.Cluster, node.Name).Set(node.MemPercentage)
+		}
+	}
+}
+
+// runClusterMetricsExporter периодически обновляет метрики всех кластеров.
+// Работает только на лидере, чтобы не умножать нагрузку на API серверы и ряды метрик.
+func (r *KubedeckReconciler) runClusterMetricsExporter(ctx context.Context) error {
+	interval := getEnvDuration("KUBEDECK_METRICS_EXPORT_INTERVAL", time.Minute)
+	// Ноды отбираются по настройкам из переменных окружения, проверенным при запуске
+	filter, err := parseNodeFilter(url.Values{})
+	if err != nil {
+		return err
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		recordClusterMetrics(r.fanOut(ctx, func(ctx context.Context) (interface{}, error) {
+			return r.clusterMetrics(ctx, true, filter)
+		}))
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
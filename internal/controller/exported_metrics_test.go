@@ -0,0 +1,62 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+var _ = Describe("Exported metrics", func() {
+	It("should replace cluster and node gauges on every collection", func() {
+		recordClusterMetrics([]ClusterResult{
+			{Cluster: "local", Data: &ClusterMetrics{CPUUtilization: 42, MemoryUtilization: 60, NodeCount: 3, ReadyNodeCount: 2,
+				Nodes: []NodeMetrics{{Name: "node-1", CPUPercentage: 80, MemPercentage: 50}}}},
+			{Cluster: "onprem", Error: "cluster is unhealthy: timeout"},
+		})
+		Expect(testutil.ToFloat64(clusterCPUUtilization.WithLabelValues("local"))).To(Equal(42.0))
+		Expect(testutil.ToFloat64(clusterReadyNodes.WithLabelValues("local"))).To(Equal(2.0))
+		Expect(testutil.ToFloat64(nodeCPUUtilization.WithLabelValues("local", "node-1"))).To(Equal(80.0))
+		Expect(testutil.ToFloat64(clusterUp.WithLabelValues("local"))).To(Equal(1.0))
+		Expect(testutil.ToFloat64(clusterUp.WithLabelValues("onprem"))).To(Equal(0.0))
+
+		// Ноды и кластеры, пропавшие из опроса, пропадают из метрик
+		recordClusterMetrics([]ClusterResult{{Cluster: "local", Data: &ClusterMetrics{NodeCount: 1}}})
+		Expect(testutil.CollectAndCount(nodeCPUUtilization)).To(Equal(0))
+		Expect(testutil.CollectAndCount(clusterUp)).To(Equal(1))
+	})
+
+	It("should count problematic pods by severity", func() {
+		recordProblematicPods(&ResourceRecommendationResponse{Namespaces: map[string][]PodResourceInfo{
+			"default": {{Name: "a", Status: "critical"}, {Name: "b", Status: "critical"}, {Name: "c", Status: "warning"}},
+			"batch":   {{Name: "d"}},
+		}})
+		Expect(testutil.ToFloat64(problematicPods.WithLabelValues("default", "critical"))).To(Equal(2.0))
+		Expect(testutil.ToFloat64(problematicPods.WithLabelValues("default", "warning"))).To(Equal(1.0))
+		Expect(testutil.ToFloat64(problematicPods.WithLabelValues("batch", "info"))).To(Equal(1.0))
+		Expect(testutil.ToFloat64(lastAnalysisTimestamp)).To(BeNumerically(">", 0))
+	})
+
+	It("should count provider requests by outcome", func() {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			if req.URL.Path == "/missing" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			_, _ = w.Write([]byte(`{}`))
+		}))
+		DeferCleanup(server.Close)
+
+		before := testutil.ToFloat64(providerRequestsTotal.WithLabelValues("test", "not_found"))
+		for _, path := range []string{"/ok", "/missing"} {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+path, nil)
+			Expect(err).NotTo(HaveOccurred())
+			_ = doProviderRequest(server.Client(), "test", req, &map[string]interface{}{})
+		}
+		Expect(testutil.ToFloat64(providerRequestsTotal.WithLabelValues("test", "not_found"))).To(Equal(before + 1))
+		Expect(testutil.ToFloat64(providerRequestsTotal.WithLabelValues("test", "success"))).To(BeNumerically(">=", 1))
+		Expect(providerOutcome(ErrCircuitOpen)).To(Equal("circuit_open"))
+	})
+})
@@ -0,0 +1,436 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// FakeNodeGroupLabel - метка, которой фейковый провайдер помечает созданные им Node
+const FakeNodeGroupLabel = "kubedeck.nikcorp.ru/fake-node-group-id"
+
+// FakeProvider хранит кластеры и группы нод в памяти, чтобы /cloud/* и сценарии
+// масштабирования можно было разрабатывать без реальных облачных аккаунтов.
+type FakeProvider struct {
+	sync.Mutex
+	clusters   []Cluster
+	nodeGroups map[string][]*fakeNodeGroup
+
+	// scaleDelay имитирует асинхронное изменение размера группы
+	scaleDelay time.Duration
+	// failureRate - вероятность (0..1) случайной ошибки провайдера на любой вызов
+	failureRate float64
+	// injected - ошибки, которые будут возвращены следующими вызовами операции
+	injected map[string][]error
+
+	// Если client задан, для каждой фейковой ноды создается объект Node в кластере
+	client client.Client
+	rand   *rand.Rand
+}
+
+type fakeNodeGroup struct {
+	NodeGroup
+	targetCount int
+	// generation отбрасывает устаревшие отложенные изменения размера
+	generation int
+}
+
+// FakeProviderOptions - параметры фейкового провайдера
+type FakeProviderOptions struct {
+	ScaleDelay  time.Duration
+	FailureRate float64
+	// ManageNodes включает создание и удаление Node объектов через Client
+	ManageNodes bool
+	Client      client.Client
+}
+
+// fakePresets - каталог фейкового провайдера с ценами, похожими на реальные
+var fakePresets = []Preset{
+	{ID: "fake-master", Name: "Control plane", Type: "master", CPU: 2, RAM: 4096, Disk: 40,
+		HourlyPrice: 2, MonthlyPrice: 2 * hoursPerMonth, Currency: "RUB"},
+	{ID: "fake-general", Name: "2 vCPU, 4 GB", Type: "worker", CPU: 2, RAM: 4096, Disk: 40,
+		HourlyPrice: 1.5, MonthlyPrice: 1.5 * hoursPerMonth, Currency: "RUB"},
+	{ID: "fake-highmem", Name: "2 vCPU, 16 GB", Type: "worker", CPU: 2, RAM: 16384, Disk: 40,
+		HourlyPrice: 4, MonthlyPrice: 4 * hoursPerMonth, Currency: "RUB"},
+}
+
+// NewFakeProvider создает фейковый провайдер с одним кластером и двумя группами нод
+func NewFakeProvider(opts FakeProviderOptions) *FakeProvider {
+	f := &FakeProvider{
+		clusters: []Cluster{
+			{ID: "fake-cluster-1", Name: "fake-dev", Status: "started", Provider: "fake",
+				Preset: findPreset(fakePresets, "fake-master"), HourlyCost: 2},
+		},
+		nodeGroups: map[string][]*fakeNodeGroup{
+			"fake-cluster-1": {
+				{NodeGroup: NodeGroup{ID: "fake-ng-general", Name: "general", NodeCount: 2,
+					Preset: findPreset(fakePresets, "fake-general")}, targetCount: 2},
+				{NodeGroup: NodeGroup{ID: "fake-ng-highmem", Name: "highmem", NodeCount: 1,
+					Preset: findPreset(fakePresets, "fake-highmem")}, targetCount: 1},
+			},
+		},
+		scaleDelay:  opts.ScaleDelay,
+		failureRate: opts.FailureRate,
+		injected:    make(map[string][]error),
+		rand:        rand.New(rand.NewSource(time.Now().UnixNano())),
+	}
+	if opts.ManageNodes {
+		f.client = opts.Client
+	}
+	return f
+}
+
+// newFakeProviderFromEnv читает настройки фейкового провайдера из переменных окружения
+func newFakeProviderFromEnv(c client.Client) *FakeProvider {
+	return NewFakeProvider(FakeProviderOptions{
+		ScaleDelay:  getEnvDuration("FAKE_PROVIDER_SCALE_DELAY", 10*time.Second),
+		FailureRate: getEnvFloat("FAKE_PROVIDER_FAILURE_RATE", 0),
+		ManageNodes: getEnvBool("FAKE_PROVIDER_MANAGE_NODES", false),
+		Client:      c,
+	})
+}
+
+// Start создает Node для начального размера групп, если включено управление нодами.
+// Реализует manager.Runnable, чтобы запись в кластер шла после старта менеджера.
+func (f *FakeProvider) Start(ctx context.Context) error {
+	if f.client == nil {
+		return nil
+	}
+
+	f.Lock()
+	type groupSize struct {
+		id, name string
+		count    int
+	}
+	var sizes []groupSize
+	for _, groups := range f.nodeGroups {
+		for _, g := range groups {
+			sizes = append(sizes, groupSize{id: g.ID, name: g.Name, count: g.NodeCount})
+		}
+	}
+	f.Unlock()
+
+	for _, size := range sizes {
+		if err := f.syncNodes(ctx, size.id, size.name, size.count); err != nil {
+			webServerLog.WithName("fake-provider").Error(err, "Failed to create initial fake nodes", "group", size.id)
+		}
+	}
+	return nil
+}
+
+// InjectFailure заставляет следующий вызов операции (ListClusters, GetNodeGroups,
+// ScaleNodeGroup, DeleteNodes, ListPresets) вернуть err. Несколько вызовов ставят ошибки в очередь.
+func (f *FakeProvider) InjectFailure(operation string, err error) {
+	f.Lock()
+	defer f.Unlock()
+	f.injected[operation] = append(f.injected[operation], err)
+}
+
+// failure возвращает внедренную или случайную ошибку для операции. Вызывается под блокировкой.
+func (f *FakeProvider) failure(operation string) error {
+	if queue := f.injected[operation]; len(queue) > 0 {
+		f.injected[operation] = queue[1:]
+		return queue[0]
+	}
+	if f.failureRate > 0 && f.rand.Float64() < f.failureRate {
+		return &ProviderError{
+			Provider:   "fake",
+			StatusCode: http.StatusServiceUnavailable,
+			Message:    "injected failure in " + operation,
+			kind:       ErrProviderUnavailable,
+		}
+	}
+	return nil
+}
+
+func (f *FakeProvider) ListClusters(ctx context.Context) ([]Cluster, error) {
+	f.Lock()
+	defer f.Unlock()
+
+	if err := f.failure("ListClusters"); err != nil {
+		return nil, err
+	}
+	return append([]Cluster{}, f.clusters...), nil
+}
+
+func (f *FakeProvider) GetNodeGroups(ctx context.Context, clusterID string) ([]NodeGroup, error) {
+	f.Lock()
+	defer f.Unlock()
+
+	if err := f.failure("GetNodeGroups"); err != nil {
+		return nil, err
+	}
+
+	groups, ok := f.nodeGroups[clusterID]
+	if !ok {
+		return nil, f.notFound("cluster " + clusterID)
+	}
+
+	result := make([]NodeGroup, 0, len(groups))
+	for _, g := range groups {
+		group := g.NodeGroup
+		group.NodeLabels = map[string]string{FakeNodeGroupLabel: g.ID}
+		group.HourlyCost = group.Preset.HourlyPrice * float64(group.NodeCount)
+		result = append(result, group)
+	}
+	return result, nil
+}
+
+// ListPresets возвращает фиксированный каталог пресетов
+func (f *FakeProvider) ListPresets(ctx context.Context) ([]Preset, error) {
+	f.Lock()
+	defer f.Unlock()
+
+	if err := f.failure("ListPresets"); err != nil {
+		return nil, err
+	}
+	return append([]Preset{}, fakePresets...), nil
+}
+
+// ScaleNodeGroup устанавливает целевой размер группы, фактический размер меняется через scaleDelay
+func (f *FakeProvider) ScaleNodeGroup(ctx context.Context, clusterID, groupID string, nodeCount int) error {
+	f.Lock()
+	defer f.Unlock()
+
+	if err := f.failure("ScaleNodeGroup"); err != nil {
+		return err
+	}
+	if nodeCount < 0 {
+		return &ProviderError{
+			Provider:   "fake",
+			StatusCode: http.StatusBadRequest,
+			Message:    fmt.Sprintf("node count must not be negative, got %d", nodeCount),
+			kind:       ErrProviderBadRequest,
+		}
+	}
+
+	group := f.findGroup(clusterID, groupID)
+	if group == nil {
+		return f.notFound("node group " + groupID)
+	}
+	if group.targetCount != group.NodeCount {
+		return &ProviderError{
+			Provider:   "fake",
+			StatusCode: http.StatusConflict,
+			Message:    fmt.Sprintf("node group %s is already scaling to %d nodes", groupID, group.targetCount),
+			kind:       ErrProviderConflict,
+		}
+	}
+
+	group.targetCount = nodeCount
+	group.generation++
+	generation := group.generation
+
+	go f.completeScaling(clusterID, groupID, generation)
+	return nil
+}
+
+// DeleteNodes уменьшает группу на число нод и после scaleDelay удаляет именно эти Node
+func (f *FakeProvider) DeleteNodes(ctx context.Context, clusterID, groupID string, nodes []corev1.Node) error {
+	f.Lock()
+	defer f.Unlock()
+
+	if err := f.failure("DeleteNodes"); err != nil {
+		return err
+	}
+
+	group := f.findGroup(clusterID, groupID)
+	if group == nil {
+		return f.notFound("node group " + groupID)
+	}
+	if group.targetCount != group.NodeCount {
+		return &ProviderError{
+			Provider:   "fake",
+			StatusCode: http.StatusConflict,
+			Message:    fmt.Sprintf("node group %s is already scaling to %d nodes", groupID, group.targetCount),
+			kind:       ErrProviderConflict,
+		}
+	}
+	if len(nodes) > group.NodeCount {
+		return &ProviderError{
+			Provider:   "fake",
+			StatusCode: http.StatusBadRequest,
+			Message:    fmt.Sprintf("cannot delete %d nodes from a group of %d", len(nodes), group.NodeCount),
+			kind:       ErrProviderBadRequest,
+		}
+	}
+
+	group.targetCount = group.NodeCount - len(nodes)
+	group.generation++
+	generation := group.generation
+
+	names := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		names = append(names, node.Name)
+	}
+
+	go func() {
+		time.Sleep(f.scaleDelay)
+
+		f.Lock()
+		group := f.findGroup(clusterID, groupID)
+		if group == nil || group.generation != generation {
+			f.Unlock()
+			return
+		}
+		group.NodeCount = group.targetCount
+		f.Unlock()
+
+		if f.client == nil {
+			return
+		}
+		for _, name := range names {
+			node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name}}
+			if err := f.client.Delete(context.Background(), node); err != nil && !apierrors.IsNotFound(err) {
+				webServerLog.WithName("fake-provider").Error(err, "Failed to delete fake node", "node", name)
+			}
+		}
+	}()
+	return nil
+}
+
+// completeScaling применяет целевой размер группы после задержки
+func (f *FakeProvider) completeScaling(clusterID, groupID string, generation int) {
+	time.Sleep(f.scaleDelay)
+
+	f.Lock()
+	group := f.findGroup(clusterID, groupID)
+	if group == nil || group.generation != generation {
+		f.Unlock()
+		return
+	}
+	group.NodeCount = group.targetCount
+	groupName := group.Name
+	nodeCount := group.NodeCount
+	f.Unlock()
+
+	if f.client == nil {
+		return
+	}
+	if err := f.syncNodes(context.Background(), groupID, groupName, nodeCount); err != nil {
+		webServerLog.WithName("fake-provider").Error(err, "Failed to sync fake nodes", "group", groupID)
+	}
+}
+
+// syncNodes создает или удаляет объекты Node, чтобы их число совпадало с размером группы.
+// Существующие ноды сохраняются: при уменьшении удаляются только лишние, начиная с последних по номеру,
+// при увеличении создаются ноды со свободными номерами. Так ноды, удаленные после drain, не возвращаются
+// под прежними именами взамен здоровых.
+func (f *FakeProvider) syncNodes(ctx context.Context, groupID, groupName string, nodeCount int) error {
+	var nodeList corev1.NodeList
+	if err := f.client.List(ctx, &nodeList, client.MatchingLabels{FakeNodeGroupLabel: groupID}); err != nil {
+		return err
+	}
+
+	prefix := "fake-" + groupName + "-"
+	index := func(name string) int {
+		if !strings.HasPrefix(name, prefix) {
+			return -1
+		}
+		i, err := strconv.Atoi(strings.TrimPrefix(name, prefix))
+		if err != nil {
+			return -1
+		}
+		return i
+	}
+	existing := make(map[string]bool, len(nodeList.Items))
+	names := make([]string, 0, len(nodeList.Items))
+	for _, node := range nodeList.Items {
+		existing[node.Name] = true
+		names = append(names, node.Name)
+	}
+
+	for i := 0; len(existing) < nodeCount; i++ {
+		name := fmt.Sprintf("%s%d", prefix, i)
+		if existing[name] {
+			continue
+		}
+		if err := f.createNode(ctx, name, groupID); err != nil {
+			return err
+		}
+		existing[name] = true
+	}
+
+	// Удаляем лишние ноды в детерминированном порядке
+	sort.Slice(names, func(i, j int) bool {
+		if index(names[i]) != index(names[j]) {
+			return index(names[i]) < index(names[j])
+		}
+		return names[i] < names[j]
+	})
+	for len(names) > nodeCount {
+		node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: names[len(names)-1]}}
+		if err := f.client.Delete(ctx, node); err != nil && !apierrors.IsNotFound(err) {
+			return err
+		}
+		names = names[:len(names)-1]
+	}
+	return nil
+}
+
+// createNode создает готовую Node с фиксированной емкостью
+func (f *FakeProvider) createNode(ctx context.Context, name, groupID string) error {
+	capacity := corev1.ResourceList{
+		corev1.ResourceCPU:    resource.MustParse("2"),
+		corev1.ResourceMemory: resource.MustParse("4Gi"),
+		corev1.ResourcePods:   resource.MustParse("110"),
+	}
+
+	node := &corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{
+			Name: name,
+			Labels: map[string]string{
+				FakeNodeGroupLabel:       groupID,
+				"kubernetes.io/hostname": name,
+			},
+		},
+	}
+	if err := f.client.Create(ctx, node); err != nil && !apierrors.IsAlreadyExists(err) {
+		return err
+	}
+
+	// Статус выставляется отдельным запросом через status subresource
+	node.Status = corev1.NodeStatus{
+		Capacity:    capacity,
+		Allocatable: capacity,
+		Conditions: []corev1.NodeCondition{{
+			Type:               corev1.NodeReady,
+			Status:             corev1.ConditionTrue,
+			Reason:             "KubeletReady",
+			LastHeartbeatTime:  metav1.Now(),
+			LastTransitionTime: metav1.Now(),
+		}},
+	}
+	return f.client.Status().Update(ctx, node)
+}
+
+// findGroup ищет группу нод. Вызывается под блокировкой.
+func (f *FakeProvider) findGroup(clusterID, groupID string) *fakeNodeGroup {
+	for _, g := range f.nodeGroups[clusterID] {
+		if g.ID == groupID {
+			return g
+		}
+	}
+	return nil
+}
+
+func (f *FakeProvider) notFound(what string) error {
+	return &ProviderError{
+		Provider:   "fake",
+		StatusCode: http.StatusNotFound,
+		Message:    what + " not found",
+		kind:       ErrProviderNotFound,
+	}
+}
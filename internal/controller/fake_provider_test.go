@@ -0,0 +1,115 @@
+package controller
+
+import (
+	"errors"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Fake cloud provider", func() {
+	It("should scale node groups asynchronously", func() {
+		provider := NewFakeProvider(FakeProviderOptions{ScaleDelay: 50 * time.Millisecond})
+
+		Expect(provider.ScaleNodeGroup(ctx, "fake-cluster-1", "fake-ng-general", 5)).To(Succeed())
+
+		By("rejecting a second change while the group is scaling")
+		err := provider.ScaleNodeGroup(ctx, "fake-cluster-1", "fake-ng-general", 1)
+		Expect(errors.Is(err, ErrProviderConflict)).To(BeTrue())
+
+		Eventually(func() int {
+			groups, err := provider.GetNodeGroups(ctx, "fake-cluster-1")
+			Expect(err).NotTo(HaveOccurred())
+			return groups[0].NodeCount
+		}).Should(Equal(5))
+	})
+
+	It("should return injected failures", func() {
+		provider := NewFakeProvider(FakeProviderOptions{})
+		provider.InjectFailure("ListClusters", ErrProviderRateLimited)
+
+		_, err := provider.ListClusters(ctx)
+		Expect(err).To(MatchError(ErrProviderRateLimited))
+
+		clusters, err := provider.ListClusters(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(clusters).To(HaveLen(1))
+
+		_, err = provider.GetNodeGroups(ctx, "missing")
+		Expect(errors.Is(err, ErrProviderNotFound)).To(BeTrue())
+	})
+
+	It("should create and delete Node objects for the group", func() {
+		provider := NewFakeProvider(FakeProviderOptions{
+			ScaleDelay:  10 * time.Millisecond,
+			ManageNodes: true,
+			Client:      k8sClient,
+		})
+		Expect(provider.Start(ctx)).To(Succeed())
+		// Start создает ноды всех групп, их нужно убрать за собой
+		DeferCleanup(func() {
+			_ = k8sClient.DeleteAllOf(ctx, &corev1.Node{}, client.HasLabels{FakeNodeGroupLabel})
+		})
+
+		countNodes := func() int {
+			var nodes corev1.NodeList
+			Expect(k8sClient.List(ctx, &nodes, client.MatchingLabels{FakeNodeGroupLabel: "fake-ng-highmem"})).To(Succeed())
+			for _, node := range nodes.Items {
+				Expect(isNodeReady(node)).To(BeTrue())
+			}
+			return len(nodes.Items)
+		}
+		Expect(countNodes()).To(Equal(1))
+
+		Expect(provider.ScaleNodeGroup(ctx, "fake-cluster-1", "fake-ng-highmem", 3)).To(Succeed())
+		Eventually(countNodes).Should(Equal(3))
+
+		By("keeping the remaining nodes after deleting a drained node and scaling up")
+		nodeUIDs := func() map[string]types.UID {
+			var nodes corev1.NodeList
+			Expect(k8sClient.List(ctx, &nodes, client.MatchingLabels{FakeNodeGroupLabel: "fake-ng-highmem"})).To(Succeed())
+			uids := make(map[string]types.UID, len(nodes.Items))
+			for _, node := range nodes.Items {
+				uids[node.Name] = node.UID
+			}
+			return uids
+		}
+		Eventually(func() error {
+			return provider.DeleteNodes(ctx, "fake-cluster-1", "fake-ng-highmem",
+				[]corev1.Node{{ObjectMeta: metav1.ObjectMeta{Name: "fake-highmem-0"}}})
+		}).Should(Succeed())
+		Eventually(countNodes).Should(Equal(2))
+		remaining := nodeUIDs()
+		Expect(remaining).To(HaveKey("fake-highmem-1"))
+		Expect(remaining).To(HaveKey("fake-highmem-2"))
+
+		Eventually(func() error {
+			return provider.ScaleNodeGroup(ctx, "fake-cluster-1", "fake-ng-highmem", 4)
+		}).Should(Succeed())
+		Eventually(countNodes).Should(Equal(4))
+		scaled := nodeUIDs()
+		for name, uid := range remaining {
+			Expect(scaled).To(HaveKeyWithValue(name, uid))
+		}
+
+		By("deleting only the surplus nodes when scaling down")
+		Eventually(func() error {
+			return provider.ScaleNodeGroup(ctx, "fake-cluster-1", "fake-ng-highmem", 2)
+		}).Should(Succeed())
+		Eventually(countNodes).Should(Equal(2))
+		Expect(nodeUIDs()).To(Equal(map[string]types.UID{
+			"fake-highmem-0": scaled["fake-highmem-0"],
+			"fake-highmem-1": scaled["fake-highmem-1"],
+		}))
+
+		Eventually(func() error {
+			return provider.ScaleNodeGroup(ctx, "fake-cluster-1", "fake-ng-highmem", 0)
+		}).Should(Succeed())
+		Eventually(countNodes).Should(Equal(0))
+	})
+})
@@ -0,0 +1,382 @@
+package controller
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	// ErrGuardrailViolation - изменение размера группы запрещено ограничениями
+	ErrGuardrailViolation = errors.New("scaling guardrail violation")
+	// ErrConfirmationRequired - изменение превышает пороги и требует токена подтверждения
+	ErrConfirmationRequired = errors.New("scaling confirmation required")
+)
+
+// confirmationTTL - время жизни токена подтверждения
+const confirmationTTL = 5 * time.Minute
+
+// ScalingGuardrail - ограничения масштабирования. Пустые provider/clusterID/nodeGroupID
+// означают любое значение; к изменению применяются все подходящие правила.
+type ScalingGuardrail struct {
+	Provider    string `json:"provider,omitempty"`
+	ClusterID   string `json:"clusterID,omitempty"`
+	NodeGroupID string `json:"nodeGroupID,omitempty"`
+
+	MinNodes *int `json:"minNodes,omitempty"`
+	MaxNodes *int `json:"maxNodes,omitempty"`
+	// MaxStep - максимальное изменение числа нод за один раз
+	MaxStep *int `json:"maxStep,omitempty"`
+	// MaxMonthlyCost - потолок месячных расходов: группы для правила с nodeGroupID, иначе кластера
+	MaxMonthlyCost *float64 `json:"maxMonthlyCost,omitempty"`
+
+	// ConfirmStep - изменения больше этого числа нод требуют подтверждения
+	ConfirmStep *int `json:"confirmStep,omitempty"`
+	// ConfirmMonthlyCostIncrease - рост месячных расходов больше этой суммы требует подтверждения
+	ConfirmMonthlyCostIncrease *float64 `json:"confirmMonthlyCostIncrease,omitempty"`
+}
+
+// validate проверяет, что лимиты правила неотрицательны и minNodes не больше maxNodes
+func (g ScalingGuardrail) validate() error {
+	counts := []struct {
+		name  string
+		value *int
+	}{{"minNodes", g.MinNodes}, {"maxNodes", g.MaxNodes}, {"confirmStep", g.ConfirmStep}}
+	for _, c := range counts {
+		if c.value != nil && *c.value < 0 {
+			return fmt.Errorf("%s must not be negative, got %d", c.name, *c.value)
+		}
+	}
+	costs := []struct {
+		name  string
+		value *float64
+	}{{"maxMonthlyCost", g.MaxMonthlyCost}, {"confirmMonthlyCostIncrease", g.ConfirmMonthlyCostIncrease}}
+	for _, c := range costs {
+		if c.value != nil && *c.value < 0 {
+			return fmt.Errorf("%s must not be negative, got %g", c.name, *c.value)
+		}
+	}
+	// Нулевой шаг запретил бы любое изменение размера
+	if g.MaxStep != nil && *g.MaxStep <= 0 {
+		return fmt.Errorf("maxStep must be positive, got %d", *g.MaxStep)
+	}
+	if g.MinNodes != nil && g.MaxNodes != nil && *g.MinNodes > *g.MaxNodes {
+		return fmt.Errorf("minNodes %d is greater than maxNodes %d", *g.MinNodes, *g.MaxNodes)
+	}
+	return nil
+}
+
+// validateGuardrails проверяет все правила
+func validateGuardrails(rules []ScalingGuardrail) error {
+	for i, rule := range rules {
+		if err := rule.validate(); err != nil {
+			return fmt.Errorf("rule %d: %w", i, err)
+		}
+	}
+	return nil
+}
+
+// matches проверяет, относится ли правило к группе нод
+func (g ScalingGuardrail) matches(change ScaleChange) bool {
+	return (g.Provider == "" || g.Provider == change.Provider) &&
+		(g.ClusterID == "" || g.ClusterID == change.ClusterID) &&
+		(g.NodeGroupID == "" || g.NodeGroupID == change.NodeGroupID)
+}
+
+// ScaleChange - запрошенное изменение размера группы нод
+type ScaleChange struct {
+	Provider    string `json:"provider"`
+	ClusterID   string `json:"cluster_id"`
+	NodeGroupID string `json:"group_id"`
+	Current     int    `json:"current_nodes"`
+	Target      int    `json:"target_nodes"`
+}
+
+// GuardrailError перечисляет нарушенные ограничения
+type GuardrailError struct {
+	Violations []string `json:"violations"`
+	kind       error
+}
+
+func (e *GuardrailError) Error() string {
+	return fmt.Sprintf("%s: %s", e.kind, strings.Join(e.Violations, "; "))
+}
+
+func (e *GuardrailError) Unwrap() error {
+	return e.kind
+}
+
+type pendingConfirmation struct {
+	subject string
+	expires time.Time
+}
+
+// confirmationStore выдает одноразовые токены подтверждения опасных действий.
+// Токен привязан к subject - описанию конкретного действия.
+type confirmationStore struct {
+	sync.Mutex
+	pending map[string]pendingConfirmation
+}
+
+// issue выдает токен для действия subject
+func (c *confirmationStore) issue(subject string) (string, time.Time) {
+	buf := make([]byte, 16)
+	_, _ = rand.Read(buf)
+	token := hex.EncodeToString(buf)
+	expires := time.Now().Add(confirmationTTL)
+
+	c.Lock()
+	defer c.Unlock()
+	if c.pending == nil {
+		c.pending = make(map[string]pendingConfirmation)
+	}
+	for t, pending := range c.pending {
+		if time.Now().After(pending.expires) {
+			delete(c.pending, t)
+		}
+	}
+	c.pending[token] = pendingConfirmation{subject: subject, expires: expires}
+	return token, expires
+}
+
+// consume проверяет токен и гасит его. Токен действителен только для того же действия.
+func (c *confirmationStore) consume(token, subject string) bool {
+	if token == "" {
+		return false
+	}
+
+	c.Lock()
+	defer c.Unlock()
+	pending, ok := c.pending[token]
+	if !ok || pending.subject != subject || time.Now().After(pending.expires) {
+		return false
+	}
+	delete(c.pending, token)
+	return true
+}
+
+// GuardrailSettings содержит ограничения масштабирования и выданные токены подтверждения
+type GuardrailSettings struct {
+	sync.RWMutex
+	rules         []ScalingGuardrail
+	confirmations confirmationStore
+}
+
+// NewGuardrailSettings читает ограничения из KUBEDECK_SCALING_GUARDRAILS (JSON список правил)
+func NewGuardrailSettings() *GuardrailSettings {
+	s := &GuardrailSettings{}
+
+	if raw := os.Getenv("KUBEDECK_SCALING_GUARDRAILS"); raw != "" {
+		var rules []ScalingGuardrail
+		if err := json.Unmarshal([]byte(raw), &rules); err != nil {
+			webServerLog.Error(err, "Failed to parse KUBEDECK_SCALING_GUARDRAILS")
+		} else if err := validateGuardrails(rules); err != nil {
+			webServerLog.Error(err, "Invalid KUBEDECK_SCALING_GUARDRAILS")
+		} else {
+			s.rules = rules
+		}
+	}
+	return s
+}
+
+// GetRules возвращает копию правил
+func (s *GuardrailSettings) GetRules() []ScalingGuardrail {
+	s.RLock()
+	defer s.RUnlock()
+	return append([]ScalingGuardrail{}, s.rules...)
+}
+
+// SetRules заменяет правила
+func (s *GuardrailSettings) SetRules(rules []ScalingGuardrail) {
+	s.Lock()
+	defer s.Unlock()
+	s.rules = append([]ScalingGuardrail{}, rules...)
+}
+
+// matching возвращает правила, относящиеся к изменению. Без настроек ограничений правил нет.
+func (s *GuardrailSettings) matching(change ScaleChange) []ScalingGuardrail {
+	var rules []ScalingGuardrail
+	if s == nil {
+		return nil
+	}
+	for _, rule := range s.GetRules() {
+		if rule.matches(change) {
+			rules = append(rules, rule)
+		}
+	}
+	return rules
+}
+
+// Clamp ограничивает целевой размер min/max и шагом, чтобы автоматические
+// масштабировщики двигались к цели в пределах ограничений, а не останавливались.
+func (s *GuardrailSettings) Clamp(change ScaleChange) int {
+	target := change.Target
+	for _, rule := range s.matching(change) {
+		if rule.MaxStep != nil {
+			target = min(max(target, change.Current-*rule.MaxStep), change.Current+*rule.MaxStep)
+		}
+		if rule.MaxNodes != nil {
+			target = min(target, *rule.MaxNodes)
+		}
+		if rule.MinNodes != nil {
+			target = max(target, *rule.MinNodes)
+		}
+	}
+	return target
+}
+
+// Check проверяет изменение. Возвращает GuardrailError с ErrGuardrailViolation для жестких ограничений
+// или с ErrConfirmationRequired, если изменение превышает пороги подтверждения.
+// costImpact вызывается только если правила ограничивают стоимость.
+func (s *GuardrailSettings) Check(change ScaleChange, costImpact func() (*ScaleCostImpact, error)) error {
+	rules := s.matching(change)
+	if len(rules) == 0 || change.Target == change.Current {
+		return nil
+	}
+
+	step := change.Target - change.Current
+	if step < 0 {
+		step = -step
+	}
+
+	var impact *ScaleCostImpact
+	var impactErr error
+	impactLoaded := false
+	loadImpact := func() (*ScaleCostImpact, error) {
+		if !impactLoaded {
+			impact, impactErr = costImpact()
+			impactLoaded = true
+		}
+		return impact, impactErr
+	}
+
+	var violations, confirmations []string
+	for _, rule := range rules {
+		if rule.MinNodes != nil && change.Target < *rule.MinNodes && change.Target < change.Current {
+			violations = append(violations, fmt.Sprintf("target %d is below the minimum of %d nodes", change.Target, *rule.MinNodes))
+		}
+		if rule.MaxNodes != nil && change.Target > *rule.MaxNodes && change.Target > change.Current {
+			violations = append(violations, fmt.Sprintf("target %d is above the maximum of %d nodes", change.Target, *rule.MaxNodes))
+		}
+		if rule.MaxStep != nil && step > *rule.MaxStep {
+			violations = append(violations, fmt.Sprintf("change of %d nodes exceeds the maximum step of %d", step, *rule.MaxStep))
+		}
+		if rule.ConfirmStep != nil && step > *rule.ConfirmStep {
+			confirmations = append(confirmations, fmt.Sprintf("change of %d nodes exceeds %d", step, *rule.ConfirmStep))
+		}
+
+		// Стоимость проверяется только при росте группы
+		if change.Target < change.Current || (rule.MaxMonthlyCost == nil && rule.ConfirmMonthlyCostIncrease == nil) {
+			continue
+		}
+		impact, err := loadImpact()
+		if err != nil || impact == nil || impact.NodeHourlyCost == 0 {
+			violations = append(violations, "cost of the change cannot be estimated but a cost guardrail is configured")
+			continue
+		}
+		if rule.MaxMonthlyCost != nil {
+			projected := impact.ClusterProjectedMonthlyCost
+			scope := "cluster"
+			if rule.NodeGroupID != "" {
+				projected, scope = impact.ProjectedMonthlyCost, "node group"
+			}
+			if projected > *rule.MaxMonthlyCost {
+				violations = append(violations, fmt.Sprintf("projected %s cost %.2f %s/month exceeds the ceiling of %.2f",
+					scope, projected, impact.Currency, *rule.MaxMonthlyCost))
+			}
+		}
+		if rule.ConfirmMonthlyCostIncrease != nil && impact.DeltaMonthlyCost > *rule.ConfirmMonthlyCostIncrease {
+			confirmations = append(confirmations, fmt.Sprintf("monthly cost increase %.2f %s exceeds %.2f",
+				impact.DeltaMonthlyCost, impact.Currency, *rule.ConfirmMonthlyCostIncrease))
+		}
+	}
+
+	if len(violations) > 0 {
+		return &GuardrailError{Violations: violations, kind: ErrGuardrailViolation}
+	}
+	if len(confirmations) > 0 {
+		return &GuardrailError{Violations: confirmations, kind: ErrConfirmationRequired}
+	}
+	return nil
+}
+
+// checkAutomatedScaling проверяет изменение от автоматических масштабировщиков.
+// Подтвердить изменение некому, поэтому ErrConfirmationRequired тоже блокирует масштабирование.
+func (r *KubedeckReconciler) checkAutomatedScaling(ctx context.Context, provider ClusterProvider, change ScaleChange) error {
+	return r.GuardrailSettings.Check(change, func() (*ScaleCostImpact, error) {
+		return estimateScaleCost(ctx, change.Provider, provider, change.ClusterID, change.NodeGroupID, change.Target)
+	})
+}
+
+// IssueConfirmation выдает одноразовый токен подтверждения для конкретного изменения
+func (s *GuardrailSettings) IssueConfirmation(change ScaleChange) (string, time.Time) {
+	return s.confirmations.issue(fmt.Sprintf("scale %+v", change))
+}
+
+// ConsumeConfirmation проверяет токен и гасит его. Токен действителен только для того же изменения.
+func (s *GuardrailSettings) ConsumeConfirmation(token string, change ScaleChange) bool {
+	return s.confirmations.consume(token, fmt.Sprintf("scale %+v", change))
+}
+
+// writeGuardrailError отвечает 422 на нарушение ограничений или 428 с новым токеном подтверждения
+func (r *KubedeckReconciler) writeGuardrailError(w http.ResponseWriter, change ScaleChange, err error) {
+	var guardrailErr *GuardrailError
+	if !errors.As(err, &guardrailErr) {
+		// Ограничения не удалось проверить (например, не получены цены), это не нарушение
+		writeProviderError(w, "Failed to check scaling guardrails", err)
+		return
+	}
+
+	response := map[string]interface{}{
+		"error":      err.Error(),
+		"violations": guardrailErr.Violations,
+		"change":     change,
+	}
+	status := http.StatusUnprocessableEntity
+	if errors.Is(err, ErrConfirmationRequired) {
+		token, expires := r.GuardrailSettings.IssueConfirmation(change)
+		response["confirmation_token"] = token
+		response["expires_at"] = expires
+		status = http.StatusPreconditionRequired
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(response); err != nil {
+		webServerLog.Error(err, "Failed to write guardrail response")
+	}
+}
+
+// handleCloudGuardrailsRequest возвращает (GET) или заменяет (POST) ограничения масштабирования
+func (r *KubedeckReconciler) handleCloudGuardrailsRequest(w http.ResponseWriter, req *http.Request) {
+	switch req.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		var rules []ScalingGuardrail
+		if err := json.NewDecoder(req.Body).Decode(&rules); err != nil {
+			http.Error(w, "Invalid JSON request: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		defer req.Body.Close()
+
+		if err := validateGuardrails(rules); err != nil {
+			http.Error(w, "Invalid guardrails: "+err.Error(), http.StatusBadRequest)
+			return
+		}
+		r.GuardrailSettings.SetRules(rules)
+		webServerLog.Info("Scaling guardrails updated", "rules", len(rules))
+	default:
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	writeJsonResponse(w, r.GuardrailSettings.GetRules(), "guardrails", nil)
+}
@@ -0,0 +1,130 @@
+package controller
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Scaling guardrails", func() {
+	intPtr := func(v int) *int { return &v }
+	floatPtr := func(v float64) *float64 { return &v }
+
+	change := func(current, target int) ScaleChange {
+		return ScaleChange{Provider: "fake", ClusterID: "fake-cluster-1", NodeGroupID: "fake-ng-general", Current: current, Target: target}
+	}
+	fakeImpact := func(c ScaleChange) func() (*ScaleCostImpact, error) {
+		return func() (*ScaleCostImpact, error) {
+			return estimateScaleCost(ctx, "fake", NewFakeProvider(FakeProviderOptions{}), c.ClusterID, c.NodeGroupID, c.Target)
+		}
+	}
+
+	It("should reject changes outside of min/max and step", func() {
+		settings := NewGuardrailSettings()
+		settings.SetRules([]ScalingGuardrail{{Provider: "fake", MinNodes: intPtr(1), MaxNodes: intPtr(6), MaxStep: intPtr(3)}})
+
+		err := settings.Check(change(2, 8), fakeImpact(change(2, 8)))
+		Expect(errors.Is(err, ErrGuardrailViolation)).To(BeTrue())
+		var guardrailErr *GuardrailError
+		Expect(errors.As(err, &guardrailErr)).To(BeTrue())
+		Expect(guardrailErr.Violations).To(HaveLen(2))
+
+		Expect(errors.Is(settings.Check(change(2, 0), nil), ErrGuardrailViolation)).To(BeTrue())
+		Expect(settings.Check(change(2, 4), nil)).To(Succeed())
+
+		// Возврат в допустимые пределы не блокируется
+		Expect(settings.Check(change(8, 6), nil)).To(Succeed())
+
+		// Правила другого провайдера не применяются
+		other := change(2, 8)
+		other.Provider = "timeweb"
+		Expect(settings.Check(other, nil)).To(Succeed())
+	})
+
+	It("should clamp automated targets to the guardrails", func() {
+		settings := NewGuardrailSettings()
+		settings.SetRules([]ScalingGuardrail{{MaxNodes: intPtr(6), MaxStep: intPtr(2)}})
+
+		Expect(settings.Clamp(change(2, 10))).To(Equal(4))
+		Expect(settings.Clamp(change(5, 10))).To(Equal(6))
+		Expect(settings.Clamp(change(5, 0))).To(Equal(3))
+
+		var unset *GuardrailSettings
+		Expect(unset.Clamp(change(2, 10))).To(Equal(10))
+		Expect(unset.Check(change(2, 10), nil)).To(Succeed())
+	})
+
+	It("should enforce monthly cost ceilings", func() {
+		settings := NewGuardrailSettings()
+		settings.SetRules([]ScalingGuardrail{{ClusterID: "fake-cluster-1", MaxMonthlyCost: floatPtr(9000)}})
+
+		// 5 нод: 9855 в месяц для кластера
+		Expect(errors.Is(settings.Check(change(2, 5), fakeImpact(change(2, 5))), ErrGuardrailViolation)).To(BeTrue())
+		Expect(settings.Check(change(2, 4), fakeImpact(change(2, 4)))).To(Succeed())
+
+		// Уменьшение не зависит от стоимости
+		Expect(settings.Check(change(2, 1), nil)).To(Succeed())
+
+		// Неизвестная стоимость при правиле по стоимости блокирует рост
+		unknown := func() (*ScaleCostImpact, error) { return nil, ErrProviderNotFound }
+		Expect(errors.Is(settings.Check(change(2, 3), unknown), ErrGuardrailViolation)).To(BeTrue())
+	})
+
+	It("should require a single-use confirmation for large changes", func() {
+		settings := NewGuardrailSettings()
+		settings.SetRules([]ScalingGuardrail{{ConfirmStep: intPtr(1), ConfirmMonthlyCostIncrease: floatPtr(5000)}})
+
+		requested := change(2, 4)
+		err := settings.Check(requested, fakeImpact(requested))
+		Expect(errors.Is(err, ErrConfirmationRequired)).To(BeTrue())
+
+		token, _ := settings.IssueConfirmation(requested)
+		Expect(settings.ConsumeConfirmation(token, change(2, 5))).To(BeFalse())
+		Expect(settings.ConsumeConfirmation(token, requested)).To(BeTrue())
+		Expect(settings.ConsumeConfirmation(token, requested)).To(BeFalse())
+		Expect(settings.ConsumeConfirmation("", requested)).To(BeFalse())
+	})
+
+	It("should validate rules before replacing them", func() {
+		reconciler := &KubedeckReconciler{GuardrailSettings: NewGuardrailSettings()}
+		post := func(body string) *httptest.ResponseRecorder {
+			recorder := httptest.NewRecorder()
+			reconciler.handleCloudGuardrailsRequest(recorder, httptest.NewRequest(http.MethodPost, "/cloud/guardrails", strings.NewReader(body)))
+			return recorder
+		}
+
+		Expect(post(`[{"provider":"fake","minNodes":1,"maxNodes":5}]`).Code).To(Equal(http.StatusOK))
+		for body, message := range map[string]string{
+			`[{"minNodes":-1}]`:             "minNodes must not be negative",
+			`[{"minNodes":4,"maxNodes":2}]`: "minNodes 4 is greater than maxNodes 2",
+			`[{"maxStep":0}]`:               "maxStep must be positive",
+			`[{"maxMonthlyCost":-100}]`:     "maxMonthlyCost must not be negative",
+			`[{}, {"confirmStep":-2}]`:      "rule 1: confirmStep",
+		} {
+			recorder := post(body)
+			Expect(recorder.Code).To(Equal(http.StatusBadRequest), body)
+			Expect(recorder.Body.String()).To(ContainSubstring(message))
+		}
+		Expect(reconciler.GuardrailSettings.GetRules()).To(HaveLen(1))
+		Expect(*reconciler.GuardrailSettings.GetRules()[0].MaxNodes).To(Equal(5))
+	})
+
+	It("should answer 500 instead of a violation when guardrails could not be checked", func() {
+		reconciler := &KubedeckReconciler{GuardrailSettings: NewGuardrailSettings()}
+		recorder := httptest.NewRecorder()
+		reconciler.writeGuardrailError(recorder, change(2, 4), errors.New("price list is not loaded"))
+		Expect(recorder.Code).To(Equal(http.StatusInternalServerError))
+		Expect(recorder.Body.String()).To(ContainSubstring("price list is not loaded"))
+
+		recorder = httptest.NewRecorder()
+		reconciler.GuardrailSettings.SetRules([]ScalingGuardrail{{MaxNodes: intPtr(5)}})
+		err := reconciler.GuardrailSettings.Check(change(2, 9), nil)
+		reconciler.writeGuardrailError(recorder, change(2, 9), err)
+		Expect(recorder.Code).To(Equal(http.StatusUnprocessableEntity))
+		Expect(recorder.Body.String()).To(ContainSubstring("violations"))
+	})
+})
@@ -1,6 +1,7 @@
 package controller
 
 import (
+	"context"
 	"io"
 	"net/http"
 
@@ -8,99 +9,111 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	networkingv1 "k8s.io/api/networking/v1"
 	storagev1 "k8s.io/api/storage/v1"
-	"k8s.io/client-go/kubernetes"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 func (r *KubedeckReconciler) handlePodsRequest(w http.ResponseWriter, req *http.Request) {
-	ctx := req.Context()
-	list := &corev1.PodList{}
-	err := r.Client.List(ctx, list, &client.ListOptions{Namespace: ""}) // List in all namespaces
-	writeJsonResponse(w, list.Items, "pods", err)
+	r.inClusters(w, req, "pods", func(ctx context.Context) (interface{}, error) {
+		list := &corev1.PodList{}
+		err := r.clientFor(ctx).List(ctx, list, &client.ListOptions{Namespace: ""}) // List in all namespaces
+		return list.Items, err
+	})
 }
 
 func (r *KubedeckReconciler) handlePVRequest(w http.ResponseWriter, req *http.Request) {
-	ctx := req.Context()
-	list := &corev1.PersistentVolumeList{}
-	err := r.Client.List(ctx, list, &client.ListOptions{}) // PVs are not namespaced
-	writeJsonResponse(w, list.Items, "persistent volumes", err)
+	r.inClusters(w, req, "persistent volumes", func(ctx context.Context) (interface{}, error) {
+		list := &corev1.PersistentVolumeList{}
+		err := r.clientFor(ctx).List(ctx, list, &client.ListOptions{}) // PVs are not namespaced
+		return list.Items, err
+	})
 }
 
 func (r *KubedeckReconciler) handlePVCRequest(w http.ResponseWriter, req *http.Request) {
-	ctx := req.Context()
-	list := &corev1.PersistentVolumeClaimList{}
-	err := r.Client.List(ctx, list, &client.ListOptions{Namespace: ""})
-	writeJsonResponse(w, list.Items, "persistent volume claims", err)
+	r.inClusters(w, req, "persistent volume claims", func(ctx context.Context) (interface{}, error) {
+		list := &corev1.PersistentVolumeClaimList{}
+		err := r.clientFor(ctx).List(ctx, list, &client.ListOptions{Namespace: ""})
+		return list.Items, err
+	})
 }
 
 func (r *KubedeckReconciler) handleConfigMapsRequest(w http.ResponseWriter, req *http.Request) {
-	ctx := req.Context()
-	list := &corev1.ConfigMapList{}
-	err := r.Client.List(ctx, list, &client.ListOptions{Namespace: ""})
-	writeJsonResponse(w, list.Items, "configmaps", err)
+	r.inClusters(w, req, "configmaps", func(ctx context.Context) (interface{}, error) {
+		list := &corev1.ConfigMapList{}
+		err := r.clientFor(ctx).List(ctx, list, &client.ListOptions{Namespace: ""})
+		return list.Items, err
+	})
 }
 
 func (r *KubedeckReconciler) handleServicesRequest(w http.ResponseWriter, req *http.Request) {
-	ctx := req.Context()
-	list := &corev1.ServiceList{}
-	err := r.Client.List(ctx, list, &client.ListOptions{Namespace: ""})
-	writeJsonResponse(w, list.Items, "services", err)
+	r.inClusters(w, req, "services", func(ctx context.Context) (interface{}, error) {
+		list := &corev1.ServiceList{}
+		err := r.clientFor(ctx).List(ctx, list, &client.ListOptions{Namespace: ""})
+		return list.Items, err
+	})
 }
 
 func (r *KubedeckReconciler) handleIngressesRequest(w http.ResponseWriter, req *http.Request) {
-	ctx := req.Context()
-	list := &networkingv1.IngressList{}
-	err := r.Client.List(ctx, list, &client.ListOptions{Namespace: ""})
-	writeJsonResponse(w, list.Items, "ingresses", err)
+	r.inClusters(w, req, "ingresses", func(ctx context.Context) (interface{}, error) {
+		list := &networkingv1.IngressList{}
+		err := r.clientFor(ctx).List(ctx, list, &client.ListOptions{Namespace: ""})
+		return list.Items, err
+	})
 }
 
 func (r *KubedeckReconciler) handleDeploymentsRequest(w http.ResponseWriter, req *http.Request) {
-	ctx := req.Context()
-	list := &appsv1.DeploymentList{}
-	err := r.Client.List(ctx, list, &client.ListOptions{Namespace: ""})
-	writeJsonResponse(w, list.Items, "deployments", err)
+	r.inClusters(w, req, "deployments", func(ctx context.Context) (interface{}, error) {
+		list := &appsv1.DeploymentList{}
+		err := r.clientFor(ctx).List(ctx, list, &client.ListOptions{Namespace: ""})
+		return list.Items, err
+	})
 }
 
 func (r *KubedeckReconciler) handleStatefulSetsRequest(w http.ResponseWriter, req *http.Request) {
-	ctx := req.Context()
-	list := &appsv1.StatefulSetList{}
-	err := r.Client.List(ctx, list, &client.ListOptions{Namespace: ""})
-	writeJsonResponse(w, list.Items, "statefulsets", err)
+	r.inClusters(w, req, "statefulsets", func(ctx context.Context) (interface{}, error) {
+		list := &appsv1.StatefulSetList{}
+		err := r.clientFor(ctx).List(ctx, list, &client.ListOptions{Namespace: ""})
+		return list.Items, err
+	})
 }
 
 func (r *KubedeckReconciler) handleDaemonSetsRequest(w http.ResponseWriter, req *http.Request) {
-	ctx := req.Context()
-	list := &appsv1.DaemonSetList{}
-	err := r.Client.List(ctx, list, &client.ListOptions{Namespace: ""})
-	writeJsonResponse(w, list.Items, "daemonsets", err)
+	r.inClusters(w, req, "daemonsets", func(ctx context.Context) (interface{}, error) {
+		list := &appsv1.DaemonSetList{}
+		err := r.clientFor(ctx).List(ctx, list, &client.ListOptions{Namespace: ""})
+		return list.Items, err
+	})
 }
 
 func (r *KubedeckReconciler) handleReplicaSetsRequest(w http.ResponseWriter, req *http.Request) {
-	ctx := req.Context()
-	list := &appsv1.ReplicaSetList{}
-	err := r.Client.List(ctx, list, &client.ListOptions{Namespace: ""})
-	writeJsonResponse(w, list.Items, "replicasets", err)
+	r.inClusters(w, req, "replicasets", func(ctx context.Context) (interface{}, error) {
+		list := &appsv1.ReplicaSetList{}
+		err := r.clientFor(ctx).List(ctx, list, &client.ListOptions{Namespace: ""})
+		return list.Items, err
+	})
 }
 
 func (r *KubedeckReconciler) handleNamespacesRequest(w http.ResponseWriter, req *http.Request) {
-	ctx := req.Context()
-	list := &corev1.NamespaceList{}
-	err := r.Client.List(ctx, list, &client.ListOptions{}) // Namespaces are not namespaced
-	writeJsonResponse(w, list.Items, "namespaces", err)
+	r.inClusters(w, req, "namespaces", func(ctx context.Context) (interface{}, error) {
+		list := &corev1.NamespaceList{}
+		err := r.clientFor(ctx).List(ctx, list, &client.ListOptions{}) // Namespaces are not namespaced
+		return list.Items, err
+	})
 }
 
 func (r *KubedeckReconciler) handleNodesRequest(w http.ResponseWriter, req *http.Request) {
-	ctx := req.Context()
-	list := &corev1.NodeList{}
-	err := r.Client.List(ctx, list, &client.ListOptions{}) // Nodes are not namespaced
-	writeJsonResponse(w, list.Items, "nodes", err)
+	r.inClusters(w, req, "nodes", func(ctx context.Context) (interface{}, error) {
+		list := &corev1.NodeList{}
+		err := r.clientFor(ctx).List(ctx, list, &client.ListOptions{}) // Nodes are not namespaced
+		return list.Items, err
+	})
 }
 
 func (r *KubedeckReconciler) handleStorageClassesRequest(w http.ResponseWriter, req *http.Request) {
-	ctx := req.Context()
-	list := &storagev1.StorageClassList{}
-	err := r.Client.List(ctx, list, &client.ListOptions{}) // StorageClasses are not namespaced
-	writeJsonResponse(w, list.Items, "storageclasses", err)
+	r.inClusters(w, req, "storageclasses", func(ctx context.Context) (interface{}, error) {
+		list := &storagev1.StorageClassList{}
+		err := r.clientFor(ctx).List(ctx, list, &client.ListOptions{}) // StorageClasses are not namespaced
+		return list.Items, err
+	})
 }
 
 func (r *KubedeckReconciler) handlePodLogsRequest(w http.ResponseWriter, req *http.Request) {
@@ -119,8 +132,8 @@ func (r *KubedeckReconciler) handlePodLogsRequest(w http.ResponseWriter, req *ht
 
 	log.Info("Fetching logs", "pod", podName, "namespace", namespace, "container", containerName)
 
-	// Create a standard Kubernetes clientset using the rest.Config from the reconciler
-	clientset, err := kubernetes.NewForConfig(r.Config)
+	// Get the Kubernetes clientset of the requested cluster
+	clientset, err := r.clientsetFor(ctx)
 	if err != nil {
 		log.Error(err, "Failed to create Kubernetes clientset")
 		http.Error(w, "Internal server error: failed to create Kubernetes clientset", http.StatusInternalServerError)
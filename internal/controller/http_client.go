@@ -0,0 +1,418 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math/rand"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"golang.org/x/time/rate"
+	"sigs.k8s.io/controller-runtime/pkg/metrics"
+)
+
+// ErrCircuitOpen возвращается, когда circuit breaker хоста разомкнут и запрос не отправлялся
+var ErrCircuitOpen = errors.New("circuit breaker is open")
+
+// HostPolicy описывает политику исходящих запросов к одному хосту
+type HostPolicy struct {
+	// Timeout - таймаут одной попытки (включая чтение тела ответа)
+	Timeout time.Duration
+	// MaxRetries - количество повторов после первой попытки
+	MaxRetries int
+	// BaseBackoff и MaxBackoff задают экспоненциальную задержку между попытками
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// MaxRetryAfter - если сервер просит ждать дольше, ответ возвращается без повтора
+	MaxRetryAfter time.Duration
+	// RequestsPerSecond и Burst - клиентский rate limit
+	RequestsPerSecond float64
+	Burst             int
+	// FailureThreshold - число подряд неуспешных попыток, после которого цепь размыкается
+	FailureThreshold int
+	// OpenDuration - время, на которое цепь размыкается перед пробным запросом
+	OpenDuration time.Duration
+}
+
+var defaultHostPolicy = HostPolicy{
+	Timeout:           30 * time.Second,
+	MaxRetries:        3,
+	BaseBackoff:       500 * time.Millisecond,
+	MaxBackoff:        10 * time.Second,
+	MaxRetryAfter:     time.Minute,
+	RequestsPerSecond: 10,
+	Burst:             20,
+	FailureThreshold:  5,
+	OpenDuration:      30 * time.Second,
+}
+
+// hostPolicies - политики для известных внешних API, остальные хосты используют defaultHostPolicy
+var hostPolicies = map[string]HostPolicy{
+	"api.timeweb.cloud": {
+		Timeout:           20 * time.Second,
+		MaxRetries:        3,
+		BaseBackoff:       time.Second,
+		MaxBackoff:        15 * time.Second,
+		MaxRetryAfter:     time.Minute,
+		RequestsPerSecond: 5,
+		Burst:             10,
+		FailureThreshold:  5,
+		OpenDuration:      30 * time.Second,
+	},
+	"mks.api.cloud.yandex.net": {
+		Timeout:           20 * time.Second,
+		MaxRetries:        3,
+		BaseBackoff:       time.Second,
+		MaxBackoff:        15 * time.Second,
+		MaxRetryAfter:     time.Minute,
+		RequestsPerSecond: 10,
+		Burst:             20,
+		FailureThreshold:  5,
+		OpenDuration:      30 * time.Second,
+	},
+	// Compute API используется для удаления конкретных нод групп Yandex Managed Kubernetes
+	"compute.api.cloud.yandex.net": {
+		Timeout:           20 * time.Second,
+		MaxRetries:        3,
+		BaseBackoff:       time.Second,
+		MaxBackoff:        15 * time.Second,
+		MaxRetryAfter:     time.Minute,
+		RequestsPerSecond: 10,
+		Burst:             20,
+		FailureThreshold:  5,
+		OpenDuration:      30 * time.Second,
+	},
+	// Operation API опрашивается при ожидании создания, обновления и удаления кластеров
+	"operation.api.cloud.yandex.net": {
+		Timeout:           20 * time.Second,
+		MaxRetries:        3,
+		BaseBackoff:       time.Second,
+		MaxBackoff:        15 * time.Second,
+		MaxRetryAfter:     time.Minute,
+		RequestsPerSecond: 10,
+		Burst:             20,
+		FailureThreshold:  5,
+		OpenDuration:      30 * time.Second,
+	},
+	"llm.glowbyteconsulting.com": {
+		Timeout:           45 * time.Second,
+		MaxRetries:        1,
+		BaseBackoff:       2 * time.Second,
+		MaxBackoff:        10 * time.Second,
+		MaxRetryAfter:     30 * time.Second,
+		RequestsPerSecond: 1,
+		Burst:             2,
+		FailureThreshold:  3,
+		OpenDuration:      2 * time.Minute,
+	},
+	// Telegram ограничивает ботов примерно одним сообщением в секунду на чат
+	"api.telegram.org": {
+		Timeout:           10 * time.Second,
+		MaxRetries:        3,
+		BaseBackoff:       time.Second,
+		MaxBackoff:        30 * time.Second,
+		MaxRetryAfter:     time.Minute,
+		RequestsPerSecond: 1,
+		Burst:             5,
+		FailureThreshold:  5,
+		OpenDuration:      time.Minute,
+	},
+}
+
+// Метрики исходящих запросов, регистрируются в реестре controller-runtime
+var (
+	outboundRequestsTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "kubedeck_outbound_requests_total",
+		Help: "Number of outbound HTTP attempts by host and result code.",
+	}, []string{"host", "code"})
+
+	outboundRetriesTotal = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Name: "kubedeck_outbound_retries_total",
+		Help: "Number of retried outbound HTTP attempts by host.",
+	}, []string{"host"})
+
+	outboundCircuitState = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "kubedeck_outbound_circuit_breaker_state",
+		Help: "Circuit breaker state by host: 0 - closed, 1 - open, 2 - half-open.",
+	}, []string{"host"})
+)
+
+func init() {
+	metrics.Registry.MustRegister(outboundRequestsTotal, outboundRetriesTotal, outboundCircuitState)
+}
+
+// outboundHTTPClient - общий клиент для запросов к облачным провайдерам, LLM и Telegram
+var outboundHTTPClient = newOutboundHTTPClient()
+
+// newOutboundHTTPClient создает http.Client с таймаутами, повторами, rate limit и circuit breaker.
+// Таймауты задаются на попытку в транспорте, поэтому Client.Timeout не используется.
+func newOutboundHTTPClient() *http.Client {
+	return &http.Client{Transport: newResilientTransport(http.DefaultTransport)}
+}
+
+type circuitState int
+
+const (
+	circuitClosed circuitState = iota
+	circuitOpen
+	circuitHalfOpen
+)
+
+// circuitBreaker размыкает цепь после FailureThreshold подряд неуспешных попыток
+type circuitBreaker struct {
+	sync.Mutex
+	host     string
+	policy   HostPolicy
+	state    circuitState
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// allow проверяет, можно ли отправить запрос
+func (b *circuitBreaker) allow() error {
+	b.Lock()
+	defer b.Unlock()
+
+	switch b.state {
+	case circuitOpen:
+		if time.Since(b.openedAt) < b.policy.OpenDuration {
+			return ErrCircuitOpen
+		}
+		b.setState(circuitHalfOpen)
+		b.probing = true
+		return nil
+	case circuitHalfOpen:
+		// В полуоткрытом состоянии пропускаем только один пробный запрос
+		if b.probing {
+			return ErrCircuitOpen
+		}
+		b.probing = true
+		return nil
+	default:
+		return nil
+	}
+}
+
+// record учитывает результат попытки
+func (b *circuitBreaker) record(success bool) {
+	b.Lock()
+	defer b.Unlock()
+
+	b.probing = false
+	if success {
+		b.failures = 0
+		b.setState(circuitClosed)
+		return
+	}
+
+	b.failures++
+	if b.state == circuitHalfOpen || b.failures >= b.policy.FailureThreshold {
+		b.openedAt = time.Now()
+		b.setState(circuitOpen)
+	}
+}
+
+// release освобождает пробный запрос, если он так и не был отправлен
+func (b *circuitBreaker) release() {
+	b.Lock()
+	defer b.Unlock()
+	b.probing = false
+}
+
+func (b *circuitBreaker) setState(state circuitState) {
+	b.state = state
+	outboundCircuitState.WithLabelValues(b.host).Set(float64(state))
+}
+
+// hostState хранит rate limiter и circuit breaker одного хоста
+type hostState struct {
+	policy  HostPolicy
+	limiter *rate.Limiter
+	breaker *circuitBreaker
+}
+
+// resilientTransport - http.RoundTripper с политиками по хостам
+type resilientTransport struct {
+	base  http.RoundTripper
+	mu    sync.Mutex
+	hosts map[string]*hostState
+}
+
+func newResilientTransport(base http.RoundTripper) *resilientTransport {
+	return &resilientTransport{
+		base:  base,
+		hosts: make(map[string]*hostState),
+	}
+}
+
+func (t *resilientTransport) hostState(host string) *hostState {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if state, ok := t.hosts[host]; ok {
+		return state
+	}
+
+	policy, ok := hostPolicies[host]
+	if !ok {
+		policy = defaultHostPolicy
+	}
+	state := &hostState{
+		policy:  policy,
+		limiter: rate.NewLimiter(rate.Limit(policy.RequestsPerSecond), policy.Burst),
+		breaker: &circuitBreaker{host: host, policy: policy},
+	}
+	outboundCircuitState.WithLabelValues(host).Set(float64(circuitClosed))
+	t.hosts[host] = state
+	return state
+}
+
+func (t *resilientTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Host
+	state := t.hostState(host)
+	policy := state.policy
+
+	for attempt := 0; ; attempt++ {
+		if err := state.breaker.allow(); err != nil {
+			outboundRequestsTotal.WithLabelValues(host, "circuit_open").Inc()
+			return nil, err
+		}
+		if err := state.limiter.Wait(req.Context()); err != nil {
+			state.breaker.release()
+			return nil, err
+		}
+
+		attemptReq, cancel, err := prepareAttempt(req, policy.Timeout)
+		if err != nil {
+			state.breaker.release()
+			return nil, err
+		}
+
+		resp, err := t.base.RoundTrip(attemptReq)
+		if err != nil {
+			outboundRequestsTotal.WithLabelValues(host, "error").Inc()
+		} else {
+			outboundRequestsTotal.WithLabelValues(host, strconv.Itoa(resp.StatusCode)).Inc()
+		}
+		if err != nil && req.Context().Err() != nil {
+			// Запрос отменил вызвавший код, о доступности хоста это ничего не говорит
+			state.breaker.release()
+		} else {
+			// 429 означает перегрузку на нашей стороне, а не отказ сервиса, поэтому цепь не размыкает
+			state.breaker.record(err == nil && resp.StatusCode < 500)
+		}
+
+		wait, retry := retryDecision(req, resp, err, attempt, policy)
+		if !retry || req.Context().Err() != nil {
+			if resp != nil {
+				resp.Body = &cancelOnCloseBody{ReadCloser: resp.Body, cancel: cancel}
+			} else {
+				cancel()
+			}
+			return resp, err
+		}
+
+		if resp != nil {
+			_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64*1024))
+			resp.Body.Close()
+		}
+		cancel()
+		outboundRetriesTotal.WithLabelValues(host).Inc()
+
+		timer := time.NewTimer(wait)
+		select {
+		case <-req.Context().Done():
+			timer.Stop()
+			return nil, req.Context().Err()
+		case <-timer.C:
+		}
+	}
+}
+
+// prepareAttempt клонирует запрос с таймаутом попытки и перечитываемым телом
+func prepareAttempt(req *http.Request, timeout time.Duration) (*http.Request, context.CancelFunc, error) {
+	ctx, cancel := context.WithTimeout(req.Context(), timeout)
+	attemptReq := req.Clone(ctx)
+
+	if req.Body != nil && req.GetBody != nil {
+		body, err := req.GetBody()
+		if err != nil {
+			cancel()
+			return nil, nil, err
+		}
+		attemptReq.Body = body
+	}
+	return attemptReq, cancel, nil
+}
+
+// retryDecision решает, повторять ли запрос и сколько ждать перед повтором
+func retryDecision(req *http.Request, resp *http.Response, err error, attempt int, policy HostPolicy) (time.Duration, bool) {
+	if attempt >= policy.MaxRetries {
+		return 0, false
+	}
+	// Тело без GetBody нельзя отправить повторно
+	if req.Body != nil && req.Body != http.NoBody && req.GetBody == nil {
+		return 0, false
+	}
+
+	idempotent := isIdempotentMethod(req.Method)
+	if err != nil {
+		return backoffDelay(attempt, policy), idempotent
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable:
+		// Запрос не был обработан сервером, его можно повторить для любого метода
+		if retryAfter := parseRetryAfter(resp.Header.Get("Retry-After")); retryAfter > 0 {
+			if retryAfter > policy.MaxRetryAfter {
+				return 0, false
+			}
+			return retryAfter, true
+		}
+		return backoffDelay(attempt, policy), true
+	case resp.StatusCode >= 500:
+		return backoffDelay(attempt, policy), idempotent
+	default:
+		return 0, false
+	}
+}
+
+func isIdempotentMethod(method string) bool {
+	switch method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions, http.MethodPut, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
+
+// backoffDelay - экспоненциальная задержка с джиттером
+func backoffDelay(attempt int, policy HostPolicy) time.Duration {
+	delay := policy.BaseBackoff << attempt
+	if delay <= 0 || delay > policy.MaxBackoff {
+		delay = policy.MaxBackoff
+	}
+	// Джиттер в пределах половины задержки, чтобы повторы разных клиентов не совпадали
+	half := int64(delay / 2)
+	if half <= 0 {
+		return delay
+	}
+	return time.Duration(half + rand.Int63n(half))
+}
+
+// cancelOnCloseBody отменяет контекст попытки при закрытии тела ответа
+type cancelOnCloseBody struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnCloseBody) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
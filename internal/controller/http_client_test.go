@@ -0,0 +1,197 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Outbound HTTP client", func() {
+	var (
+		server    *httptest.Server
+		transport *resilientTransport
+		client    *http.Client
+		attempts  atomic.Int32
+		respond   func(w http.ResponseWriter, req *http.Request, attempt int32)
+	)
+
+	policy := HostPolicy{
+		Timeout:           time.Second,
+		MaxRetries:        2,
+		BaseBackoff:       time.Millisecond,
+		MaxBackoff:        5 * time.Millisecond,
+		MaxRetryAfter:     2 * time.Second,
+		RequestsPerSecond: 1000,
+		Burst:             100,
+		FailureThreshold:  100,
+		OpenDuration:      time.Minute,
+	}
+
+	// withPolicy задает политику хоста тестового сервера
+	withPolicy := func(p HostPolicy) string {
+		host := strings.TrimPrefix(server.URL, "http://")
+		hostPolicies[host] = p
+		DeferCleanup(func() { delete(hostPolicies, host) })
+		return host
+	}
+	send := func(ctx context.Context, method string) (*http.Response, error) {
+		req, err := http.NewRequestWithContext(ctx, method, server.URL+"/resource", nil)
+		Expect(err).NotTo(HaveOccurred())
+		resp, err := client.Do(req)
+		if resp != nil {
+			DeferCleanup(resp.Body.Close)
+		}
+		return resp, err
+	}
+
+	BeforeEach(func() {
+		attempts.Store(0)
+		respond = func(w http.ResponseWriter, req *http.Request, attempt int32) {}
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+			respond(w, req, attempts.Add(1))
+		}))
+		DeferCleanup(server.Close)
+		transport = newResilientTransport(http.DefaultTransport)
+		client = &http.Client{Transport: transport}
+	})
+
+	It("should wait for Retry-After on 429 and give up when it is too long", func() {
+		withPolicy(policy)
+		retryAfter := "1"
+		respond = func(w http.ResponseWriter, req *http.Request, attempt int32) {
+			if attempt == 1 {
+				w.Header().Set("Retry-After", retryAfter)
+				w.WriteHeader(http.StatusTooManyRequests)
+			}
+		}
+
+		// 429 означает, что запрос не обработан, поэтому повторяется и POST
+		started := time.Now()
+		resp, err := send(ctx, http.MethodPost)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(attempts.Load()).To(BeEquivalentTo(2))
+		Expect(time.Since(started)).To(BeNumerically(">=", time.Second))
+
+		attempts.Store(0)
+		retryAfter = "60"
+		resp, err = send(ctx, http.MethodGet)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusTooManyRequests))
+		Expect(resp.Header.Get("Retry-After")).To(Equal("60"))
+		Expect(attempts.Load()).To(BeEquivalentTo(1))
+	})
+
+	It("should retry 5xx only for idempotent methods", func() {
+		withPolicy(policy)
+		respond = func(w http.ResponseWriter, req *http.Request, attempt int32) {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+
+		resp, err := send(ctx, http.MethodGet)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusBadGateway))
+		Expect(attempts.Load()).To(BeEquivalentTo(policy.MaxRetries + 1))
+
+		attempts.Store(0)
+		resp, err = send(ctx, http.MethodPost)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusBadGateway))
+		Expect(attempts.Load()).To(BeEquivalentTo(1))
+	})
+
+	It("should open the circuit after consecutive failures and close it after a successful probe", func() {
+		breakerPolicy := policy
+		breakerPolicy.MaxRetries = 0
+		breakerPolicy.FailureThreshold = 2
+		breakerPolicy.OpenDuration = 50 * time.Millisecond
+		host := withPolicy(breakerPolicy)
+
+		healthy := false
+		respond = func(w http.ResponseWriter, req *http.Request, attempt int32) {
+			if !healthy {
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		}
+		breaker := func() circuitState {
+			state := transport.hostState(host)
+			state.breaker.Lock()
+			defer state.breaker.Unlock()
+			return state.breaker.state
+		}
+
+		for range 2 {
+			_, err := send(ctx, http.MethodGet)
+			Expect(err).NotTo(HaveOccurred())
+		}
+		Expect(breaker()).To(Equal(circuitOpen))
+		_, err := send(ctx, http.MethodGet)
+		Expect(errors.Is(err, ErrCircuitOpen)).To(BeTrue())
+		Expect(attempts.Load()).To(BeEquivalentTo(2))
+
+		By("reopening the circuit when the half-open probe fails")
+		time.Sleep(breakerPolicy.OpenDuration)
+		_, err = send(ctx, http.MethodGet)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(attempts.Load()).To(BeEquivalentTo(3))
+		Expect(breaker()).To(Equal(circuitOpen))
+
+		By("closing the circuit after a successful probe")
+		healthy = true
+		time.Sleep(breakerPolicy.OpenDuration)
+		resp, err := send(ctx, http.MethodGet)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusOK))
+		Expect(breaker()).To(Equal(circuitClosed))
+	})
+
+	It("should not count requests canceled by the caller as failures", func() {
+		breakerPolicy := policy
+		breakerPolicy.FailureThreshold = 1
+		host := withPolicy(breakerPolicy)
+
+		release := make(chan struct{})
+		respond = func(w http.ResponseWriter, req *http.Request, attempt int32) {
+			select {
+			case <-release:
+			case <-req.Context().Done():
+			}
+		}
+		DeferCleanup(func() { close(release) })
+
+		requestCtx, cancel := context.WithTimeout(ctx, 50*time.Millisecond)
+		defer cancel()
+		_, err := send(requestCtx, http.MethodGet)
+		Expect(errors.Is(err, context.DeadlineExceeded)).To(BeTrue())
+		Expect(transport.hostState(host).breaker.state).To(Equal(circuitClosed))
+		Expect(transport.hostState(host).breaker.failures).To(BeZero())
+	})
+
+	It("should wait for the rate limiter and stop waiting when the caller gives up", func() {
+		limited := policy
+		limited.RequestsPerSecond = 20
+		limited.Burst = 1
+		withPolicy(limited)
+
+		started := time.Now()
+		for range 3 {
+			_, err := send(ctx, http.MethodGet)
+			Expect(err).NotTo(HaveOccurred())
+		}
+		// Первый запрос проходит сразу, каждый следующий ждет 1/20 секунды
+		Expect(time.Since(started)).To(BeNumerically(">=", 90*time.Millisecond))
+
+		requestCtx, cancel := context.WithCancel(ctx)
+		cancel()
+		_, err := send(requestCtx, http.MethodGet)
+		Expect(errors.Is(err, context.Canceled)).To(BeTrue())
+		Expect(attempts.Load()).To(BeEquivalentTo(3))
+	})
+})
@@ -3,15 +3,24 @@ package controller
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"net/http"
+	"net/url"
 	"strconv"
+	"time"
 
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
 	"k8s.io/client-go/rest"
+	"k8s.io/client-go/util/retry"
 	ctrlv1 "nikcorp.ru/kubedeck/api/v1"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	logf "sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/manager"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
 
 // KubedeckReconciler reconciles a Kubedeck object
@@ -19,10 +28,27 @@ type KubedeckReconciler struct {
 	client.Client
 	Scheme *runtime.Scheme
 	Config *rest.Config
-	// Cloud providers
-	timewebProvider     ClusterProvider
-	yandexCloudProvider ClusterProvider
+	// Cloud providers by name (timeweb, yandex, openstack, selectel, fake, capi)
+	providers           map[string]ClusterProvider
 	TelegramBotSettings *TelegramBotSettings
+	AutoscalerSettings  *AutoscalerSettings
+	GuardrailSettings   *GuardrailSettings
+	// Операции безопасного уменьшения групп нод
+	scaleIns scaleInOperations
+	// Кластеры провайдеров, доступные через параметр cluster
+	targets targetClusters
+	// Операции создания, обновления и удаления кластеров
+	clusterOps operationStore[ClusterOperation]
+	// Подтверждения удаления кластеров
+	clusterConfirmations confirmationStore
+	// Источник метрик кластера kubedeck, nil - metrics-server
+	metrics MetricsBackend
+	// Clientset кластера kubedeck для логов подов, создается при запуске
+	clientset kubernetes.Interface
+	// Снимки метрик и подов кластеров, общие для /metrics/*, анализа ресурсов и Telegram
+	snapshots snapshotCache
+	// История использования ресурсов, nil - отключена
+	history *metricsHistory
 }
 
 // Separate logger for the web server
@@ -33,8 +59,65 @@ var webServerLog = logf.Log.WithName("kubedeck-webserver")
 // +kubebuilder:rbac:groups=ctrl.nikcorp.ru,resources=kubedecks/finalizers,verbs=update
 func (r *KubedeckReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	_ = logf.FromContext(ctx)
-	// TODO(user): your logic here
-	return ctrl.Result{}, nil
+
+	kubedeck := &ctrlv1.Kubedeck{}
+	if err := r.Get(ctx, req.NamespacedName, kubedeck); err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+
+	// Плановое масштабирование групп нод. Запуски сохраняются в статусе до изменения групп и уведомлений,
+	// поэтому конфликт обновления статуса не приводит к повторному масштабированию.
+	var requeueAfter time.Duration
+	var runs []scheduledRun
+	err := r.updateKubedeckStatus(ctx, kubedeck, func(kubedeck *ctrlv1.Kubedeck) bool {
+		var changed bool
+		requeueAfter, runs, changed = r.reconcileScalingSchedules(ctx, kubedeck, time.Now())
+		return changed
+	})
+	if err != nil {
+		return ctrl.Result{}, client.IgnoreNotFound(err)
+	}
+	if len(runs) == 0 {
+		return ctrl.Result{RequeueAfter: requeueAfter}, nil
+	}
+
+	results := make([]string, len(runs))
+	errs := make([]error, len(runs))
+	for i, run := range runs {
+		results[i], errs[i] = r.runScheduledScaling(ctx, run)
+		r.notifyScheduledScaling(run.schedule, results[i], errs[i])
+	}
+
+	err = r.updateKubedeckStatus(ctx, kubedeck, func(kubedeck *ctrlv1.Kubedeck) bool {
+		changed := false
+		for i, run := range runs {
+			if applyScheduleResult(kubedeck, run, results[i], errs[i]) {
+				changed = true
+			}
+		}
+		return changed
+	})
+	return ctrl.Result{RequeueAfter: requeueAfter}, client.IgnoreNotFound(err)
+}
+
+// updateKubedeckStatus применяет mutate к объекту и сохраняет его статус. При конфликте объект
+// перечитывается и mutate применяется заново. mutate возвращает false, если сохранять нечего.
+func (r *KubedeckReconciler) updateKubedeckStatus(ctx context.Context, kubedeck *ctrlv1.Kubedeck,
+	mutate func(kubedeck *ctrlv1.Kubedeck) bool) error {
+	refresh := false
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		if refresh {
+			if err := r.Get(ctx, client.ObjectKeyFromObject(kubedeck), kubedeck); err != nil {
+				return err
+			}
+		}
+		refresh = true
+
+		if !mutate(kubedeck) {
+			return nil
+		}
+		return r.Status().Update(ctx, kubedeck)
+	})
 }
 
 // --- Handler Helper ---
@@ -58,27 +141,56 @@ func writeJsonResponse(w http.ResponseWriter, data interface{}, resourceName str
 	}
 }
 
+// writeProviderError пишет ошибку облачного провайдера с соответствующим HTTP статусом
+func writeProviderError(w http.ResponseWriter, message string, err error) {
+	webServerLog.Error(err, message)
+
+	var perr *ProviderError
+	if errors.As(err, &perr) && perr.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(perr.RetryAfter.Seconds())))
+	}
+	http.Error(w, message+": "+err.Error(), providerErrorStatus(err))
+}
+
 // --- Cloud Provider Handlers ---
+
+// getClusterProvider возвращает инициализированного провайдера по имени
+func (r *KubedeckReconciler) getClusterProvider(provider string) (ClusterProvider, int, error) {
+	clusterProvider, ok := r.providers[provider]
+	if !ok {
+		return nil, http.StatusBadRequest, fmt.Errorf("unsupported provider: %s", provider)
+	}
+
+	if clusterProvider == nil {
+		return nil, http.StatusServiceUnavailable, fmt.Errorf("provider %s is not initialized", provider)
+	}
+	return clusterProvider, http.StatusOK, nil
+}
+
 func (r *KubedeckReconciler) handleCloudClustersRequest(w http.ResponseWriter, req *http.Request) {
+	if req.Method == http.MethodPost {
+		r.handleCreateClusterRequest(w, req)
+		return
+	}
+
 	provider := req.URL.Query().Get("provider")
 	if provider == "" {
 		http.Error(w, "provider parameter is required", http.StatusBadRequest)
 		return
 	}
 
-	var clusterProvider ClusterProvider
-	switch provider {
-	case "timeweb":
-		clusterProvider = r.timewebProvider
-	case "yandex":
-		clusterProvider = r.yandexCloudProvider
-	default:
-		http.Error(w, "unsupported provider: "+provider, http.StatusBadRequest)
+	clusterProvider, status, err := r.getClusterProvider(provider)
+	if err != nil {
+		http.Error(w, err.Error(), status)
 		return
 	}
 
 	clusters, err := clusterProvider.ListClusters(req.Context())
-	writeJsonResponse(w, clusters, "cloud clusters", err)
+	if err != nil {
+		writeProviderError(w, "Failed to list cloud clusters", err)
+		return
+	}
+	writeJsonResponse(w, clusters, "cloud clusters", nil)
 }
 
 func (r *KubedeckReconciler) handleCloudNodeGroupsRequest(w http.ResponseWriter, req *http.Request) {
@@ -90,19 +202,18 @@ func (r *KubedeckReconciler) handleCloudNodeGroupsRequest(w http.ResponseWriter,
 		return
 	}
 
-	var clusterProvider ClusterProvider
-	switch provider {
-	case "timeweb":
-		clusterProvider = r.timewebProvider
-	case "yandex":
-		clusterProvider = r.yandexCloudProvider
-	default:
-		http.Error(w, "unsupported provider: "+provider, http.StatusBadRequest)
+	clusterProvider, status, err := r.getClusterProvider(provider)
+	if err != nil {
+		http.Error(w, err.Error(), status)
 		return
 	}
 
 	nodeGroups, err := clusterProvider.GetNodeGroups(req.Context(), clusterID)
-	writeJsonResponse(w, nodeGroups, "node groups", err)
+	if err != nil {
+		writeProviderError(w, "Failed to list node groups", err)
+		return
+	}
+	writeJsonResponse(w, nodeGroups, "node groups", nil)
 }
 
 func (r *KubedeckReconciler) handleCloudScaleNodeGroupRequest(w http.ResponseWriter, req *http.Request) {
@@ -127,35 +238,175 @@ func (r *KubedeckReconciler) handleCloudScaleNodeGroupRequest(w http.ResponseWri
 		return
 	}
 
-	var clusterProvider ClusterProvider
-	switch provider {
-	case "timeweb":
-		clusterProvider = r.timewebProvider
-	case "yandex":
-		clusterProvider = r.yandexCloudProvider
-	default:
-		http.Error(w, "unsupported provider: "+provider, http.StatusBadRequest)
+	clusterProvider, status, err := r.getClusterProvider(provider)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	groups, err := clusterProvider.GetNodeGroups(req.Context(), clusterID)
+	if err != nil {
+		writeProviderError(w, "Failed to get node groups", err)
+		return
+	}
+	var group *NodeGroup
+	for i := range groups {
+		if groups[i].ID == groupID {
+			group = &groups[i]
+			break
+		}
+	}
+	if group == nil {
+		http.Error(w, "Node group not found", http.StatusNotFound)
+		return
+	}
+
+	// Оценка стоимости best-effort: отсутствие цен не мешает масштабированию
+	costImpact, costErr := estimateScaleCost(req.Context(), provider, clusterProvider, clusterID, groupID, nodeCount)
+	if costErr != nil {
+		webServerLog.V(1).Info("Cost impact is not available", "error", costErr.Error())
+	}
+
+	// Ограничения проверяются на сервере; изменения сверх порогов требуют токена из предыдущего ответа 428
+	change := ScaleChange{Provider: provider, ClusterID: clusterID, NodeGroupID: groupID, Current: group.NodeCount, Target: nodeCount}
+	if err := r.GuardrailSettings.Check(change, func() (*ScaleCostImpact, error) { return costImpact, costErr }); err != nil {
+		if !errors.Is(err, ErrConfirmationRequired) || !r.GuardrailSettings.ConsumeConfirmation(req.URL.Query().Get("confirm"), change) {
+			r.writeGuardrailError(w, change, err)
+			return
+		}
+	}
+
+	// Уменьшение группы по умолчанию идет через drain выбранных нод, drain=false удаляет ноды на усмотрение провайдера
+	if deleter, ok := clusterProvider.(NodeDeleter); ok && nodeCount < group.NodeCount && req.URL.Query().Get("drain") != "false" {
+		ctx, err := r.nodeGroupContext(req.Context(), provider, clusterID)
+		if err != nil {
+			writeProviderError(w, "Failed to connect to the node group cluster", err)
+			return
+		}
+		force := req.URL.Query().Get("force") == "true"
+		op, err := r.startScaleIn(ctx, provider, deleter, clusterID, *group, nodeCount, force, costImpact)
+		if err != nil {
+			writeProviderError(w, "Failed to start node group scale-in", err)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Location", "/cloud/scale/operations/"+op.ID)
+		w.WriteHeader(http.StatusAccepted)
+		if err := json.NewEncoder(w).Encode(op); err != nil {
+			webServerLog.Error(err, "Failed to write scale-in response")
+		}
 		return
 	}
 
 	err = clusterProvider.ScaleNodeGroup(req.Context(), clusterID, groupID, nodeCount)
 	if err != nil {
-		webServerLog.Error(err, "Failed to scale node group")
-		http.Error(w, "Failed to scale node group: "+err.Error(), http.StatusInternalServerError)
+		writeProviderError(w, "Failed to scale node group", err)
 		return
 	}
 
 	response := map[string]interface{}{
-		"success":    true,
-		"message":    "Node group scaled successfully",
-		"provider":   provider,
-		"cluster_id": clusterID,
-		"group_id":   groupID,
-		"node_count": nodeCount,
+		"success":     true,
+		"message":     "Node group scaled successfully",
+		"provider":    provider,
+		"cluster_id":  clusterID,
+		"group_id":    groupID,
+		"node_count":  nodeCount,
+		"cost_impact": costImpact,
 	}
 	writeJsonResponse(w, response, "scale response", nil)
 }
 
+// NodeGroupNodesResponse - группа нод провайдера вместе с соответствующими Node и их загрузкой
+type NodeGroupNodesResponse struct {
+	Provider  string    `json:"provider"`
+	ClusterID string    `json:"cluster_id"`
+	NodeGroup NodeGroup `json:"node_group"`
+	// Metrics - загрузка группы; Metrics.Nodes содержит ноды группы с готовностью и использованием
+	Metrics *ClusterMetrics `json:"metrics"`
+	// MissingNodes - сколько нод провайдер заявляет, но еще нет в кластере (или уже удалены)
+	MissingNodes int `json:"missing_nodes"`
+}
+
+// handleCloudNodeGroupNodesRequest обрабатывает /cloud/nodegroups/{id}/nodes
+func (r *KubedeckReconciler) handleCloudNodeGroupNodesRequest(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	provider := req.URL.Query().Get("provider")
+	clusterID := req.URL.Query().Get("cluster_id")
+	groupID := req.PathValue("id")
+
+	if provider == "" || clusterID == "" || groupID == "" {
+		http.Error(w, "provider and cluster_id parameters and node group id are required", http.StatusBadRequest)
+		return
+	}
+
+	clusterProvider, status, err := r.getClusterProvider(provider)
+	if err != nil {
+		http.Error(w, err.Error(), status)
+		return
+	}
+
+	nodeGroups, err := clusterProvider.GetNodeGroups(ctx, clusterID)
+	if err != nil {
+		writeProviderError(w, "Failed to list node groups", err)
+		return
+	}
+
+	var group *NodeGroup
+	for i := range nodeGroups {
+		if nodeGroups[i].ID == groupID {
+			group = &nodeGroups[i]
+			break
+		}
+	}
+	if group == nil {
+		http.Error(w, "node group "+groupID+" not found", http.StatusNotFound)
+		return
+	}
+	if len(group.NodeLabels) == 0 {
+		http.Error(w, "provider "+provider+" does not expose node labels for node group "+groupID, http.StatusUnprocessableEntity)
+		return
+	}
+
+	ctx, err = r.nodeGroupContext(ctx, provider, clusterID)
+	if err != nil {
+		writeProviderError(w, "Failed to connect to the node group cluster", err)
+		return
+	}
+
+	nodeList := &corev1.NodeList{}
+	if err := r.clientFor(ctx).List(ctx, nodeList, client.MatchingLabels(group.NodeLabels)); err != nil {
+		writeJsonResponse(w, nil, "nodes", err)
+		return
+	}
+
+	pods, err := r.podsSnapshot(ctx)
+	if err != nil {
+		writeJsonResponse(w, nil, "pods", err)
+		return
+	}
+
+	// В загрузку группы входят все ее ноды, включая ноды с NoSchedule
+	metrics, err := r.computeClusterMetrics(ctx, nodeList.Items, pods, nil)
+	if err != nil {
+		writeJsonResponse(w, nil, "node group metrics", err)
+		return
+	}
+
+	missing := group.NodeCount - len(nodeList.Items)
+	if missing < 0 {
+		missing = 0
+	}
+
+	response := NodeGroupNodesResponse{
+		Provider:     provider,
+		ClusterID:    clusterID,
+		NodeGroup:    *group,
+		Metrics:      metrics,
+		MissingNodes: missing,
+	}
+	writeJsonResponse(w, response, "node group nodes", nil)
+}
+
 // --- Handlers for Kubernetes Resources ---
 // startWebServer starts the HTTP server in a new goroutine.
 func (r *KubedeckReconciler) startWebServer() {
@@ -173,84 +424,105 @@ func (r *KubedeckReconciler) startWebServer() {
 	mux.HandleFunc("/namespaces", r.handleNamespacesRequest)         //ok+
 	mux.HandleFunc("/nodes", r.handleNodesRequest)                   //ok+
 	mux.HandleFunc("/storageclasses", r.handleStorageClassesRequest) //ok+
-	mux.HandleFunc("/logs", r.handlePodLogsRequest)                  //ok+
+	mux.HandleFunc("/logs", r.withCluster(r.handlePodLogsRequest))   //ok+
 
 	// Update для фронта
-	mux.HandleFunc("/updatefromfront", r.HandleUpdateForFront)
+	mux.HandleFunc("/updatefromfront", r.withCluster(r.HandleUpdateForFront))
 
 	// Йобаный в рот, статистика блять cyka
-	mux.HandleFunc("/analyze/resources", r.handleResourceAnalysisRequest)
+	mux.HandleFunc("/analyze/resources", r.withCluster(r.handleResourceAnalysisRequest))
+	mux.HandleFunc("/analyze/anomalies", r.handleAnomaliesRequest)
 
 	// Pods CRUD
-	mux.HandleFunc("/pods/create", r.HandleCreatePod) //ok+
-	mux.HandleFunc("/pods/update", r.HandleUpdatePod) //ok+
-	mux.HandleFunc("/pods/delete", r.HandleDeletePod) //ok+
+	mux.HandleFunc("/pods/create", r.withCluster(r.HandleCreatePod)) //ok+
+	mux.HandleFunc("/pods/update", r.withCluster(r.HandleUpdatePod)) //ok+
+	mux.HandleFunc("/pods/delete", r.withCluster(r.HandleDeletePod)) //ok+
 
 	// ConfigMaps CRUD
-	mux.HandleFunc("/configmaps/create", r.HandleCreateConfigMap) //ok+
-	mux.HandleFunc("/configmaps/update", r.HandleUpdateConfigMap)
-	mux.HandleFunc("/configmaps/delete", r.HandleDeleteConfigMap)
+	mux.HandleFunc("/configmaps/create", r.withCluster(r.HandleCreateConfigMap)) //ok+
+	mux.HandleFunc("/configmaps/update", r.withCluster(r.HandleUpdateConfigMap))
+	mux.HandleFunc("/configmaps/delete", r.withCluster(r.HandleDeleteConfigMap))
 
 	// Deployments CRUD
-	mux.HandleFunc("/deployments/create", r.HandleCreateDeployment) //ok+
-	mux.HandleFunc("/deployments/update", r.HandleUpdateDeployment) //ok+
-	mux.HandleFunc("/deployments/delete", r.HandleDeleteDeployment)
+	mux.HandleFunc("/deployments/create", r.withCluster(r.HandleCreateDeployment)) //ok+
+	mux.HandleFunc("/deployments/update", r.withCluster(r.HandleUpdateDeployment)) //ok+
+	mux.HandleFunc("/deployments/delete", r.withCluster(r.HandleDeleteDeployment))
 
 	// Новые обработчики для метрик
 	mux.HandleFunc("/metrics/cluster", r.handleClusterMetricsRequest)
-	mux.HandleFunc("/metrics/pods", r.handlePodMetricsRequest)
+	mux.HandleFunc("/metrics/pods", r.withCluster(r.handlePodMetricsRequest))
+	mux.HandleFunc("/metrics/workloads", r.withCluster(r.handleWorkloadMetricsRequest))
+	mux.HandleFunc("/metrics/namespaces", r.withCluster(r.handleNamespaceMetricsRequest))
+	mux.HandleFunc("/metrics/history", r.handleMetricsHistoryRequest)
 
 	// PV CRUD
-	mux.HandleFunc("/pv/create", r.HandleCreatePersistentVolume)
-	mux.HandleFunc("/pv/update", r.HandleUpdatePersistentVolume)
-	mux.HandleFunc("/pv/delete", r.HandleDeletePersistentVolume)
+	mux.HandleFunc("/pv/create", r.withCluster(r.HandleCreatePersistentVolume))
+	mux.HandleFunc("/pv/update", r.withCluster(r.HandleUpdatePersistentVolume))
+	mux.HandleFunc("/pv/delete", r.withCluster(r.HandleDeletePersistentVolume))
 
 	// DaemonSets CRUD
-	mux.HandleFunc("/daemonsets/create", r.HandleCreateDaemonSet)
-	mux.HandleFunc("/daemonsets/update", r.HandleUpdateDaemonSet) //ok+
-	mux.HandleFunc("/daemonsets/delete", r.HandleDeleteDaemonSet)
+	mux.HandleFunc("/daemonsets/create", r.withCluster(r.HandleCreateDaemonSet))
+	mux.HandleFunc("/daemonsets/update", r.withCluster(r.HandleUpdateDaemonSet)) //ok+
+	mux.HandleFunc("/daemonsets/delete", r.withCluster(r.HandleDeleteDaemonSet))
 
 	// StatefulSet CRUD
-	mux.HandleFunc("/statefulsets/create", r.HandleCreateStatefulSet)
-	mux.HandleFunc("/statefulsets/update", r.HandleUpdateStatefulSet) //ok+
-	mux.HandleFunc("/statefulsets/delete", r.HandleDeleteStatefulSet)
+	mux.HandleFunc("/statefulsets/create", r.withCluster(r.HandleCreateStatefulSet))
+	mux.HandleFunc("/statefulsets/update", r.withCluster(r.HandleUpdateStatefulSet)) //ok+
+	mux.HandleFunc("/statefulsets/delete", r.withCluster(r.HandleDeleteStatefulSet))
 
 	// PVC CRUD
-	mux.HandleFunc("/pvc/create", r.HandleCreatePersistentVolumeClaim)
-	mux.HandleFunc("/pvc/update", r.HandleUpdatePersistentVolumeClaim)
-	mux.HandleFunc("/pvc/delete", r.HandleDeletePersistentVolumeClaim)
+	mux.HandleFunc("/pvc/create", r.withCluster(r.HandleCreatePersistentVolumeClaim))
+	mux.HandleFunc("/pvc/update", r.withCluster(r.HandleUpdatePersistentVolumeClaim))
+	mux.HandleFunc("/pvc/delete", r.withCluster(r.HandleDeletePersistentVolumeClaim))
 
 	// Service CRUD
-	mux.HandleFunc("/services/create", r.HandleCreateService)
-	mux.HandleFunc("/services/update", r.HandleUpdateService)
-	mux.HandleFunc("/services/delete", r.HandleDeleteService)
+	mux.HandleFunc("/services/create", r.withCluster(r.HandleCreateService))
+	mux.HandleFunc("/services/update", r.withCluster(r.HandleUpdateService))
+	mux.HandleFunc("/services/delete", r.withCluster(r.HandleDeleteService))
 
 	// Ingress CRUD
-	mux.HandleFunc("/ingresses/create", r.HandleCreateIngress)
-	mux.HandleFunc("/ingresses/update", r.HandleUpdateIngress)
-	mux.HandleFunc("/ingresses/delete", r.HandleDeleteIngress)
+	mux.HandleFunc("/ingresses/create", r.withCluster(r.HandleCreateIngress))
+	mux.HandleFunc("/ingresses/update", r.withCluster(r.HandleUpdateIngress))
+	mux.HandleFunc("/ingresses/delete", r.withCluster(r.HandleDeleteIngress))
 
 	// StorageClass CRUD
-	mux.HandleFunc("/storageclasses/create", r.HandleCreateStorageClass)
-	mux.HandleFunc("/storageclasses/update", r.HandleUpdateStorageClass)
-	mux.HandleFunc("/storageclasses/delete", r.HandleDeleteStorageClass)
+	mux.HandleFunc("/storageclasses/create", r.withCluster(r.HandleCreateStorageClass))
+	mux.HandleFunc("/storageclasses/update", r.withCluster(r.HandleUpdateStorageClass))
+	mux.HandleFunc("/storageclasses/delete", r.withCluster(r.HandleDeleteStorageClass))
 
 	// Node CRUD
-	mux.HandleFunc("/nodes/update", r.HandleUpdateNode)
-	mux.HandleFunc("/nodes/delete", r.HandleDeleteNode)
+	mux.HandleFunc("/nodes/update", r.withCluster(r.HandleUpdateNode))
+	mux.HandleFunc("/nodes/delete", r.withCluster(r.HandleDeleteNode))
 
 	// ReplicaSet CRUD
-	mux.HandleFunc("/replicasets/create", r.HandleCreateReplicaSet)
-	mux.HandleFunc("/replicasets/update", r.HandleUpdateReplicaSet) //ok хз он не делает replicas их readi
-	mux.HandleFunc("/replicasets/delete", r.HandleDeleteReplicaSet)
+	mux.HandleFunc("/replicasets/create", r.withCluster(r.HandleCreateReplicaSet))
+	mux.HandleFunc("/replicasets/update", r.withCluster(r.HandleUpdateReplicaSet)) //ok хз он не делает replicas их readi
+	mux.HandleFunc("/replicasets/delete", r.withCluster(r.HandleDeleteReplicaSet))
 
 	// Namespaces CRUD (пример для кластерного)
-	mux.HandleFunc("/namespaces/create", r.HandleCreateNamespace)
+	mux.HandleFunc("/namespaces/create", r.withCluster(r.HandleCreateNamespace))
+
+	// Кластеры, доступные через параметр cluster
+	mux.HandleFunc("/clusters", r.handleClustersRequest)
 
 	// Cloud Provider Handlers
 	mux.HandleFunc("/cloud/clusters", r.handleCloudClustersRequest)
+	mux.HandleFunc("/cloud/clusters/{id}", r.handleCloudClusterRequest)
+	mux.HandleFunc("/cloud/clusters/{id}/upgrades", r.handleClusterUpgradesRequest)
+	mux.HandleFunc("/cloud/clusters/{id}/upgrade", r.handleClusterUpgradeRequest)
+	mux.HandleFunc("/cloud/versions", r.handleCloudVersionsRequest)
+	mux.HandleFunc("/cloud/operations", r.handleCloudOperationsRequest)
+	mux.HandleFunc("/cloud/operations/{id}", r.handleCloudOperationsRequest)
 	mux.HandleFunc("/cloud/nodegroups", r.handleCloudNodeGroupsRequest)
 	mux.HandleFunc("/cloud/scale", r.handleCloudScaleNodeGroupRequest)
+	mux.HandleFunc("/cloud/scale/operations", r.handleCloudScaleOperationsRequest)
+	mux.HandleFunc("/cloud/scale/operations/{id}", r.handleCloudScaleOperationsRequest)
+	mux.HandleFunc("/cloud/nodegroups/{id}/nodes", r.handleCloudNodeGroupNodesRequest)
+	mux.HandleFunc("/cloud/costs", r.handleCloudCostsRequest)
+	mux.HandleFunc("/cloud/guardrails", r.handleCloudGuardrailsRequest)
+
+	mux.HandleFunc("/autoscaler/config", r.handleAutoscalerConfigRequest)
+	mux.HandleFunc("/autoscaler/decisions", r.handleAutoscalerDecisionsRequest)
 
 	mux.HandleFunc("/telegram/config", r.handleTelegramBotConfigRequest)
 
@@ -262,19 +534,90 @@ func (r *KubedeckReconciler) startWebServer() {
 
 // SetupWithManager sets up the controller with the Manager.
 func (r *KubedeckReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	// Initialize cloud providers
-	timewebProvider, err := NewClusterProvider("timeweb")
-	if err != nil {
-		webServerLog.Error(err, "Failed to initialize TimeWeb provider")
+	// Initialize cloud providers enabled in KUBEDECK_CLOUD_PROVIDERS
+	r.providers = make(map[string]ClusterProvider)
+	for _, name := range getEnvList("KUBEDECK_CLOUD_PROVIDERS", []string{"timeweb", "yandex"}) {
+		provider, err := NewClusterProvider(name, mgr.GetClient())
+		if err != nil {
+			webServerLog.Error(err, "Failed to initialize cloud provider", "provider", name)
+			continue
+		}
+		r.providers[name] = provider
+
+		// Провайдеры с фоновой работой (например, fake с управлением Node) запускаются менеджером
+		if runnable, ok := provider.(manager.Runnable); ok {
+			if err := mgr.Add(runnable); err != nil {
+				return err
+			}
+		}
+	}
+
+	// Реестр кластеров из Secret работает на всех репликах вместе с веб-сервером
+	if err := mgr.Add(nonLeaderRunnable(func(ctx context.Context) error {
+		return r.runClusterRegistry(ctx, mgr.GetAPIReader())
+	})); err != nil {
+		return err
+	}
+
+	// Источник метрик кластера kubedeck из KUBEDECK_METRICS_BACKEND
+	backendType := getEnvString("KUBEDECK_METRICS_BACKEND", "metrics-server")
+	if backend, err := NewMetricsBackend(backendType, r.Config); err != nil {
+		webServerLog.Error(err, "Failed to initialize metrics backend, falling back to metrics-server", "backend", backendType)
 	} else {
-		r.timewebProvider = timewebProvider
+		r.metrics = backend
+	}
+	if r.metrics == nil {
+		backend, err := newMetricsServerBackend(r.Config)
+		if err != nil {
+			return err
+		}
+		r.metrics = backend
 	}
+	clientset, err := kubernetes.NewForConfig(r.Config)
+	if err != nil {
+		return fmt.Errorf("failed to create kubernetes clientset: %w", err)
+	}
+	r.clientset = clientset
+	r.snapshots.ttl = getEnvDuration("KUBEDECK_METRICS_CACHE_TTL", defaultSnapshotTTL)
 
-	yandexProvider, err := NewClusterProvider("yandex")
+	// История метрик пишется на каждой реплике, чтобы любая могла ответить на /metrics/history
+	history, err := newMetricsHistoryFromEnv()
 	if err != nil {
-		webServerLog.Error(err, "Failed to initialize Yandex Cloud provider")
-	} else {
-		r.yandexCloudProvider = yandexProvider
+		webServerLog.Error(err, "Failed to open metrics history, history is disabled")
+	}
+	r.history = history
+	if err := mgr.Add(nonLeaderRunnable(r.runMetricsSampler)); err != nil {
+		return err
+	}
+
+	// Отбор нод для утилизации кластера из переменных окружения проверяется при запуске
+	if _, err := parseNodeFilter(url.Values{}); err != nil {
+		return fmt.Errorf("invalid node selection for cluster metrics: %w", err)
+	}
+
+	// Метрики кластеров для Prometheus собирает только лидер
+	if err := mgr.Add(manager.RunnableFunc(r.runClusterMetricsExporter)); err != nil {
+		return err
+	}
+
+	// CloudCluster и CloudNodeGroup синхронизирует только лидер
+	if err := mgr.Add(manager.RunnableFunc(r.runCloudResourceSync)); err != nil {
+		return err
+	}
+	if err := ctrl.NewControllerManagedBy(mgr).
+		For(&ctrlv1.CloudNodeGroup{}).
+		Named("cloudnodegroup").
+		Complete(reconcile.Func(r.reconcileCloudNodeGroup)); err != nil {
+		return err
+	}
+
+	// Ограничения масштабирования общие для API, автоскейлера и расписаний
+	r.GuardrailSettings = NewGuardrailSettings()
+
+	// Автоскейлер групп нод запускается менеджером, чтобы работать только на лидере
+	r.AutoscalerSettings = NewAutoscalerSettings()
+	if err := mgr.Add(manager.RunnableFunc(r.runAutoscaler)); err != nil {
+		return err
 	}
 
 	// Initialize Telegram bot settings
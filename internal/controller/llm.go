@@ -11,10 +11,7 @@ import (
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/metrics/pkg/apis/metrics/v1beta1"
-	metricsv "k8s.io/metrics/pkg/client/clientset/versioned"
-	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 const (
@@ -37,6 +34,8 @@ type PodResourceInfo struct {
 	MemPercentage float64 `json:"memPercentage,omitempty"`
 	Status        string  `json:"status,omitempty"`
 	Replicas      int     `json:"replicas,omitempty"`
+	// Containers - рекомендации по контейнерам от встроенного рекомендателя
+	Containers []ContainerRecommendation `json:"containers,omitempty"`
 }
 
 // LLMRequest represents the request structure for the LLM API
@@ -79,19 +78,21 @@ func (r *KubedeckReconciler) handleResourceAnalysisRequest(w http.ResponseWriter
 	ctx := req.Context()
 	log := webServerLog.WithName("handleResourceAnalysisRequest")
 
-	// Collect pod resource data
-	podResourceData, err := r.collectPodResourceData(ctx)
+	// Choose the recommender: the LLM or the built-in rightsizing engine
+	engine, narrative, err := recommenderParams(req)
 	if err != nil {
-		log.Error(err, "Failed to collect pod resource data")
-		http.Error(w, "Failed to collect pod resource data: "+err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if _, ok := ctx.Value(targetClusterKey{}).(*TargetCluster); ok && engine == recommenderRightsizing {
+		http.Error(w, "rightsizing uses the metrics history of the local cluster only", http.StatusBadRequest)
 		return
 	}
 
-	// Send data to LLM for analysis
-	recommendation, err := r.getLLMResourceRecommendations(ctx, podResourceData)
+	recommendation, err := r.resourceRecommendations(ctx, engine, narrative)
 	if err != nil {
-		log.Error(err, "Failed to get LLM recommendations")
-		http.Error(w, "Failed to get LLM recommendations: "+err.Error(), http.StatusInternalServerError)
+		log.Error(err, "Failed to get resource recommendations", "engine", engine)
+		http.Error(w, "Failed to get resource recommendations: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
@@ -101,27 +102,21 @@ func (r *KubedeckReconciler) handleResourceAnalysisRequest(w http.ResponseWriter
 
 // collectPodResourceData collects resource data for all pods in the cluster
 func (r *KubedeckReconciler) collectPodResourceData(ctx context.Context) (map[string][]PodResourceInfo, error) {
-	// Get all pods
-	var podList corev1.PodList
-	if err := r.Client.List(ctx, &podList, &client.ListOptions{}); err != nil {
-		return nil, fmt.Errorf("failed to list pods: %w", err)
-	}
-
-	// Get metrics client
-	metricsClient, err := metricsv.NewForConfig(r.Config)
+	// Get all pods from the shared snapshot
+	pods, err := r.podsSnapshot(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to create metrics client: %w", err)
+		return nil, err
 	}
 
-	// Get pod metrics
-	podMetricsList, err := metricsClient.MetricsV1beta1().PodMetricses(corev1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	// Get pod metrics from the shared snapshot
+	snapshot, err := r.metricsSnapshot(ctx)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get pod metrics: %w", err)
+		return nil, err
 	}
 
 	// Create a map of pod metrics for quick lookup
 	podMetricsMap := make(map[string]v1beta1.PodMetrics)
-	for _, metric := range podMetricsList.Items {
+	for _, metric := range snapshot.pods {
 		key := fmt.Sprintf("%s/%s", metric.Namespace, metric.Name)
 		podMetricsMap[key] = metric
 	}
@@ -129,7 +124,7 @@ func (r *KubedeckReconciler) collectPodResourceData(ctx context.Context) (map[st
 	// Organize pod data by namespace
 	result := make(map[string][]PodResourceInfo)
 
-	for _, pod := range podList.Items {
+	for _, pod := range pods {
 		// Skip pods that are not Running
 		if pod.Status.Phase != corev1.PodRunning {
 			continue
@@ -212,12 +207,49 @@ func (r *KubedeckReconciler) collectPodResourceData(ctx context.Context) (map[st
 	return result, nil
 }
 
-// getLLMResourceRecommendations sends pod resource data to the LLM and gets recommendations
+// getLLMResourceRecommendations sends pod resource data to the LLM and gets recommendations.
+// Calls, latency and the problematic pods found are exported as Prometheus metrics.
 func (r *KubedeckReconciler) getLLMResourceRecommendations(ctx context.Context, podResourceData map[string][]PodResourceInfo) (*ResourceRecommendationResponse, error) {
-	log := webServerLog.WithName("getLLMResourceRecommendations")
+	start := time.Now()
+	recommendation, err := r.requestLLMResourceRecommendations(ctx, podResourceData)
+	llmRequestDuration.Observe(time.Since(start).Seconds())
+	llmRequestsTotal.WithLabelValues(resultLabel(err)).Inc()
+	if err == nil {
+		recordProblematicPods(recommendation)
+	}
+	return recommendation, err
+}
 
-	// Create the prompt for the LLM
-	prompt := r.createLLMPrompt(podResourceData)
+// requestLLMResourceRecommendations performs the LLM API call and parses the recommendations
+func (r *KubedeckReconciler) requestLLMResourceRecommendations(ctx context.Context, podResourceData map[string][]PodResourceInfo) (*ResourceRecommendationResponse, error) {
+	// Create the prompt for the LLM and send it
+	content, err := callLLM(ctx, r.createLLMPrompt(podResourceData))
+	if err != nil {
+		return nil, err
+	}
+
+	// Parse the LLM response to get the recommendations
+	var recommendation ResourceRecommendationResponse
+	if err := json.Unmarshal([]byte(content), &recommendation); err != nil {
+		// If we can't parse the JSON directly, try to extract it from the content
+		jsonStart := strings.Index(content, "{")
+		jsonEnd := strings.LastIndex(content, "}")
+		if jsonStart != -1 && jsonEnd != -1 && jsonEnd > jsonStart {
+			jsonContent := content[jsonStart : jsonEnd+1]
+			if err := json.Unmarshal([]byte(jsonContent), &recommendation); err != nil {
+				return nil, fmt.Errorf("failed to extract and parse recommendation JSON: %w", err)
+			}
+		} else {
+			return nil, fmt.Errorf("failed to parse recommendation: %w", err)
+		}
+	}
+
+	return &recommendation, nil
+}
+
+// callLLM sends a single user prompt to the LLM API and returns the content of the first choice
+func callLLM(ctx context.Context, prompt string) (string, error) {
+	log := webServerLog.WithName("callLLM")
 
 	// Create the LLM request
 	llmReq := LLMRequest{
@@ -234,16 +266,13 @@ func (r *KubedeckReconciler) getLLMResourceRecommendations(ctx context.Context,
 	// Marshal the request
 	reqBody, err := json.Marshal(llmReq)
 	if err != nil {
-		return nil, fmt.Errorf("failed to marshal LLM request: %w", err)
+		return "", fmt.Errorf("failed to marshal LLM request: %w", err)
 	}
 
-	// Create HTTP request with timeout
-	httpCtx, cancel := context.WithTimeout(ctx, 45*time.Second)
-	defer cancel()
-
-	httpReq, err := http.NewRequestWithContext(httpCtx, "POST", LLMApiURL, bytes.NewBuffer(reqBody))
+	// Timeout, retries and rate limiting come from the LLM host policy of outboundHTTPClient
+	httpReq, err := http.NewRequestWithContext(ctx, "POST", LLMApiURL, bytes.NewReader(reqBody))
 	if err != nil {
-		return nil, fmt.Errorf("failed to create HTTP request: %w", err)
+		return "", fmt.Errorf("failed to create HTTP request: %w", err)
 	}
 
 	httpReq.Header.Set("Content-Type", "application/json")
@@ -251,10 +280,9 @@ func (r *KubedeckReconciler) getLLMResourceRecommendations(ctx context.Context,
 
 	// Send the request
 	log.Info("Sending request to LLM API", "url", LLMApiURL)
-	client := &http.Client{}
-	resp, err := client.Do(httpReq)
+	resp, err := outboundHTTPClient.Do(httpReq)
 	if err != nil {
-		return nil, fmt.Errorf("failed to send request to LLM API: %w", err)
+		return "", fmt.Errorf("failed to send request to LLM API: %w", err)
 	}
 	defer resp.Body.Close()
 
@@ -262,43 +290,26 @@ func (r *KubedeckReconciler) getLLMResourceRecommendations(ctx context.Context,
 	if resp.StatusCode != http.StatusOK {
 		var errorResponse map[string]any
 		if err := json.NewDecoder(resp.Body).Decode(&errorResponse); err != nil {
-			return nil, fmt.Errorf("LLM API returned status %d", resp.StatusCode)
+			return "", fmt.Errorf("LLM API returned status %d", resp.StatusCode)
 		}
-		return nil, fmt.Errorf("LLM API returned status %d: %v", resp.StatusCode, errorResponse)
+		return "", fmt.Errorf("LLM API returned status %d: %v", resp.StatusCode, errorResponse)
 	}
 
 	// Parse the response
 	var llmResp LLMResponse
 	if err := json.NewDecoder(resp.Body).Decode(&llmResp); err != nil {
-		return nil, fmt.Errorf("failed to decode LLM response: %w", err)
+		return "", fmt.Errorf("failed to decode LLM response: %w", err)
 	}
 
 	// Check if the response has at least one choice
 	if len(llmResp.Choices) == 0 {
-		return nil, fmt.Errorf("LLM response has no choices")
+		return "", fmt.Errorf("LLM response has no choices")
 	}
 
 	// Extract the content
 	content := llmResp.Choices[0].Message.Content
 	log.Info("Received response from LLM", "content", content)
-
-	// Parse the LLM response to get the recommendations
-	var recommendation ResourceRecommendationResponse
-	if err := json.Unmarshal([]byte(content), &recommendation); err != nil {
-		// If we can't parse the JSON directly, try to extract it from the content
-		jsonStart := strings.Index(content, "{")
-		jsonEnd := strings.LastIndex(content, "}")
-		if jsonStart != -1 && jsonEnd != -1 && jsonEnd > jsonStart {
-			jsonContent := content[jsonStart : jsonEnd+1]
-			if err := json.Unmarshal([]byte(jsonContent), &recommendation); err != nil {
-				return nil, fmt.Errorf("failed to extract and parse recommendation JSON: %w", err)
-			}
-		} else {
-			return nil, fmt.Errorf("failed to parse recommendation: %w", err)
-		}
-	}
-
-	return &recommendation, nil
+	return content, nil
 }
 
 // createLLMPrompt creates the prompt for the LLM based on pod resource data
@@ -0,0 +1,317 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/rest"
+	"k8s.io/metrics/pkg/apis/metrics/v1beta1"
+	metricsv "k8s.io/metrics/pkg/client/clientset/versioned"
+)
+
+// MetricsBackend - источник текущего использования ресурсов нод, подов и контейнеров.
+// Результат в формате Metrics API, чтобы потребители не зависели от источника.
+type MetricsBackend interface {
+	// NodeMetrics возвращает использование всех нод
+	NodeMetrics(ctx context.Context) ([]v1beta1.NodeMetrics, error)
+	// PodMetrics возвращает использование подов namespace ("" - всех namespace).
+	// Непустой name ограничивает результат одним подом.
+	PodMetrics(ctx context.Context, namespace, name string) ([]v1beta1.PodMetrics, error)
+}
+
+// NewMetricsBackend создает источник метрик по имени: metrics-server или prometheus
+func NewMetricsBackend(backendType string, config *rest.Config) (MetricsBackend, error) {
+	switch backendType {
+	case "", "metrics-server":
+		return newMetricsServerBackend(config)
+	case "prometheus":
+		return NewPrometheusBackend()
+	default:
+		return nil, fmt.Errorf("unknown metrics backend: %s", backendType)
+	}
+}
+
+// metricsBackendFor возвращает источник метрик кластера из контекста.
+// Для кластера kubedeck используется настроенный источник, для остальных кластеров - их metrics-server,
+// созданный при регистрации кластера.
+func (r *KubedeckReconciler) metricsBackendFor(ctx context.Context) (MetricsBackend, error) {
+	if cluster, ok := ctx.Value(targetClusterKey{}).(*TargetCluster); ok {
+		if cluster.metrics != nil {
+			return cluster.metrics, nil
+		}
+	} else if r.metrics != nil {
+		return r.metrics, nil
+	}
+	return newMetricsServerBackend(r.configFor(ctx))
+}
+
+// metricsServerBackend читает Metrics API (metrics-server)
+type metricsServerBackend struct {
+	client *metricsv.Clientset
+}
+
+func newMetricsServerBackend(config *rest.Config) (*metricsServerBackend, error) {
+	client, err := metricsv.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create metrics client: %w", err)
+	}
+	return &metricsServerBackend{client: client}, nil
+}
+
+func (b *metricsServerBackend) NodeMetrics(ctx context.Context) ([]v1beta1.NodeMetrics, error) {
+	list, err := b.client.MetricsV1beta1().NodeMetricses().List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list node metrics: %w", err)
+	}
+	return list.Items, nil
+}
+
+func (b *metricsServerBackend) PodMetrics(ctx context.Context, namespace, name string) ([]v1beta1.PodMetrics, error) {
+	if name != "" {
+		metrics, err := b.client.MetricsV1beta1().PodMetricses(namespace).Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get pod metrics: %w", err)
+		}
+		return []v1beta1.PodMetrics{*metrics}, nil
+	}
+	list, err := b.client.MetricsV1beta1().PodMetricses(namespace).List(ctx, metav1.ListOptions{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pod metrics: %w", err)
+	}
+	return list.Items, nil
+}
+
+// PrometheusBackend считает использование по метрикам cAdvisor через HTTP API Prometheus.
+// CPU - скорость роста container_cpu_usage_seconds_total за rateWindow, память - working set, как в metrics-server.
+type PrometheusBackend struct {
+	baseURL string
+	token   string
+	// rateWindow - окно rate() для CPU
+	rateWindow time.Duration
+	// nodeLabel - метка с именем ноды в рядах cAdvisor
+	nodeLabel string
+	client    *http.Client
+}
+
+// NewPrometheusBackend создает источник метрик по настройкам KUBEDECK_PROMETHEUS_*
+func NewPrometheusBackend() (*PrometheusBackend, error) {
+	baseURL := strings.TrimSuffix(getEnvString("KUBEDECK_PROMETHEUS_URL", ""), "/")
+	if baseURL == "" {
+		return nil, fmt.Errorf("KUBEDECK_PROMETHEUS_URL is required for the prometheus metrics backend")
+	}
+	return &PrometheusBackend{
+		baseURL:    baseURL,
+		token:      getEnvString("KUBEDECK_PROMETHEUS_TOKEN", ""),
+		rateWindow: getEnvDuration("KUBEDECK_PROMETHEUS_RATE_WINDOW", 5*time.Minute),
+		nodeLabel:  getEnvString("KUBEDECK_PROMETHEUS_NODE_LABEL", "node"),
+		client:     outboundHTTPClient,
+	}, nil
+}
+
+// PromSample - значение instant vector с метками ряда
+type PromSample struct {
+	Labels map[string]string
+	Value  float64
+}
+
+// promResponse - ответ /api/v1/query
+type promResponse struct {
+	Status    string `json:"status"`
+	ErrorType string `json:"errorType"`
+	Error     string `json:"error"`
+	Data      struct {
+		ResultType string `json:"resultType"`
+		Result     []struct {
+			Metric map[string]string `json:"metric"`
+			Value  [2]interface{}    `json:"value"`
+		} `json:"result"`
+	} `json:"data"`
+}
+
+// Query выполняет instant-запрос PromQL и возвращает элементы вектора
+func (p *PrometheusBackend) Query(ctx context.Context, query string) ([]PromSample, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet,
+		p.baseURL+"/api/v1/query?"+url.Values{"query": {query}}.Encode(), nil)
+	if err != nil {
+		return nil, err
+	}
+	if p.token != "" {
+		req.Header.Set("Authorization", "Bearer "+p.token)
+	}
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("prometheus query failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	// Ошибки PromQL приходят с кодом 400/422 и телом {"status":"error"}
+	var parsed promResponse
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		if resp.StatusCode >= 400 {
+			return nil, &ProviderError{Provider: "prometheus", StatusCode: resp.StatusCode, kind: ErrProviderUnavailable}
+		}
+		return nil, fmt.Errorf("failed to decode prometheus response: %w", err)
+	}
+	if parsed.Status != "success" {
+		return nil, fmt.Errorf("prometheus query failed: %s: %s", parsed.ErrorType, parsed.Error)
+	}
+	if parsed.Data.ResultType != "vector" {
+		return nil, fmt.Errorf("prometheus query returned %s, expected vector", parsed.Data.ResultType)
+	}
+
+	samples := make([]PromSample, 0, len(parsed.Data.Result))
+	for _, item := range parsed.Data.Result {
+		raw, _ := item.Value[1].(string)
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid prometheus sample value %q: %w", raw, err)
+		}
+		samples = append(samples, PromSample{Labels: item.Metric, Value: value})
+	}
+	return samples, nil
+}
+
+// promMatchers собирает селектор рядов контейнеров с фильтром по namespace и поду
+func promMatchers(namespace, name string) string {
+	matchers := []string{`container!=""`, `container!="POD"`}
+	if namespace != "" {
+		matchers = append(matchers, fmt.Sprintf("namespace=%q", namespace))
+	}
+	if name != "" {
+		matchers = append(matchers, fmt.Sprintf("pod=%q", name))
+	}
+	return strings.Join(matchers, ",")
+}
+
+// promDuration форматирует длительность для PromQL (целые секунды)
+func promDuration(d time.Duration) string {
+	return strconv.FormatInt(int64(d.Seconds()), 10) + "s"
+}
+
+func (p *PrometheusBackend) NodeMetrics(ctx context.Context) ([]v1beta1.NodeMetrics, error) {
+	// Корневой cgroup (id="/") cAdvisor - использование всей ноды
+	cpu, err := p.Query(ctx, fmt.Sprintf(`sum by (%s) (rate(container_cpu_usage_seconds_total{id="/"}[%s]))`,
+		p.nodeLabel, promDuration(p.rateWindow)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query node cpu: %w", err)
+	}
+	memory, err := p.Query(ctx, fmt.Sprintf(`sum by (%s) (container_memory_working_set_bytes{id="/"})`, p.nodeLabel))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query node memory: %w", err)
+	}
+
+	usage := map[string]corev1.ResourceList{}
+	add := func(samples []PromSample, name corev1.ResourceName, quantity func(float64) *resource.Quantity) {
+		for _, sample := range samples {
+			node := sample.Labels[p.nodeLabel]
+			if node == "" {
+				continue
+			}
+			if usage[node] == nil {
+				usage[node] = corev1.ResourceList{}
+			}
+			usage[node][name] = *quantity(sample.Value)
+		}
+	}
+	add(cpu, corev1.ResourceCPU, cpuQuantity)
+	add(memory, corev1.ResourceMemory, memoryQuantity)
+
+	now := metav1.Now()
+	result := make([]v1beta1.NodeMetrics, 0, len(usage))
+	for node, list := range usage {
+		result = append(result, v1beta1.NodeMetrics{
+			ObjectMeta: metav1.ObjectMeta{Name: node},
+			Timestamp:  now,
+			Window:     metav1.Duration{Duration: p.rateWindow},
+			Usage:      list,
+		})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+func (p *PrometheusBackend) PodMetrics(ctx context.Context, namespace, name string) ([]v1beta1.PodMetrics, error) {
+	matchers := promMatchers(namespace, name)
+	cpu, err := p.Query(ctx, fmt.Sprintf(`sum by (namespace, pod, container) (rate(container_cpu_usage_seconds_total{%s}[%s]))`,
+		matchers, promDuration(p.rateWindow)))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pod cpu: %w", err)
+	}
+	memory, err := p.Query(ctx, fmt.Sprintf(`sum by (namespace, pod, container) (container_memory_working_set_bytes{%s})`, matchers))
+	if err != nil {
+		return nil, fmt.Errorf("failed to query pod memory: %w", err)
+	}
+
+	// namespace/pod -> контейнер -> использование
+	usage := map[string]map[string]corev1.ResourceList{}
+	add := func(samples []PromSample, resourceName corev1.ResourceName, quantity func(float64) *resource.Quantity) {
+		for _, sample := range samples {
+			key := sample.Labels["namespace"] + "/" + sample.Labels["pod"]
+			container := sample.Labels["container"]
+			if usage[key] == nil {
+				usage[key] = map[string]corev1.ResourceList{}
+			}
+			if usage[key][container] == nil {
+				usage[key][container] = corev1.ResourceList{}
+			}
+			usage[key][container][resourceName] = *quantity(sample.Value)
+		}
+	}
+	add(cpu, corev1.ResourceCPU, cpuQuantity)
+	add(memory, corev1.ResourceMemory, memoryQuantity)
+
+	if name != "" && len(usage) == 0 {
+		return nil, fmt.Errorf("no metrics for pod %s/%s in prometheus", namespace, name)
+	}
+
+	now := metav1.Now()
+	result := make([]v1beta1.PodMetrics, 0, len(usage))
+	for key, containers := range usage {
+		namespace, pod, _ := strings.Cut(key, "/")
+		metrics := v1beta1.PodMetrics{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: pod},
+			Timestamp:  now,
+			Window:     metav1.Duration{Duration: p.rateWindow},
+		}
+		for container, list := range containers {
+			// Контейнер без одной из метрик получает нулевое значение, как в metrics-server
+			if _, ok := list[corev1.ResourceCPU]; !ok {
+				list[corev1.ResourceCPU] = *cpuQuantity(0)
+			}
+			if _, ok := list[corev1.ResourceMemory]; !ok {
+				list[corev1.ResourceMemory] = *memoryQuantity(0)
+			}
+			metrics.Containers = append(metrics.Containers, v1beta1.ContainerMetrics{Name: container, Usage: list})
+		}
+		sort.Slice(metrics.Containers, func(i, j int) bool { return metrics.Containers[i].Name < metrics.Containers[j].Name })
+		result = append(result, metrics)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Namespace != result[j].Namespace {
+			return result[i].Namespace < result[j].Namespace
+		}
+		return result[i].Name < result[j].Name
+	})
+	return result, nil
+}
+
+// cpuQuantity переводит ядра в quantity с точностью до наноядер
+func cpuQuantity(cores float64) *resource.Quantity {
+	return resource.NewScaledQuantity(int64(cores*1e9), resource.Nano)
+}
+
+// memoryQuantity переводит байты в quantity
+func memoryQuantity(bytes float64) *resource.Quantity {
+	return resource.NewQuantity(int64(bytes), resource.BinarySI)
+}
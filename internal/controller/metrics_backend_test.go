@@ -0,0 +1,149 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// prometheusStandIn - httptest-замена HTTP API Prometheus, отвечает векторами по подстроке запроса
+type prometheusStandIn struct {
+	*httptest.Server
+	queries []string
+}
+
+func newPrometheusStandIn(vectors map[string][]map[string]interface{}) *prometheusStandIn {
+	s := &prometheusStandIn{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		Expect(req.URL.Path).To(Equal("/api/v1/query"))
+		query := req.URL.Query().Get("query")
+		s.queries = append(s.queries, query)
+
+		if strings.Contains(query, "broken") {
+			w.WriteHeader(http.StatusBadRequest)
+			_, _ = w.Write([]byte(`{"status":"error","errorType":"bad_data","error":"parse error"}`))
+			return
+		}
+		// Ряды отбираются по подстроке запроса и по матчерам меток вида label="value"
+		result := []map[string]interface{}{}
+		for substring, vector := range vectors {
+			if !strings.Contains(query, substring) {
+				continue
+			}
+		samples:
+			for _, sample := range vector {
+				for label, value := range sample["metric"].(map[string]string) {
+					if strings.Contains(query, label+`="`) && !strings.Contains(query, label+`="`+value+`"`) {
+						continue samples
+					}
+				}
+				result = append(result, sample)
+			}
+		}
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{
+			"status": "success",
+			"data":   map[string]interface{}{"resultType": "vector", "result": result},
+		})
+	}))
+	return s
+}
+
+func (s *prometheusStandIn) backend() *PrometheusBackend {
+	return &PrometheusBackend{baseURL: s.URL, rateWindow: 5 * time.Minute, nodeLabel: "node", client: s.Client()}
+}
+
+// promSample собирает элемент вектора ответа Prometheus
+func promSample(value string, labels ...string) map[string]interface{} {
+	metric := map[string]string{}
+	for i := 0; i+1 < len(labels); i += 2 {
+		metric[labels[i]] = labels[i+1]
+	}
+	return map[string]interface{}{"metric": metric, "value": []interface{}{1700000000.0, value}}
+}
+
+var _ = Describe("Prometheus metrics backend", func() {
+	var prometheus *prometheusStandIn
+
+	BeforeEach(func() {
+		prometheus = newPrometheusStandIn(map[string][]map[string]interface{}{
+			`rate(container_cpu_usage_seconds_total{id="/"}`: {
+				promSample("1.5", "node", "node-1"),
+				promSample("0.25", "node", "node-2"),
+			},
+			`container_memory_working_set_bytes{id="/"}`: {
+				promSample("2147483648", "node", "node-1"),
+			},
+			`rate(container_cpu_usage_seconds_total{container!=""`: {
+				promSample("0.1", "namespace", "default", "pod", "web", "container", "app"),
+				promSample("0.05", "namespace", "default", "pod", "web", "container", "sidecar"),
+			},
+			`container_memory_working_set_bytes{container!=""`: {
+				promSample("104857600", "namespace", "default", "pod", "web", "container", "app"),
+			},
+		})
+		DeferCleanup(prometheus.Close)
+	})
+
+	It("should map cAdvisor series to node and pod metrics", func() {
+		backend := prometheus.backend()
+
+		nodes, err := backend.NodeMetrics(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(nodes).To(HaveLen(2))
+		Expect(nodes[0].Name).To(Equal("node-1"))
+		Expect(nodes[0].Usage.Cpu().MilliValue()).To(Equal(int64(1500)))
+		Expect(nodes[0].Usage.Memory().Value()).To(Equal(int64(2 << 30)))
+		Expect(nodes[1].Usage.Memory().IsZero()).To(BeTrue())
+
+		pods, err := backend.PodMetrics(ctx, "default", "web")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(pods).To(HaveLen(1))
+		Expect(pods[0].Namespace).To(Equal("default"))
+		Expect(pods[0].Containers).To(HaveLen(2))
+		Expect(pods[0].Containers[0].Name).To(Equal("app"))
+		Expect(pods[0].Containers[0].Usage.Memory().Value()).To(Equal(int64(100 << 20)))
+		Expect(pods[0].Containers[1].Usage.Cpu().MilliValue()).To(Equal(int64(50)))
+		Expect(pods[0].Containers[1].Usage.Memory().IsZero()).To(BeTrue())
+		Expect(prometheus.queries[len(prometheus.queries)-2]).To(ContainSubstring(`namespace="default",pod="web"}[300s]`))
+	})
+
+	It("should feed cluster metrics from the configured backend", func() {
+		reconciler := &KubedeckReconciler{metrics: prometheus.backend()}
+		node := func(name string) corev1.Node {
+			return corev1.Node{
+				ObjectMeta: metav1.ObjectMeta{Name: name},
+				Status: corev1.NodeStatus{Capacity: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("4"),
+					corev1.ResourceMemory: resource.MustParse("8Gi"),
+				}},
+			}
+		}
+
+		metrics, err := reconciler.computeClusterMetrics(ctx, []corev1.Node{node("node-1"), node("node-2")}, nil, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(metrics.UsedCPU).To(Equal("1750m"))
+		Expect(metrics.CPUUtilization).To(BeNumerically("~", 1.75/8*100, 1e-6))
+		Expect(metrics.MemoryUtilization).To(BeNumerically("~", 2.0/16*100, 1e-6))
+	})
+
+	It("should report PromQL errors and missing pods", func() {
+		backend := prometheus.backend()
+
+		_, err := backend.Query(ctx, "broken(")
+		Expect(err).To(MatchError(ContainSubstring("bad_data: parse error")))
+
+		_, err = backend.PodMetrics(ctx, "default", "missing")
+		Expect(err).To(MatchError(ContainSubstring("no metrics for pod default/missing")))
+
+		_, err = NewMetricsBackend("influx", nil)
+		Expect(err).To(HaveOccurred())
+	})
+})
@@ -0,0 +1,162 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/metrics/pkg/apis/metrics/v1beta1"
+)
+
+const (
+	// defaultSnapshotTTL - время жизни снимка метрик и подов кластера
+	defaultSnapshotTTL = 15 * time.Second
+	// snapshotFetchTimeout ограничивает обновление снимка, которое не отменяется вместе с запросом
+	snapshotFetchTimeout = 30 * time.Second
+)
+
+// metricsSnapshot - использование нод и подов кластера
+type metricsSnapshot struct {
+	nodes []v1beta1.NodeMetrics
+	pods  []v1beta1.PodMetrics
+}
+
+// snapshotCache хранит снимки кластеров в течение ttl. Одновременные обновления одного снимка
+// выполняются одним запросом. Нулевое значение готово к использованию и только объединяет запросы.
+type snapshotCache struct {
+	ttl     time.Duration
+	group   singleflight.Group
+	mu      sync.Mutex
+	entries map[string]snapshotEntry
+}
+
+type snapshotEntry struct {
+	value   interface{}
+	expires time.Time
+}
+
+// get возвращает снимок по ключу, при необходимости обновляя его через fetch.
+// Ошибки не кешируются. Обновление продолжается, даже если ctx вызвавшего его запроса отменен,
+// чтобы не прерывать остальных ожидающих.
+func (c *snapshotCache) get(ctx context.Context, key string, fetch func(context.Context) (interface{}, error)) (interface{}, error) {
+	c.mu.Lock()
+	if entry, ok := c.entries[key]; ok && time.Now().Before(entry.expires) {
+		c.mu.Unlock()
+		return entry.value, nil
+	}
+	c.mu.Unlock()
+
+	result := c.group.DoChan(key, func() (interface{}, error) {
+		fetchCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), snapshotFetchTimeout)
+		defer cancel()
+		value, err := fetch(fetchCtx)
+		if err == nil && c.ttl > 0 {
+			c.mu.Lock()
+			if c.entries == nil {
+				c.entries = make(map[string]snapshotEntry)
+			}
+			c.entries[key] = snapshotEntry{value: value, expires: time.Now().Add(c.ttl)}
+			c.mu.Unlock()
+		}
+		return value, err
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-result:
+		return res.Val, res.Err
+	}
+}
+
+// snapshotKey возвращает ключ снимка кластера из контекста
+func snapshotKey(ctx context.Context, kind string) string {
+	if cluster, ok := ctx.Value(targetClusterKey{}).(*TargetCluster); ok {
+		return cluster.Name + "/" + kind
+	}
+	return localClusterName + "/" + kind
+}
+
+// metricsSnapshot возвращает использование нод и подов кластера из контекста.
+// Снимок общий для всех потребителей и не должен изменяться.
+func (r *KubedeckReconciler) metricsSnapshot(ctx context.Context) (*metricsSnapshot, error) {
+	value, err := r.snapshots.get(ctx, snapshotKey(ctx, "metrics"), func(ctx context.Context) (interface{}, error) {
+		backend, err := r.metricsBackendFor(ctx)
+		if err != nil {
+			return nil, err
+		}
+		nodes, err := backend.NodeMetrics(ctx)
+		if err != nil {
+			return nil, err
+		}
+		pods, err := backend.PodMetrics(ctx, corev1.NamespaceAll, "")
+		if err != nil {
+			return nil, fmt.Errorf("failed to get pod metrics: %w", err)
+		}
+		return &metricsSnapshot{nodes: nodes, pods: pods}, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.(*metricsSnapshot), nil
+}
+
+// podsSnapshot возвращает поды всех namespace кластера из контекста.
+// Список общий для всех потребителей и не должен изменяться.
+func (r *KubedeckReconciler) podsSnapshot(ctx context.Context) ([]corev1.Pod, error) {
+	value, err := r.snapshots.get(ctx, snapshotKey(ctx, "pods"), func(ctx context.Context) (interface{}, error) {
+		podList := &corev1.PodList{}
+		if err := r.clientFor(ctx).List(ctx, podList); err != nil {
+			return nil, fmt.Errorf("failed to list pods: %w", err)
+		}
+		return podList.Items, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value.([]corev1.Pod), nil
+}
+
+// podMetricsOf возвращает метрики подов namespace ("" - всех namespace), непустой name оставляет один под
+func (s *metricsSnapshot) podMetricsOf(namespace, name string) []v1beta1.PodMetrics {
+	if namespace == "" && name == "" {
+		return s.pods
+	}
+	var result []v1beta1.PodMetrics
+	for _, metrics := range s.pods {
+		if (namespace == "" || metrics.Namespace == namespace) && (name == "" || metrics.Name == name) {
+			result = append(result, metrics)
+		}
+	}
+	return result
+}
+
+// podUsage возвращает суммарное использование контейнеров подов по ключу namespace/name
+func (s *metricsSnapshot) podUsage() map[string]corev1.ResourceList {
+	usage := make(map[string]corev1.ResourceList, len(s.pods))
+	for _, metrics := range s.pods {
+		list := corev1.ResourceList{}
+		for _, container := range metrics.Containers {
+			addResourceList(list, container.Usage)
+		}
+		usage[metrics.Namespace+"/"+metrics.Name] = list
+	}
+	return usage
+}
+
+// podsInNamespace возвращает поды namespace; для "" возвращает исходный список
+func podsInNamespace(pods []corev1.Pod, namespace string) []corev1.Pod {
+	if namespace == "" {
+		return pods
+	}
+	var result []corev1.Pod
+	for _, pod := range pods {
+		if pod.Namespace == namespace {
+			result = append(result, pod)
+		}
+	}
+	return result
+}
@@ -0,0 +1,102 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Snapshot cache", func() {
+	It("should share one refresh between concurrent callers and reuse it within the TTL", func() {
+		cache := &snapshotCache{ttl: time.Minute}
+		var calls atomic.Int32
+		release := make(chan struct{})
+		fetch := func(context.Context) (interface{}, error) {
+			calls.Add(1)
+			<-release
+			return "snapshot", nil
+		}
+
+		var wg sync.WaitGroup
+		for range 5 {
+			wg.Add(1)
+			go func() {
+				defer GinkgoRecover()
+				defer wg.Done()
+				value, err := cache.get(ctx, "local/metrics", fetch)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(value).To(Equal("snapshot"))
+			}()
+		}
+		Eventually(calls.Load).Should(Equal(int32(1)))
+		close(release)
+		wg.Wait()
+
+		_, err := cache.get(ctx, "local/metrics", fetch)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(calls.Load()).To(Equal(int32(1)))
+
+		// Снимки разных кластеров не смешиваются
+		_, err = cache.get(ctx, "onprem/metrics", fetch)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(calls.Load()).To(Equal(int32(2)))
+	})
+
+	It("should not cache errors and should let a caller give up without cancelling the refresh", func() {
+		cache := &snapshotCache{ttl: time.Minute}
+		failure := errors.New("metrics API unavailable")
+		_, err := cache.get(ctx, "local/metrics", func(context.Context) (interface{}, error) { return nil, failure })
+		Expect(err).To(MatchError(failure))
+
+		release := make(chan struct{})
+		done := make(chan struct{})
+		callerCtx, cancel := context.WithCancel(ctx)
+		go func() {
+			defer GinkgoRecover()
+			defer close(done)
+			_, err := cache.get(callerCtx, "local/metrics", func(fetchCtx context.Context) (interface{}, error) {
+				<-release
+				return "snapshot", fetchCtx.Err()
+			})
+			Expect(err).To(MatchError(context.Canceled))
+		}()
+		cancel()
+		Eventually(done).Should(BeClosed())
+		close(release)
+
+		Eventually(func() interface{} {
+			value, _ := cache.get(ctx, "local/metrics", func(context.Context) (interface{}, error) { return "fresh", nil })
+			return value
+		}).Should(Equal("snapshot"))
+	})
+
+	It("should serve pod metrics requests from the shared snapshot", func() {
+		prometheus := newPrometheusStandIn(map[string][]map[string]interface{}{
+			`rate(container_cpu_usage_seconds_total{container!=""`: {
+				promSample("0.2", "namespace", "default", "pod", "web", "container", "app"),
+				promSample("0.1", "namespace", "batch", "pod", "job", "container", "app"),
+			},
+		})
+		DeferCleanup(prometheus.Close)
+		reconciler := &KubedeckReconciler{metrics: prometheus.backend(), snapshots: snapshotCache{ttl: time.Minute}}
+
+		first, err := reconciler.metricsSnapshot(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		queries := len(prometheus.queries)
+		second, err := reconciler.metricsSnapshot(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(second).To(BeIdenticalTo(first))
+		Expect(prometheus.queries).To(HaveLen(queries))
+
+		Expect(first.podMetricsOf("default", "")).To(HaveLen(1))
+		Expect(first.podMetricsOf("batch", "job")[0].Name).To(Equal("job"))
+		Expect(first.podMetricsOf("", "")).To(HaveLen(2))
+		usage := first.podUsage()["default/web"]
+		Expect(usage.Cpu().MilliValue()).To(Equal(int64(200)))
+	})
+})
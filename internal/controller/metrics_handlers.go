@@ -1,35 +1,102 @@
 package controller
 
 import (
+	"context"
+	"fmt"
 	"net/http"
+	"sort"
 
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
-	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"k8s.io/metrics/pkg/apis/metrics/v1beta1"
-	metricsv "k8s.io/metrics/pkg/client/clientset/versioned"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 // NodeMetrics представляет метрики узла
 type NodeMetrics struct {
 	Name          string  `json:"name"`
+	Ready         bool    `json:"ready"`
 	CPU           string  `json:"cpu"`
 	CPUPercentage float64 `json:"cpuPercentage"`
 	Memory        string  `json:"memory"`
 	MemPercentage float64 `json:"memPercentage"`
 	CPUCapacity   string  `json:"cpuCapacity"`
 	MemCapacity   string  `json:"memCapacity"`
+	// Pool - группа нод по метке провайдера, Excluded - нода не учитывается в утилизации кластера
+	Pool     string `json:"pool,omitempty"`
+	Excluded bool   `json:"excluded,omitempty"`
+	ResourceAllocation
 }
 
-// PodMetrics представляет метрики пода
+// ResourceAllocation - запросы и лимиты подов относительно allocatable и фактического использования.
+// Планировщик размещает поды по запросам, поэтому ноды могут быть заполнены при низком использовании.
+type ResourceAllocation struct {
+	CPUAllocatable string `json:"cpuAllocatable"`
+	MemAllocatable string `json:"memAllocatable"`
+	CPURequests    string `json:"cpuRequests"`
+	MemRequests    string `json:"memRequests"`
+	CPULimits      string `json:"cpuLimits"`
+	MemLimits      string `json:"memLimits"`
+	// Доля allocatable, занятая запросами, в процентах
+	CPURequestPercentage float64 `json:"cpuRequestPercentage"`
+	MemRequestPercentage float64 `json:"memRequestPercentage"`
+	// Отношение суммы лимитов к allocatable, больше 1 - overcommit. Контейнеры без лимита не учитываются.
+	CPUOvercommit float64 `json:"cpuOvercommit"`
+	MemOvercommit float64 `json:"memOvercommit"`
+	// Запрошено, но не используется: сумма по подам max(0, запрос - использование)
+	CPUUnusedRequests string `json:"cpuUnusedRequests"`
+	MemUnusedRequests string `json:"memUnusedRequests"`
+	Pods              int    `json:"pods"`
+	MaxPods           int    `json:"maxPods"`
+}
+
+// Базы процента использования в полях cpuPercentageOf и memPercentageOf
+const (
+	percentageOfLimit   = "limit"
+	percentageOfRequest = "request"
+)
+
+// PodMetrics представляет метрики пода.
+// CPUPercentage и MemPercentage считаются от суммы лимитов, если лимит задан у всех контейнеров,
+// иначе от суммы запросов, если запрос задан у всех контейнеров, иначе равны 0.
+// База указана в CPUPercentageOf и MemPercentageOf ("limit", "request" или пусто).
 type PodMetrics struct {
-	Name          string  `json:"name"`
-	Namespace     string  `json:"namespace"`
-	CPU           string  `json:"cpu"`
-	Memory        string  `json:"memory"`
-	CPUPercentage float64 `json:"cpuPercentage"`
-	MemPercentage float64 `json:"memPercentage"`
+	Name            string             `json:"name"`
+	Namespace       string             `json:"namespace"`
+	CPU             string             `json:"cpu"`
+	Memory          string             `json:"memory"`
+	CPURequest      string             `json:"cpuRequest"`
+	MemRequest      string             `json:"memRequest"`
+	CPULimit        string             `json:"cpuLimit"`
+	MemLimit        string             `json:"memLimit"`
+	CPUPercentage   float64            `json:"cpuPercentage"`
+	CPUPercentageOf string             `json:"cpuPercentageOf"`
+	MemPercentage   float64            `json:"memPercentage"`
+	MemPercentageOf string             `json:"memPercentageOf"`
+	Containers      []ContainerMetrics `json:"containers"`
+}
+
+// ContainerMetrics представляет метрики контейнера пода.
+// Проценты от запроса и лимита равны 0, если запрос или лимит не задан; такие ресурсы перечислены
+// в MissingRequests и MissingLimits. CPUPercentage и MemPercentage определены так же, как у пода.
+type ContainerMetrics struct {
+	Name                 string   `json:"name"`
+	CPU                  string   `json:"cpu"`
+	Memory               string   `json:"memory"`
+	CPURequest           string   `json:"cpuRequest"`
+	MemRequest           string   `json:"memRequest"`
+	CPULimit             string   `json:"cpuLimit"`
+	MemLimit             string   `json:"memLimit"`
+	CPURequestPercentage float64  `json:"cpuRequestPercentage"`
+	MemRequestPercentage float64  `json:"memRequestPercentage"`
+	CPULimitPercentage   float64  `json:"cpuLimitPercentage"`
+	MemLimitPercentage   float64  `json:"memLimitPercentage"`
+	CPUPercentage        float64  `json:"cpuPercentage"`
+	CPUPercentageOf      string   `json:"cpuPercentageOf"`
+	MemPercentage        float64  `json:"memPercentage"`
+	MemPercentageOf      string   `json:"memPercentageOf"`
+	MissingRequests      []string `json:"missingRequests,omitempty"`
+	MissingLimits        []string `json:"missingLimits,omitempty"`
 }
 
 // ClusterMetrics представляет общие метрики кластера
@@ -41,136 +108,311 @@ type ClusterMetrics struct {
 	CPUUtilization    float64       `json:"cpuUtilization"`
 	MemoryUtilization float64       `json:"memoryUtilization"`
 	Nodes             []NodeMetrics `json:"nodes,omitempty"`
-	NodeCount         int           `json:"nodeCount"`
-	ReadyNodeCount    int           `json:"readyNodeCount"`
+	// Pools - метрики пулов нод, включая ноды, не учтенные в утилизации кластера
+	Pools          []NodePoolMetrics `json:"pools,omitempty"`
+	NodeCount      int               `json:"nodeCount"`
+	ReadyNodeCount int               `json:"readyNodeCount"`
+	// IncludedNodeCount - число нод, учтенных в утилизации кластера
+	IncludedNodeCount int `json:"includedNodeCount"`
+	ResourceAllocation
+}
+
+// NodePoolMetrics представляет метрики пула нод
+type NodePoolMetrics struct {
+	Name              string  `json:"name"`
+	TotalCPU          string  `json:"totalCPU"`
+	TotalMemory       string  `json:"totalMemory"`
+	UsedCPU           string  `json:"usedCPU"`
+	UsedMemory        string  `json:"usedMemory"`
+	CPUUtilization    float64 `json:"cpuUtilization"`
+	MemoryUtilization float64 `json:"memoryUtilization"`
+	NodeCount         int     `json:"nodeCount"`
+	ReadyNodeCount    int     `json:"readyNodeCount"`
+	ResourceAllocation
+}
+
+// allocationTotals накапливает запросы, лимиты и allocatable нод
+type allocationTotals struct {
+	cpuAllocatable, memAllocatable resource.Quantity
+	cpuRequests, memRequests       resource.Quantity
+	cpuLimits, memLimits           resource.Quantity
+	cpuUnused, memUnused           resource.Quantity
+	pods, maxPods                  int
+}
+
+func (a *allocationTotals) add(b allocationTotals) {
+	a.cpuAllocatable.Add(b.cpuAllocatable)
+	a.memAllocatable.Add(b.memAllocatable)
+	a.cpuRequests.Add(b.cpuRequests)
+	a.memRequests.Add(b.memRequests)
+	a.cpuLimits.Add(b.cpuLimits)
+	a.memLimits.Add(b.memLimits)
+	a.cpuUnused.Add(b.cpuUnused)
+	a.memUnused.Add(b.memUnused)
+	a.pods += b.pods
+	a.maxPods += b.maxPods
+}
+
+func (a *allocationTotals) allocation() ResourceAllocation {
+	return ResourceAllocation{
+		CPUAllocatable:       a.cpuAllocatable.String(),
+		MemAllocatable:       a.memAllocatable.String(),
+		CPURequests:          a.cpuRequests.String(),
+		MemRequests:          a.memRequests.String(),
+		CPULimits:            a.cpuLimits.String(),
+		MemLimits:            a.memLimits.String(),
+		CPURequestPercentage: calculateUtilization(a.cpuRequests, a.cpuAllocatable),
+		MemRequestPercentage: calculateUtilization(a.memRequests, a.memAllocatable),
+		CPUOvercommit:        calculateUtilization(a.cpuLimits, a.cpuAllocatable) / 100,
+		MemOvercommit:        calculateUtilization(a.memLimits, a.memAllocatable) / 100,
+		CPUUnusedRequests:    a.cpuUnused.String(),
+		MemUnusedRequests:    a.memUnused.String(),
+		Pods:                 a.pods,
+		MaxPods:              a.maxPods,
+	}
+}
+
+// podResources возвращает эффективные запросы и лимиты пода так же, как их считает планировщик:
+// сумма по контейнерам, но не меньше максимума по init-контейнерам, плюс overhead
+func podResources(pod corev1.Pod) (requests, limits corev1.ResourceList) {
+	requests, limits = corev1.ResourceList{}, corev1.ResourceList{}
+	for _, container := range pod.Spec.Containers {
+		addResourceList(requests, container.Resources.Requests)
+		addResourceList(limits, container.Resources.Limits)
+	}
+	for _, container := range pod.Spec.InitContainers {
+		maxResourceList(requests, container.Resources.Requests)
+		maxResourceList(limits, container.Resources.Limits)
+	}
+	addResourceList(requests, pod.Spec.Overhead)
+	if len(limits) > 0 {
+		addResourceList(limits, pod.Spec.Overhead)
+	}
+	return requests, limits
+}
+
+func addResourceList(list, add corev1.ResourceList) {
+	for name, quantity := range add {
+		value := list[name]
+		value.Add(quantity)
+		list[name] = value
+	}
+}
+
+func maxResourceList(list, other corev1.ResourceList) {
+	for name, quantity := range other {
+		if value, ok := list[name]; !ok || quantity.Cmp(value) > 0 {
+			list[name] = quantity.DeepCopy()
+		}
+	}
 }
 
-// getMetricsClient создает клиент для доступа к Metrics API
-func (r *KubedeckReconciler) getMetricsClient() (*metricsv.Clientset, error) {
-	return metricsv.NewForConfig(r.Config)
+// nodeAllocations считает запросы и лимиты подов по нодам. Завершившиеся поды не учитываются.
+// usage - использование подов по ключу namespace/name, по нему считается неиспользуемый запрос.
+func nodeAllocations(nodes []corev1.Node, pods []corev1.Pod, usage map[string]corev1.ResourceList) map[string]*allocationTotals {
+	allocations := make(map[string]*allocationTotals, len(nodes))
+	for _, node := range nodes {
+		allocations[node.Name] = &allocationTotals{
+			cpuAllocatable: node.Status.Allocatable.Cpu().DeepCopy(),
+			memAllocatable: node.Status.Allocatable.Memory().DeepCopy(),
+			maxPods:        int(node.Status.Allocatable.Pods().Value()),
+		}
+	}
+
+	for _, pod := range pods {
+		allocation, ok := allocations[pod.Spec.NodeName]
+		if !ok || isPodFinished(pod) {
+			continue
+		}
+		requests, limits := podResources(pod)
+		allocation.pods++
+		allocation.cpuRequests.Add(*requests.Cpu())
+		allocation.memRequests.Add(*requests.Memory())
+		allocation.cpuLimits.Add(*limits.Cpu())
+		allocation.memLimits.Add(*limits.Memory())
+
+		if used, ok := usage[pod.Namespace+"/"+pod.Name]; ok {
+			allocation.cpuUnused.Add(unusedQuantity(*requests.Cpu(), *used.Cpu()))
+			allocation.memUnused.Add(unusedQuantity(*requests.Memory(), *used.Memory()))
+		}
+	}
+	return allocations
+}
+
+// unusedQuantity возвращает max(0, requested - used)
+func unusedQuantity(requested, used resource.Quantity) resource.Quantity {
+	requested.Sub(used)
+	if requested.Sign() < 0 {
+		return resource.Quantity{}
+	}
+	return requested
 }
 
 // handleClusterMetricsRequest обрабатывает запрос на получение метрик кластера
 func (r *KubedeckReconciler) handleClusterMetricsRequest(w http.ResponseWriter, req *http.Request) {
-	ctx := req.Context()
-	log := webServerLog.WithName("handleClusterMetricsRequest")
-
 	// Получаем параметры запроса
-	includeNodesStr := req.URL.Query().Get("includeNodes")
-	includeNodes := includeNodesStr == "true"
-
-	// Получаем метрики узлов
-	metricsClient, err := r.getMetricsClient()
+	includeNodes := req.URL.Query().Get("includeNodes") == "true"
+	filter, err := parseNodeFilter(req.URL.Query())
 	if err != nil {
-		log.Error(err, "Failed to create metrics client")
-		http.Error(w, "Failed to create metrics client: "+err.Error(), http.StatusInternalServerError)
+		http.Error(w, err.Error(), http.StatusBadRequest)
 		return
 	}
 
+	r.inClusters(w, req, "cluster metrics", func(ctx context.Context) (interface{}, error) {
+		return r.clusterMetrics(ctx, includeNodes, filter)
+	})
+}
+
+// clusterMetrics считает метрики кластера из контекста по нодам, отобранным filter
+func (r *KubedeckReconciler) clusterMetrics(ctx context.Context, includeNodes bool, filter *nodeFilter) (*ClusterMetrics, error) {
 	// Получаем список узлов
 	nodeList := &corev1.NodeList{}
-	err = r.Client.List(ctx, nodeList, &client.ListOptions{})
+	if err := r.clientFor(ctx).List(ctx, nodeList, &client.ListOptions{}); err != nil {
+		return nil, fmt.Errorf("failed to list nodes: %w", err)
+	}
+
+	// Поды нужны для запросов и лимитов узлов
+	pods, err := r.podsSnapshot(ctx)
 	if err != nil {
-		log.Error(err, "Failed to list nodes")
-		http.Error(w, "Failed to list nodes: "+err.Error(), http.StatusInternalServerError)
-		return
+		return nil, err
 	}
 
-	// Получаем метрики узлов
-	nodeMetricsList, err := metricsClient.MetricsV1beta1().NodeMetricses().List(ctx, metav1.ListOptions{})
+	result, err := r.computeClusterMetrics(ctx, nodeList.Items, pods, filter)
 	if err != nil {
-		log.Error(err, "Failed to list node metrics")
-		http.Error(w, "Failed to list node metrics: "+err.Error(), http.StatusInternalServerError)
-		return
+		return nil, err
+	}
+
+	// Включаем информацию о узлах только если запрошено
+	if !includeNodes {
+		result.Nodes = nil
+	}
+	return result, nil
+}
+
+// utilizationTotals накапливает емкость, использование и размещение группы нод
+type utilizationTotals struct {
+	cpuCapacity, memCapacity resource.Quantity
+	cpuUsed, memUsed         resource.Quantity
+	allocation               allocationTotals
+	nodes, readyNodes        int
+}
+
+func (t *utilizationTotals) addNode(node NodeMetrics, cpuCapacity, memCapacity, cpuUsed, memUsed resource.Quantity, allocation allocationTotals) {
+	t.cpuCapacity.Add(cpuCapacity)
+	t.memCapacity.Add(memCapacity)
+	t.cpuUsed.Add(cpuUsed)
+	t.memUsed.Add(memUsed)
+	t.allocation.add(allocation)
+	t.nodes++
+	if node.Ready {
+		t.readyNodes++
+	}
+}
+
+// computeClusterMetrics считает использование ресурсов по списку узлов.
+// Узлы, не отобранные filter, учитываются только в NodeCount, ReadyNodeCount и в своем пуле
+// и помечаются excluded. nil filter учитывает все узлы и не разбивает их на пулы.
+// Запросы и лимиты считаются по pods, размещенным на узлах.
+func (r *KubedeckReconciler) computeClusterMetrics(ctx context.Context, nodes []corev1.Node, pods []corev1.Pod, filter *nodeFilter) (*ClusterMetrics, error) {
+	// Получаем метрики узлов и подов
+	snapshot, err := r.metricsSnapshot(ctx)
+	if err != nil {
+		return nil, err
 	}
 
 	// Создаем карту метрик узлов для быстрого доступа
-	nodeMetricsMap := make(map[string]v1beta1.NodeMetrics, len(nodeMetricsList.Items))
-	for _, metrics := range nodeMetricsList.Items {
+	nodeMetricsMap := make(map[string]v1beta1.NodeMetrics, len(snapshot.nodes))
+	for _, metrics := range snapshot.nodes {
 		nodeMetricsMap[metrics.Name] = metrics
 	}
 
-	var totalCPUCapacity, totalMemoryCapacity resource.Quantity
-	var totalCPUUsed, totalMemoryUsed resource.Quantity
+	// Использование подов нужно для неиспользуемых запросов
+	allocations := nodeAllocations(nodes, pods, snapshot.podUsage())
 
-	nodeMetrics := make([]NodeMetrics, 0, len(nodeList.Items))
+	var total utilizationTotals
+	pools := map[string]*utilizationTotals{}
+	nodeMetrics := make([]NodeMetrics, 0, len(nodes))
 	readyNodeCount := 0
 
-	for _, node := range nodeList.Items {
-		// Проверяем готовность узла
-		if isNodeReady(node) {
-			readyNodeCount++
-		}
-
-		// Пропускаем узлы с NoSchedule
-		if hasNoScheduleTaint(node) {
-			continue
-		}
-
-		// Получаем емкость узла
+	for _, node := range nodes {
+		// Получаем емкость и использование узла, узел без метрик считается неиспользуемым
 		cpuCapacity := node.Status.Capacity.Cpu().DeepCopy()
 		memoryCapacity := node.Status.Capacity.Memory().DeepCopy()
-
-		totalCPUCapacity.Add(cpuCapacity)
-		totalMemoryCapacity.Add(memoryCapacity)
-
-		// Проверяем наличие метрик
-		metrics, exists := nodeMetricsMap[node.Name]
-		if !exists {
-			nodeMetrics = append(nodeMetrics, NodeMetrics{
-				Name:        node.Name,
-				CPU:         "0",
-				Memory:      "0",
-				CPUCapacity: cpuCapacity.String(),
-				MemCapacity: memoryCapacity.String(),
-			})
-			continue
+		var cpuUsed, memoryUsed resource.Quantity
+		if metrics, exists := nodeMetricsMap[node.Name]; exists {
+			cpuUsed = metrics.Usage.Cpu().DeepCopy()
+			memoryUsed = metrics.Usage.Memory().DeepCopy()
 		}
 
-		// Получаем использование
-		cpuUsed := metrics.Usage.Cpu().DeepCopy()
-		memoryUsed := metrics.Usage.Memory().DeepCopy()
-
-		totalCPUUsed.Add(cpuUsed)
-		totalMemoryUsed.Add(memoryUsed)
-
-		// Вычисляем процент использования
-		cpuPercentage := calculateUtilization(cpuUsed, cpuCapacity)
-		memPercentage := calculateUtilization(memoryUsed, memoryCapacity)
-
-		nodeMetrics = append(nodeMetrics, NodeMetrics{
+		allocation := allocations[node.Name]
+		result := NodeMetrics{
 			Name:          node.Name,
+			Ready:         isNodeReady(node),
 			CPU:           cpuUsed.String(),
 			Memory:        memoryUsed.String(),
-			CPUPercentage: cpuPercentage,
-			MemPercentage: memPercentage,
+			CPUPercentage: calculateUtilization(cpuUsed, cpuCapacity),
+			MemPercentage: calculateUtilization(memoryUsed, memoryCapacity),
 			CPUCapacity:   cpuCapacity.String(),
 			MemCapacity:   memoryCapacity.String(),
-		})
+
+			ResourceAllocation: allocation.allocation(),
+		}
+		if result.Ready {
+			readyNodeCount++
+		}
+
+		if filter != nil {
+			result.Pool = filter.pool(node)
+			result.Excluded = !filter.includes(node)
+			if pools[result.Pool] == nil {
+				pools[result.Pool] = &utilizationTotals{}
+			}
+			pools[result.Pool].addNode(result, cpuCapacity, memoryCapacity, cpuUsed, memoryUsed, *allocation)
+		}
+		nodeMetrics = append(nodeMetrics, result)
+
+		if !result.Excluded {
+			total.addNode(result, cpuCapacity, memoryCapacity, cpuUsed, memoryUsed, *allocation)
+		}
 	}
 
-	// Вычисляем процент использования для кластера
-	cpuUtilization := calculateUtilization(totalCPUUsed, totalCPUCapacity)
-	memoryUtilization := calculateUtilization(totalMemoryUsed, totalMemoryCapacity)
-
-	// Создаем объект метрик кластера
-	result := ClusterMetrics{
-		TotalCPU:          totalCPUCapacity.String(),
-		TotalMemory:       totalMemoryCapacity.String(),
-		UsedCPU:           totalCPUUsed.String(),
-		UsedMemory:        totalMemoryUsed.String(),
-		CPUUtilization:    cpuUtilization,
-		MemoryUtilization: memoryUtilization,
-		NodeCount:         len(nodeList.Items),
+	return &ClusterMetrics{
+		TotalCPU:          total.cpuCapacity.String(),
+		TotalMemory:       total.memCapacity.String(),
+		UsedCPU:           total.cpuUsed.String(),
+		UsedMemory:        total.memUsed.String(),
+		CPUUtilization:    calculateUtilization(total.cpuUsed, total.cpuCapacity),
+		MemoryUtilization: calculateUtilization(total.memUsed, total.memCapacity),
+		Nodes:             nodeMetrics,
+		Pools:             nodePoolMetrics(pools),
+		NodeCount:         len(nodes),
 		ReadyNodeCount:    readyNodeCount,
-	}
+		IncludedNodeCount: total.nodes,
 
-	// Включаем информацию о узлах только если запрошено
-	if includeNodes {
-		result.Nodes = nodeMetrics
-	}
+		ResourceAllocation: total.allocation.allocation(),
+	}, nil
+}
 
-	writeJsonResponse(w, result, "cluster metrics", nil)
+// nodePoolMetrics возвращает метрики пулов, отсортированные по имени
+func nodePoolMetrics(pools map[string]*utilizationTotals) []NodePoolMetrics {
+	result := make([]NodePoolMetrics, 0, len(pools))
+	for name, t := range pools {
+		result = append(result, NodePoolMetrics{
+			Name:              name,
+			TotalCPU:          t.cpuCapacity.String(),
+			TotalMemory:       t.memCapacity.String(),
+			UsedCPU:           t.cpuUsed.String(),
+			UsedMemory:        t.memUsed.String(),
+			CPUUtilization:    calculateUtilization(t.cpuUsed, t.cpuCapacity),
+			MemoryUtilization: calculateUtilization(t.memUsed, t.memCapacity),
+			NodeCount:         t.nodes,
+			ReadyNodeCount:    t.readyNodes,
+
+			ResourceAllocation: t.allocation.allocation(),
+		})
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
 }
 
 // handlePodMetricsRequest обрабатывает запрос на получение метрик подов
@@ -182,135 +424,158 @@ func (r *KubedeckReconciler) handlePodMetricsRequest(w http.ResponseWriter, req
 	namespace := req.URL.Query().Get("namespace")
 	podName := req.URL.Query().Get("name")
 
-	// Получаем клиент для метрик
-	metricsClient, err := r.getMetricsClient()
+	// Получаем снимок метрик подов
+	snapshot, err := r.metricsSnapshot(ctx)
 	if err != nil {
-		log.Error(err, "Failed to create metrics client")
-		http.Error(w, "Failed to create metrics client: "+err.Error(), http.StatusInternalServerError)
+		log.Error(err, "Failed to get pod metrics", "namespace", namespace)
+		http.Error(w, "Failed to get pod metrics: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	// Получаем список подов для определения лимитов
-	var podList corev1.PodList
-	if namespace != "" {
-		err = r.Client.List(ctx, &podList, &client.ListOptions{Namespace: namespace})
-	} else {
-		err = r.Client.List(ctx, &podList, &client.ListOptions{})
-	}
+	// Получаем список подов для определения запросов и лимитов
+	podList, err := r.podsSnapshot(ctx)
 	if err != nil {
 		log.Error(err, "Failed to list pods")
 		http.Error(w, "Failed to list pods: "+err.Error(), http.StatusInternalServerError)
 		return
 	}
 
-	// Создаем карту подов для быстрого доступа к лимитам
-	podLimitsMap := make(map[string]map[string]corev1.ResourceList)
-	for _, pod := range podList.Items {
-		key := pod.Namespace + "/" + pod.Name
-		podLimitsMap[key] = make(map[string]corev1.ResourceList)
-
-		for _, container := range pod.Spec.Containers {
-			podLimitsMap[key][container.Name] = container.Resources.Limits
-		}
+	// Создаем карту подов для быстрого доступа к спецификации контейнеров
+	pods := make(map[string]*corev1.Pod, len(podList))
+	for i := range podList {
+		pods[podList[i].Namespace+"/"+podList[i].Name] = &podList[i]
 	}
 
 	// Если запрошен конкретный под
 	if podName != "" && namespace != "" {
-		podMetrics, err := metricsClient.MetricsV1beta1().PodMetricses(namespace).Get(ctx, podName, metav1.GetOptions{})
-		if err != nil {
+		podMetricsList := snapshot.podMetricsOf(namespace, podName)
+		if len(podMetricsList) == 0 {
+			err := fmt.Errorf("no metrics for pod %s/%s", namespace, podName)
 			log.Error(err, "Failed to get pod metrics", "pod", podName, "namespace", namespace)
 			http.Error(w, "Failed to get pod metrics: "+err.Error(), http.StatusInternalServerError)
 			return
 		}
 
-		// Суммируем использование CPU и памяти по всем контейнерам
-		var totalCPU, totalMemory resource.Quantity
-		var totalCPULimit, totalMemoryLimit resource.Quantity
-		key := namespace + "/" + podName
-
-		for _, container := range podMetrics.Containers {
-			totalCPU.Add(container.Usage.Cpu().DeepCopy())
-			totalMemory.Add(container.Usage.Memory().DeepCopy())
-
-			// Добавляем лимиты, если они определены
-			if limits, exists := podLimitsMap[key][container.Name]; exists {
-				if cpuLimit, ok := limits[corev1.ResourceCPU]; ok {
-					totalCPULimit.Add(cpuLimit)
-				}
-				if memLimit, ok := limits[corev1.ResourceMemory]; ok {
-					totalMemoryLimit.Add(memLimit)
-				}
-			}
-		}
-
-		// Вычисляем проценты использования
-		cpuPercentage := calculateUtilization(totalCPU, totalCPULimit)
-		memPercentage := calculateUtilization(totalMemory, totalMemoryLimit)
-
-		result := PodMetrics{
-			Name:          podMetrics.Name,
-			Namespace:     podMetrics.Namespace,
-			CPU:           totalCPU.String(),
-			Memory:        totalMemory.String(),
-			CPUPercentage: cpuPercentage,
-			MemPercentage: memPercentage,
-		}
-
-		writeJsonResponse(w, result, "pod metrics", nil)
+		writeJsonResponse(w, buildPodMetrics(podMetricsList[0], pods[namespace+"/"+podName]), "pod metrics", nil)
 		return
 	}
 
 	// Если запрошены все поды в определенном namespace или во всех namespace
-	var podMetricsList *v1beta1.PodMetricsList
-	if namespace != "" {
-		podMetricsList, err = metricsClient.MetricsV1beta1().PodMetricses(namespace).List(ctx, metav1.ListOptions{})
-	} else {
-		podMetricsList, err = metricsClient.MetricsV1beta1().PodMetricses(corev1.NamespaceAll).List(ctx, metav1.ListOptions{})
+	podMetricsList := snapshot.podMetricsOf(namespace, "")
+
+	// Преобразуем метрики в удобный формат
+	results := make([]PodMetrics, 0, len(podMetricsList))
+	for _, podMetrics := range podMetricsList {
+		results = append(results, buildPodMetrics(podMetrics, pods[podMetrics.Namespace+"/"+podMetrics.Name]))
 	}
 
-	if err != nil {
-		log.Error(err, "Failed to list pod metrics", "namespace", namespace)
-		http.Error(w, "Failed to list pod metrics: "+err.Error(), http.StatusInternalServerError)
-		return
+	writeJsonResponse(w, results, "pod metrics", nil)
+}
+
+// containerUtilization - использование одного ресурса контейнера с его запросом и лимитом
+type containerUtilization struct {
+	used, request, limit resource.Quantity
+	hasRequest, hasLimit bool
+}
+
+// percentageBasis выбирает базу процента использования группы контейнеров: лимит, если он задан у всех контейнеров,
+// иначе запрос, если он задан у всех. Возвращает процент и базу ("limit", "request" или "" - процент не определен).
+func percentageBasis(containers []containerUtilization) (float64, string) {
+	var used, requests, limits resource.Quantity
+	allRequests, allLimits := len(containers) > 0, len(containers) > 0
+	for _, c := range containers {
+		used.Add(c.used)
+		requests.Add(c.request)
+		limits.Add(c.limit)
+		allRequests = allRequests && c.hasRequest
+		allLimits = allLimits && c.hasLimit
 	}
+	switch {
+	case allLimits:
+		return calculateUtilization(used, limits), percentageOfLimit
+	case allRequests:
+		return calculateUtilization(used, requests), percentageOfRequest
+	default:
+		return 0, ""
+	}
+}
 
-	// Преобразуем метрики в удобный формат
-	results := make([]PodMetrics, 0, len(podMetricsList.Items))
-	for _, podMetrics := range podMetricsList.Items {
-		var totalCPU, totalMemory resource.Quantity
-		var totalCPULimit, totalMemoryLimit resource.Quantity
-		key := podMetrics.Namespace + "/" + podMetrics.Name
-
-		for _, container := range podMetrics.Containers {
-			totalCPU.Add(container.Usage.Cpu().DeepCopy())
-			totalMemory.Add(container.Usage.Memory().DeepCopy())
-
-			// Добавляем лимиты, если они определены
-			if limits, exists := podLimitsMap[key][container.Name]; exists {
-				if cpuLimit, ok := limits[corev1.ResourceCPU]; ok {
-					totalCPULimit.Add(cpuLimit)
-				}
-				if memLimit, ok := limits[corev1.ResourceMemory]; ok {
-					totalMemoryLimit.Add(memLimit)
-				}
-			}
+// buildPodMetrics собирает метрики пода и его контейнеров. pod - спецификация пода, nil если под не найден.
+func buildPodMetrics(podMetrics v1beta1.PodMetrics, pod *corev1.Pod) PodMetrics {
+	specs := map[string]corev1.ResourceRequirements{}
+	if pod != nil {
+		for _, container := range pod.Spec.Containers {
+			specs[container.Name] = container.Resources
 		}
+	}
 
-		// Вычисляем проценты использования
-		cpuPercentage := calculateUtilization(totalCPU, totalCPULimit)
-		memPercentage := calculateUtilization(totalMemory, totalMemoryLimit)
-
-		results = append(results, PodMetrics{
-			Name:          podMetrics.Name,
-			Namespace:     podMetrics.Namespace,
-			CPU:           totalCPU.String(),
-			Memory:        totalMemory.String(),
-			CPUPercentage: cpuPercentage,
-			MemPercentage: memPercentage,
-		})
+	var totalCPU, totalMemory resource.Quantity
+	var totalCPURequest, totalMemoryRequest, totalCPULimit, totalMemoryLimit resource.Quantity
+	var cpuUtilization, memoryUtilization []containerUtilization
+	containers := make([]ContainerMetrics, 0, len(podMetrics.Containers))
+
+	for _, container := range podMetrics.Containers {
+		spec := specs[container.Name]
+		cpu := containerUtilization{used: container.Usage.Cpu().DeepCopy()}
+		cpu.request, cpu.hasRequest = spec.Requests[corev1.ResourceCPU]
+		cpu.limit, cpu.hasLimit = spec.Limits[corev1.ResourceCPU]
+		memory := containerUtilization{used: container.Usage.Memory().DeepCopy()}
+		memory.request, memory.hasRequest = spec.Requests[corev1.ResourceMemory]
+		memory.limit, memory.hasLimit = spec.Limits[corev1.ResourceMemory]
+
+		totalCPU.Add(cpu.used)
+		totalMemory.Add(memory.used)
+		totalCPURequest.Add(cpu.request)
+		totalMemoryRequest.Add(memory.request)
+		totalCPULimit.Add(cpu.limit)
+		totalMemoryLimit.Add(memory.limit)
+		cpuUtilization = append(cpuUtilization, cpu)
+		memoryUtilization = append(memoryUtilization, memory)
+
+		result := ContainerMetrics{
+			Name:                 container.Name,
+			CPU:                  cpu.used.String(),
+			Memory:               memory.used.String(),
+			CPURequest:           cpu.request.String(),
+			MemRequest:           memory.request.String(),
+			CPULimit:             cpu.limit.String(),
+			MemLimit:             memory.limit.String(),
+			CPURequestPercentage: calculateUtilization(cpu.used, cpu.request),
+			MemRequestPercentage: calculateUtilization(memory.used, memory.request),
+			CPULimitPercentage:   calculateUtilization(cpu.used, cpu.limit),
+			MemLimitPercentage:   calculateUtilization(memory.used, memory.limit),
+		}
+		result.CPUPercentage, result.CPUPercentageOf = percentageBasis([]containerUtilization{cpu})
+		result.MemPercentage, result.MemPercentageOf = percentageBasis([]containerUtilization{memory})
+		if !cpu.hasRequest {
+			result.MissingRequests = append(result.MissingRequests, string(corev1.ResourceCPU))
+		}
+		if !memory.hasRequest {
+			result.MissingRequests = append(result.MissingRequests, string(corev1.ResourceMemory))
+		}
+		if !cpu.hasLimit {
+			result.MissingLimits = append(result.MissingLimits, string(corev1.ResourceCPU))
+		}
+		if !memory.hasLimit {
+			result.MissingLimits = append(result.MissingLimits, string(corev1.ResourceMemory))
+		}
+		containers = append(containers, result)
 	}
 
-	writeJsonResponse(w, results, "pod metrics", nil)
+	result := PodMetrics{
+		Name:       podMetrics.Name,
+		Namespace:  podMetrics.Namespace,
+		CPU:        totalCPU.String(),
+		Memory:     totalMemory.String(),
+		CPURequest: totalCPURequest.String(),
+		MemRequest: totalMemoryRequest.String(),
+		CPULimit:   totalCPULimit.String(),
+		MemLimit:   totalMemoryLimit.String(),
+		Containers: containers,
+	}
+	result.CPUPercentage, result.CPUPercentageOf = percentageBasis(cpuUtilization)
+	result.MemPercentage, result.MemPercentageOf = percentageBasis(memoryUtilization)
+	return result
 }
 
 // isNodeReady проверяет, готов ли узел
@@ -323,16 +588,6 @@ func isNodeReady(node corev1.Node) bool {
 	return false
 }
 
-// hasNoScheduleTaint проверяет, имеет ли узел taint NoSchedule
-func hasNoScheduleTaint(node corev1.Node) bool {
-	for _, taint := range node.Spec.Taints {
-		if taint.Effect == corev1.TaintEffectNoSchedule {
-			return true
-		}
-	}
-	return false
-}
-
 // calculateUtilization вычисляет процент использования ресурса
 func calculateUtilization(used, capacity resource.Quantity) float64 {
 	if capacity.IsZero() {
@@ -0,0 +1,239 @@
+package controller
+
+import (
+	"net/url"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/metrics/pkg/apis/metrics/v1beta1"
+)
+
+var _ = Describe("Cluster metrics allocation", func() {
+	resources := func(cpu, memory string) corev1.ResourceList {
+		list := corev1.ResourceList{}
+		if cpu != "" {
+			list[corev1.ResourceCPU] = resource.MustParse(cpu)
+		}
+		if memory != "" {
+			list[corev1.ResourceMemory] = resource.MustParse(memory)
+		}
+		return list
+	}
+	pod := func(name, node string, requests, limits corev1.ResourceList) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+			Spec: corev1.PodSpec{NodeName: node, Containers: []corev1.Container{{
+				Name: "app", Resources: corev1.ResourceRequirements{Requests: requests, Limits: limits},
+			}}},
+			Status: corev1.PodStatus{Phase: corev1.PodRunning},
+		}
+	}
+
+	It("should use the larger of init and app container requests plus overhead", func() {
+		p := pod("web", "node-1", resources("100m", "64Mi"), resources("200m", ""))
+		p.Spec.InitContainers = []corev1.Container{{Name: "migrate", Resources: corev1.ResourceRequirements{
+			Requests: resources("500m", "32Mi"),
+		}}}
+		p.Spec.Overhead = resources("10m", "")
+
+		requests, limits := podResources(p)
+		Expect(requests.Cpu().MilliValue()).To(Equal(int64(510)))
+		Expect(requests.Memory().String()).To(Equal("64Mi"))
+		Expect(limits.Cpu().MilliValue()).To(Equal(int64(210)))
+		Expect(limits.Memory().IsZero()).To(BeTrue())
+	})
+
+	It("should report requests, limits, overcommit and unused requests per node and cluster", func() {
+		prometheus := newPrometheusStandIn(map[string][]map[string]interface{}{
+			`rate(container_cpu_usage_seconds_total{id="/"}`: {promSample("1", "node", "node-1")},
+			`rate(container_cpu_usage_seconds_total{container!=""`: {
+				promSample("0.2", "namespace", "default", "pod", "web", "container", "app"),
+				promSample("1.5", "namespace", "default", "pod", "busy", "container", "app"),
+			},
+		})
+		DeferCleanup(prometheus.Close)
+		reconciler := &KubedeckReconciler{metrics: prometheus.backend()}
+
+		node := corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: "node-1"},
+			Status: corev1.NodeStatus{
+				Capacity: resources("4", "8Gi"),
+				Allocatable: corev1.ResourceList{
+					corev1.ResourceCPU:    resource.MustParse("2"),
+					corev1.ResourceMemory: resource.MustParse("4Gi"),
+					corev1.ResourcePods:   resource.MustParse("110"),
+				},
+			},
+		}
+		finished := pod("job", "node-1", resources("1", ""), nil)
+		finished.Status.Phase = corev1.PodSucceeded
+		pods := []corev1.Pod{
+			pod("web", "node-1", resources("1", "1Gi"), resources("2", "2Gi")),
+			pod("busy", "node-1", resources("500m", ""), resources("1", "")),
+			finished,
+			pod("pending", "", resources("8", ""), nil),
+		}
+
+		metrics, err := reconciler.computeClusterMetrics(ctx, []corev1.Node{node}, pods, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(metrics.Nodes).To(HaveLen(1))
+
+		allocation := metrics.Nodes[0].ResourceAllocation
+		Expect(allocation.Pods).To(Equal(2))
+		Expect(allocation.MaxPods).To(Equal(110))
+		Expect(allocation.CPURequests).To(Equal("1500m"))
+		Expect(allocation.CPURequestPercentage).To(BeNumerically("~", 75, 1e-9))
+		Expect(allocation.MemRequestPercentage).To(BeNumerically("~", 25, 1e-9))
+		Expect(allocation.CPUOvercommit).To(BeNumerically("~", 1.5, 1e-9))
+		// web запросил 1 ядро и использует 0.2, busy использует больше запроса
+		Expect(allocation.CPUUnusedRequests).To(Equal("800m"))
+		Expect(allocation.MemUnusedRequests).To(Equal("1Gi"))
+
+		Expect(metrics.ResourceAllocation.CPUAllocatable).To(Equal("2"))
+		Expect(metrics.ResourceAllocation.Pods).To(Equal(2))
+		Expect(metrics.CPUUtilization).To(BeNumerically("~", 25, 1e-9))
+	})
+})
+
+var _ = Describe("Pod metrics", func() {
+	requirements := func(requests, limits map[corev1.ResourceName]string) corev1.ResourceRequirements {
+		result := corev1.ResourceRequirements{Requests: corev1.ResourceList{}, Limits: corev1.ResourceList{}}
+		for name, value := range requests {
+			result.Requests[name] = resource.MustParse(value)
+		}
+		for name, value := range limits {
+			result.Limits[name] = resource.MustParse(value)
+		}
+		return result
+	}
+	usage := func(name, cpu, memory string) v1beta1.ContainerMetrics {
+		return v1beta1.ContainerMetrics{Name: name, Usage: corev1.ResourceList{
+			corev1.ResourceCPU: resource.MustParse(cpu), corev1.ResourceMemory: resource.MustParse(memory),
+		}}
+	}
+
+	It("should report containers against requests and limits with the percentage basis", func() {
+		pod := &corev1.Pod{Spec: corev1.PodSpec{Containers: []corev1.Container{
+			{Name: "app", Resources: requirements(
+				map[corev1.ResourceName]string{corev1.ResourceCPU: "500m", corev1.ResourceMemory: "256Mi"},
+				map[corev1.ResourceName]string{corev1.ResourceCPU: "1", corev1.ResourceMemory: "512Mi"})},
+			{Name: "sidecar", Resources: requirements(
+				map[corev1.ResourceName]string{corev1.ResourceCPU: "100m"}, nil)},
+		}}}
+		metrics := v1beta1.PodMetrics{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web"},
+			Containers: []v1beta1.ContainerMetrics{usage("app", "250m", "128Mi"), usage("sidecar", "50m", "32Mi")},
+		}
+
+		result := buildPodMetrics(metrics, pod)
+		Expect(result.CPU).To(Equal("300m"))
+		Expect(result.CPURequest).To(Equal("600m"))
+		// У sidecar нет лимитов, поэтому CPU пода считается от запросов, а память не определена
+		Expect(result.CPUPercentageOf).To(Equal(percentageOfRequest))
+		Expect(result.CPUPercentage).To(BeNumerically("~", 50, 1e-9))
+		Expect(result.MemPercentageOf).To(BeEmpty())
+		Expect(result.MemPercentage).To(BeZero())
+
+		Expect(result.Containers).To(HaveLen(2))
+		app := result.Containers[0]
+		Expect(app.CPURequestPercentage).To(BeNumerically("~", 50, 1e-9))
+		Expect(app.CPULimitPercentage).To(BeNumerically("~", 25, 1e-9))
+		Expect(app.MemPercentageOf).To(Equal(percentageOfLimit))
+		Expect(app.MemPercentage).To(BeNumerically("~", 25, 1e-9))
+		Expect(app.MissingRequests).To(BeEmpty())
+		Expect(app.MissingLimits).To(BeEmpty())
+
+		sidecar := result.Containers[1]
+		Expect(sidecar.CPUPercentageOf).To(Equal(percentageOfRequest))
+		Expect(sidecar.MemLimitPercentage).To(BeZero())
+		Expect(sidecar.MissingRequests).To(Equal([]string{"memory"}))
+		Expect(sidecar.MissingLimits).To(Equal([]string{"cpu", "memory"}))
+	})
+
+	It("should flag every resource as missing when the pod spec is unknown", func() {
+		result := buildPodMetrics(v1beta1.PodMetrics{Containers: []v1beta1.ContainerMetrics{usage("app", "1", "1Gi")}}, nil)
+		Expect(result.CPUPercentageOf).To(BeEmpty())
+		Expect(result.Containers[0].MissingRequests).To(Equal([]string{"cpu", "memory"}))
+	})
+})
+
+var _ = Describe("Node selection for cluster utilization", func() {
+	node := func(name string, labels map[string]string, taints ...corev1.Taint) corev1.Node {
+		return corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Labels: labels},
+			Spec:       corev1.NodeSpec{Taints: taints},
+			Status: corev1.NodeStatus{
+				Capacity:   corev1.ResourceList{corev1.ResourceCPU: resource.MustParse("4")},
+				Conditions: []corev1.NodeCondition{{Type: corev1.NodeReady, Status: corev1.ConditionTrue}},
+			},
+		}
+	}
+	gpuTaint := corev1.Taint{Key: "nvidia.com/gpu", Value: "true", Effect: corev1.TaintEffectNoSchedule}
+	worker := node("worker-1", map[string]string{yandexNodeGroupLabel: "general", nodeRoleLabelPrefix + "worker": ""})
+	gpu := node("gpu-1", map[string]string{yandexNodeGroupLabel: "gpu"}, gpuTaint)
+	master := node("master-1", map[string]string{nodeRoleLabelPrefix + "control-plane": ""},
+		corev1.Taint{Key: "node-role.kubernetes.io/control-plane", Effect: corev1.TaintEffectNoSchedule})
+
+	filterOf := func(query string) *nodeFilter {
+		values, err := url.ParseQuery(query)
+		Expect(err).NotTo(HaveOccurred())
+		filter, err := parseNodeFilter(values)
+		Expect(err).NotTo(HaveOccurred())
+		return filter
+	}
+	included := func(filter *nodeFilter) []string {
+		var names []string
+		for _, n := range []corev1.Node{worker, gpu, master} {
+			if filter.includes(n) {
+				names = append(names, n.Name)
+			}
+		}
+		return names
+	}
+
+	It("should select nodes by taint, selector, role and node group", func() {
+		Expect(included(filterOf(""))).To(Equal([]string{"worker-1"}))
+		Expect(included(filterOf("excludeTaints="))).To(Equal([]string{"worker-1", "gpu-1", "master-1"}))
+		Expect(included(filterOf("excludeTaints=node-role.kubernetes.io/control-plane"))).To(Equal([]string{"worker-1", "gpu-1"}))
+		Expect(included(filterOf("excludeTaints=nvidia.com/gpu=false:NoSchedule"))).To(HaveLen(3))
+		Expect(included(filterOf("excludeTaints=&excludeRoles=control-plane"))).To(Equal([]string{"worker-1", "gpu-1"}))
+		Expect(included(filterOf("excludeTaints=&roles=control-plane"))).To(Equal([]string{"master-1"}))
+		Expect(included(filterOf("excludeTaints=&nodeGroups=gpu"))).To(Equal([]string{"gpu-1"}))
+		Expect(included(filterOf("excludeTaints=&excludeNodeGroups=gpu,unassigned"))).To(Equal([]string{"worker-1"}))
+		Expect(included(filterOf("excludeTaints=&nodeSelector=" + url.QueryEscape(yandexNodeGroupLabel+" in (general,gpu)")))).
+			To(Equal([]string{"worker-1", "gpu-1"}))
+
+		for _, query := range []string{"excludeTaints=:Sometimes", "excludeTaints==x", "nodeSelector=" + url.QueryEscape("a in b")} {
+			values, _ := url.ParseQuery(query)
+			_, err := parseNodeFilter(values)
+			Expect(err).To(HaveOccurred(), query)
+		}
+	})
+
+	It("should report excluded nodes in their pool but not in the cluster total", func() {
+		prometheus := newPrometheusStandIn(map[string][]map[string]interface{}{
+			`rate(container_cpu_usage_seconds_total{id="/"}`: {
+				promSample("1", "node", "worker-1"), promSample("3", "node", "gpu-1"),
+			},
+		})
+		DeferCleanup(prometheus.Close)
+		reconciler := &KubedeckReconciler{metrics: prometheus.backend()}
+
+		metrics, err := reconciler.computeClusterMetrics(ctx, []corev1.Node{worker, gpu, master}, nil, filterOf(""))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(metrics.NodeCount).To(Equal(3))
+		Expect(metrics.IncludedNodeCount).To(Equal(1))
+		Expect(metrics.CPUUtilization).To(BeNumerically("~", 25, 1e-9))
+		Expect(metrics.Nodes[1].Excluded).To(BeTrue())
+		Expect(metrics.Nodes[1].Pool).To(Equal("gpu"))
+
+		Expect(metrics.Pools).To(HaveLen(3))
+		Expect(metrics.Pools[1].Name).To(Equal("gpu"))
+		Expect(metrics.Pools[1].CPUUtilization).To(BeNumerically("~", 75, 1e-9))
+		Expect(metrics.Pools[2].Name).To(Equal(unassignedNodePool))
+		Expect(metrics.Pools[2].NodeCount).To(Equal(1))
+	})
+})
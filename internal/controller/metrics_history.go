@@ -0,0 +1,459 @@
+package controller
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+)
+
+// Виды рядов истории метрик
+const (
+	historyKindNode      = "node"
+	historyKindPod       = "pod"
+	historyKindContainer = "container"
+
+	// maxHistoryPoints ограничивает число точек в ответе /metrics/history
+	maxHistoryPoints = 2000
+	// defaultHistoryPoints - сколько точек возвращается, если step не задан
+	defaultHistoryPoints = 300
+)
+
+// HistoryPoint - значение ряда за интервал. CPU в ядрах, память в байтах.
+// Для сырых точек среднее и максимум совпадают.
+type HistoryPoint struct {
+	Time      time.Time `json:"time"`
+	CPU       float64   `json:"cpu"`
+	CPUMax    float64   `json:"cpuMax"`
+	Memory    float64   `json:"memory"`
+	MemoryMax float64   `json:"memoryMax"`
+}
+
+// historyTier - уровень хранения: сырые точки или прореженные до resolution.
+// Точки лежат в файлах по block, файлы старше retention удаляются.
+type historyTier struct {
+	Name       string
+	Resolution time.Duration
+	Retention  time.Duration
+	block      time.Duration
+}
+
+// historyLine - строка файла уровня: все ряды на один момент времени
+type historyLine struct {
+	T int64                 `json:"t"`
+	S map[string][4]float64 `json:"s"`
+}
+
+// historyBucket накапливает незакрытый интервал прореженного уровня
+type historyBucket struct {
+	start time.Time
+	sums  map[string]*historySum
+}
+
+type historySum struct {
+	cpu, cpuMax, memory, memoryMax float64
+	count                          int
+}
+
+func (s *historySum) add(p HistoryPoint) {
+	s.cpu += p.CPU
+	s.memory += p.Memory
+	s.cpuMax = math.Max(s.cpuMax, p.CPUMax)
+	s.memoryMax = math.Max(s.memoryMax, p.MemoryMax)
+	s.count++
+}
+
+func (s *historySum) point(t time.Time) HistoryPoint {
+	return HistoryPoint{Time: t, CPU: s.cpu / float64(s.count), CPUMax: s.cpuMax,
+		Memory: s.memory / float64(s.count), MemoryMax: s.memoryMax}
+}
+
+// metricsHistory - встроенное хранилище истории использования ресурсов.
+// Каждый уровень хранится в каталоге dir/<уровень> файлами JSON Lines по блокам времени.
+// Сырые точки пишутся сразу, прореженные - при закрытии интервала.
+type metricsHistory struct {
+	sync.RWMutex
+	dir     string
+	tiers   []historyTier
+	buckets []*historyBucket
+}
+
+// historyKey возвращает ключ ряда
+func historyKey(kind, name string) string {
+	return kind + "/" + name
+}
+
+// newMetricsHistory открывает хранилище в dir. Первый уровень - сырые точки с шагом опроса.
+func newMetricsHistory(dir string, tiers []historyTier) (*metricsHistory, error) {
+	for _, tier := range tiers {
+		if err := os.MkdirAll(filepath.Join(dir, tier.Name), 0o755); err != nil {
+			return nil, err
+		}
+	}
+	return &metricsHistory{dir: dir, tiers: tiers, buckets: make([]*historyBucket, len(tiers))}, nil
+}
+
+// newMetricsHistoryFromEnv создает хранилище по настройкам окружения, nil - история отключена
+func newMetricsHistoryFromEnv() (*metricsHistory, error) {
+	if !getEnvBool("KUBEDECK_METRICS_HISTORY", true) {
+		return nil, nil
+	}
+	return newMetricsHistory(getEnvString("KUBEDECK_METRICS_HISTORY_DIR", "/tmp/kubedeck/metrics"), []historyTier{
+		{Name: "raw", Resolution: metricsSampleInterval,
+			Retention: getEnvDuration("KUBEDECK_METRICS_RETENTION", 24*time.Hour), block: time.Hour},
+		{Name: "5m", Resolution: 5 * time.Minute,
+			Retention: getEnvDuration("KUBEDECK_METRICS_RETENTION_5M", 7*24*time.Hour), block: 24 * time.Hour},
+		{Name: "1h", Resolution: time.Hour,
+			Retention: getEnvDuration("KUBEDECK_METRICS_RETENTION_1H", 90*24*time.Hour), block: 7 * 24 * time.Hour},
+	})
+}
+
+// metricsSampleInterval - период опроса metrics-server для истории
+var metricsSampleInterval = getEnvDuration("KUBEDECK_METRICS_SAMPLE_INTERVAL", time.Minute)
+
+// append сохраняет точки всех рядов на момент t, закрывает прошедшие интервалы прореженных уровней
+// и удаляет файлы старше срока хранения
+func (h *metricsHistory) append(t time.Time, samples map[string]HistoryPoint) error {
+	h.Lock()
+	defer h.Unlock()
+
+	if err := h.writeLine(0, t, samples); err != nil {
+		return err
+	}
+
+	for i := 1; i < len(h.tiers); i++ {
+		start := t.Truncate(h.tiers[i].Resolution)
+		bucket := h.buckets[i]
+		if bucket != nil && !bucket.start.Equal(start) {
+			closed := make(map[string]HistoryPoint, len(bucket.sums))
+			for key, sum := range bucket.sums {
+				closed[key] = sum.point(bucket.start)
+			}
+			if err := h.writeLine(i, bucket.start, closed); err != nil {
+				return err
+			}
+			bucket = nil
+		}
+		if bucket == nil {
+			bucket = &historyBucket{start: start, sums: make(map[string]*historySum)}
+			h.buckets[i] = bucket
+		}
+		for key, point := range samples {
+			sum, ok := bucket.sums[key]
+			if !ok {
+				sum = &historySum{}
+				bucket.sums[key] = sum
+			}
+			sum.add(point)
+		}
+	}
+
+	return h.prune(t)
+}
+
+// writeLine дописывает строку в файл блока уровня. Вызывается под блокировкой.
+func (h *metricsHistory) writeLine(tier int, t time.Time, points map[string]HistoryPoint) error {
+	line := historyLine{T: t.Unix(), S: make(map[string][4]float64, len(points))}
+	for key, p := range points {
+		line.S[key] = [4]float64{p.CPU, p.CPUMax, p.Memory, p.MemoryMax}
+	}
+	data, err := json.Marshal(line)
+	if err != nil {
+		return err
+	}
+
+	block := t.Truncate(h.tiers[tier].block).Unix()
+	path := filepath.Join(h.dir, h.tiers[tier].Name, strconv.FormatInt(block, 10)+".jsonl")
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		_ = f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// blocks возвращает начала блоков уровня, отсортированные по времени
+func (h *metricsHistory) blocks(tier int) ([]int64, error) {
+	entries, err := os.ReadDir(filepath.Join(h.dir, h.tiers[tier].Name))
+	if err != nil {
+		return nil, err
+	}
+	var blocks []int64
+	for _, entry := range entries {
+		start, err := strconv.ParseInt(strings.TrimSuffix(entry.Name(), ".jsonl"), 10, 64)
+		if err == nil && strings.HasSuffix(entry.Name(), ".jsonl") {
+			blocks = append(blocks, start)
+		}
+	}
+	sort.Slice(blocks, func(i, j int) bool { return blocks[i] < blocks[j] })
+	return blocks, nil
+}
+
+// prune удаляет блоки, целиком вышедшие за срок хранения. Вызывается под блокировкой.
+func (h *metricsHistory) prune(now time.Time) error {
+	for i, tier := range h.tiers {
+		blocks, err := h.blocks(i)
+		if err != nil {
+			return err
+		}
+		for _, start := range blocks {
+			if !time.Unix(start, 0).Add(tier.block).After(now.Add(-tier.Retention)) {
+				path := filepath.Join(h.dir, tier.Name, strconv.FormatInt(start, 10)+".jsonl")
+				if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// scan вызывает fn для точек уровня в интервале [from, to], включая незакрытый интервал прореженного уровня.
+// match отбирает ряды по ключу, nil - все ряды.
+func (h *metricsHistory) scan(tier int, from, to time.Time, match func(key string) bool, fn func(key string, p HistoryPoint)) error {
+	h.RLock()
+	defer h.RUnlock()
+
+	blocks, err := h.blocks(tier)
+	if err != nil {
+		return err
+	}
+	emit := func(t time.Time, key string, p HistoryPoint) {
+		if t.Before(from) || t.After(to) || (match != nil && !match(key)) {
+			return
+		}
+		p.Time = t
+		fn(key, p)
+	}
+
+	for _, start := range blocks {
+		blockStart := time.Unix(start, 0)
+		if blockStart.After(to) || blockStart.Add(h.tiers[tier].block).Before(from) {
+			continue
+		}
+		if err := h.scanFile(filepath.Join(h.dir, h.tiers[tier].Name, strconv.FormatInt(start, 10)+".jsonl"), emit); err != nil {
+			return err
+		}
+	}
+
+	if bucket := h.buckets[tier]; bucket != nil {
+		for key, sum := range bucket.sums {
+			emit(bucket.start, key, sum.point(bucket.start))
+		}
+	}
+	return nil
+}
+
+// scanFile читает файл блока построчно. Недописанная при остановке строка пропускается.
+func (h *metricsHistory) scanFile(path string, emit func(t time.Time, key string, p HistoryPoint)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		var line historyLine
+		if err := json.Unmarshal(scanner.Bytes(), &line); err != nil {
+			continue
+		}
+		t := time.Unix(line.T, 0)
+		for key, v := range line.S {
+			emit(t, key, HistoryPoint{CPU: v[0], CPUMax: v[1], Memory: v[2], MemoryMax: v[3]})
+		}
+	}
+	return scanner.Err()
+}
+
+// selectTier выбирает самый подробный уровень, который хранит данные с from и не мельче step
+func (h *metricsHistory) selectTier(from time.Time, step time.Duration, now time.Time) int {
+	for i, tier := range h.tiers {
+		if i == len(h.tiers)-1 {
+			return i
+		}
+		// Следующий уровень подходит лучше, если шаг не мельче его разрешения
+		if step >= h.tiers[i+1].Resolution {
+			continue
+		}
+		if !from.Before(now.Add(-tier.Retention)) {
+			return i
+		}
+	}
+	return len(h.tiers) - 1
+}
+
+// query возвращает ряд с шагом step: среднее по интервалу и максимум максимумов
+func (h *metricsHistory) query(kind, name string, from, to time.Time, step time.Duration, now time.Time) ([]HistoryPoint, historyTier, error) {
+	tier := h.selectTier(from, step, now)
+	key := historyKey(kind, name)
+
+	sums := map[int64]*historySum{}
+	err := h.scan(tier, from, to, func(k string) bool { return k == key }, func(_ string, p HistoryPoint) {
+		slot := from.Add(p.Time.Sub(from) / step * step).Unix()
+		sum, ok := sums[slot]
+		if !ok {
+			sum = &historySum{}
+			sums[slot] = sum
+		}
+		sum.add(p)
+	})
+	if err != nil {
+		return nil, h.tiers[tier], err
+	}
+
+	points := make([]HistoryPoint, 0, len(sums))
+	for slot, sum := range sums {
+		points = append(points, sum.point(time.Unix(slot, 0).UTC()))
+	}
+	sort.Slice(points, func(i, j int) bool { return points[i].Time.Before(points[j].Time) })
+	return points, h.tiers[tier], nil
+}
+
+// runMetricsSampler периодически сохраняет использование нод, подов и контейнеров кластера kubedeck.
+// Работает на всех репликах: каждая отвечает на /metrics/history из своего хранилища.
+func (r *KubedeckReconciler) runMetricsSampler(ctx context.Context) error {
+	log := webServerLog.WithName("metrics-sampler")
+	if r.history == nil {
+		return nil
+	}
+
+	ticker := time.NewTicker(metricsSampleInterval)
+	defer ticker.Stop()
+	for {
+		samples, err := r.sampleMetrics(ctx)
+		if err != nil {
+			log.Error(err, "Failed to sample metrics")
+		} else if err := r.history.append(time.Now().Truncate(time.Second), samples); err != nil {
+			log.Error(err, "Failed to store metrics history")
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// sampleMetrics читает текущее использование из источника метрик
+func (r *KubedeckReconciler) sampleMetrics(ctx context.Context) (map[string]HistoryPoint, error) {
+	snapshot, err := r.metricsSnapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	nodeMetricsList, podMetricsList := snapshot.nodes, snapshot.pods
+
+	samples := make(map[string]HistoryPoint, len(nodeMetricsList)+len(podMetricsList)*2)
+	point := func(usage corev1.ResourceList) HistoryPoint {
+		cpu := usage.Cpu().AsApproximateFloat64()
+		memory := usage.Memory().AsApproximateFloat64()
+		return HistoryPoint{CPU: cpu, CPUMax: cpu, Memory: memory, MemoryMax: memory}
+	}
+	for _, node := range nodeMetricsList {
+		samples[historyKey(historyKindNode, node.Name)] = point(node.Usage)
+	}
+	for _, pod := range podMetricsList {
+		podName := pod.Namespace + "/" + pod.Name
+		var total HistoryPoint
+		for _, container := range pod.Containers {
+			p := point(container.Usage)
+			samples[historyKey(historyKindContainer, podName+"/"+container.Name)] = p
+			total.CPU += p.CPU
+			total.Memory += p.Memory
+		}
+		total.CPUMax, total.MemoryMax = total.CPU, total.Memory
+		samples[historyKey(historyKindPod, podName)] = total
+	}
+	return samples, nil
+}
+
+// parseHistoryTime разбирает время в RFC3339 или unix-секундах
+func parseHistoryTime(value string, def time.Time) (time.Time, error) {
+	if value == "" {
+		return def, nil
+	}
+	if seconds, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.Unix(seconds, 0), nil
+	}
+	return time.Parse(time.RFC3339, value)
+}
+
+// handleMetricsHistoryRequest обрабатывает GET /metrics/history?kind=&name=&from=&to=&step=
+func (r *KubedeckReconciler) handleMetricsHistoryRequest(w http.ResponseWriter, req *http.Request) {
+	if r.history == nil {
+		http.Error(w, "Metrics history is disabled", http.StatusServiceUnavailable)
+		return
+	}
+
+	query := req.URL.Query()
+	kind, name := query.Get("kind"), query.Get("name")
+	switch kind {
+	case historyKindNode, historyKindPod, historyKindContainer:
+	default:
+		http.Error(w, "kind must be node, pod or container", http.StatusBadRequest)
+		return
+	}
+	if name == "" {
+		http.Error(w, "name parameter is required (node, namespace/pod or namespace/pod/container)", http.StatusBadRequest)
+		return
+	}
+
+	now := time.Now()
+	to, err := parseHistoryTime(query.Get("to"), now)
+	if err != nil {
+		http.Error(w, "Invalid to: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	from, err := parseHistoryTime(query.Get("from"), to.Add(-time.Hour))
+	if err != nil {
+		http.Error(w, "Invalid from: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if !from.Before(to) {
+		http.Error(w, "from must be before to", http.StatusBadRequest)
+		return
+	}
+
+	step := to.Sub(from) / defaultHistoryPoints
+	if value := query.Get("step"); value != "" {
+		if step, err = time.ParseDuration(value); err != nil || step <= 0 {
+			http.Error(w, "Invalid step: expected a positive duration such as 5m", http.StatusBadRequest)
+			return
+		}
+	}
+	step = max(step, r.history.tiers[0].Resolution)
+	if to.Sub(from)/step > maxHistoryPoints {
+		http.Error(w, fmt.Sprintf("Too many points: at most %d per request, increase step", maxHistoryPoints), http.StatusBadRequest)
+		return
+	}
+
+	points, tier, err := r.history.query(kind, name, from, to, step, now)
+	writeJsonResponse(w, map[string]interface{}{
+		"kind":       kind,
+		"name":       name,
+		"from":       from.UTC(),
+		"to":         to.UTC(),
+		"step":       step.String(),
+		"resolution": tier.Name,
+		"points":     points,
+	}, "metrics history", err)
+}
@@ -0,0 +1,118 @@
+package controller
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+var _ = Describe("Metrics history", func() {
+	var dir string
+	tiers := []historyTier{
+		{Name: "raw", Resolution: time.Minute, Retention: 2 * time.Hour, block: time.Hour},
+		{Name: "5m", Resolution: 5 * time.Minute, Retention: 24 * time.Hour, block: 6 * time.Hour},
+	}
+	// Начало часа, чтобы интервалы прореживания совпадали с минутами
+	start := time.Now().Add(-3 * time.Hour).Truncate(time.Hour)
+
+	sample := func(cpu, memory float64) map[string]HistoryPoint {
+		return map[string]HistoryPoint{
+			historyKey(historyKindPod, "default/web"): {CPU: cpu, CPUMax: cpu, Memory: memory, MemoryMax: memory},
+			historyKey(historyKindNode, "node-1"):     {CPU: 1, CPUMax: 1, Memory: 1 << 30, MemoryMax: 1 << 30},
+		}
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "kubedeck-history")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(os.RemoveAll, dir)
+	})
+
+	It("should store raw points and downsample them", func() {
+		history, err := newMetricsHistory(dir, tiers)
+		Expect(err).NotTo(HaveOccurred())
+
+		// 10 минут: CPU растет от 0.1 до 1.0
+		for i := range 10 {
+			Expect(history.append(start.Add(time.Duration(i)*time.Minute), sample(float64(i+1)/10, 100))).To(Succeed())
+		}
+
+		raw, tier, err := history.query(historyKindPod, "default/web", start, start.Add(time.Hour), time.Minute, start.Add(10*time.Minute))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tier.Name).To(Equal("raw"))
+		Expect(raw).To(HaveLen(10))
+		Expect(raw[9].CPU).To(BeNumerically("~", 1.0, 1e-9))
+
+		downsampled, tier, err := history.query(historyKindPod, "default/web", start, start.Add(time.Hour), 5*time.Minute, start.Add(10*time.Minute))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tier.Name).To(Equal("5m"))
+		Expect(downsampled).To(HaveLen(2))
+		Expect(downsampled[0].CPU).To(BeNumerically("~", 0.3, 1e-9))
+		Expect(downsampled[0].CPUMax).To(BeNumerically("~", 0.5, 1e-9))
+		// Второй интервал еще не закрыт и берется из памяти
+		Expect(downsampled[1].CPU).To(BeNumerically("~", 0.8, 1e-9))
+
+		// Данные читаются с диска после перезапуска
+		reopened, err := newMetricsHistory(dir, tiers)
+		Expect(err).NotTo(HaveOccurred())
+		points, _, err := reopened.query(historyKindPod, "default/web", start, start.Add(time.Hour), 5*time.Minute, start.Add(10*time.Minute))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(points).To(HaveLen(1))
+		Expect(points[0].Time).To(BeTemporally("==", start))
+	})
+
+	It("should pick a coarser tier for ranges beyond raw retention and prune old blocks", func() {
+		history, err := newMetricsHistory(dir, tiers)
+		Expect(err).NotTo(HaveOccurred())
+
+		now := start.Add(3 * time.Hour)
+		Expect(history.selectTier(now.Add(-time.Hour), time.Minute, now)).To(Equal(0))
+		Expect(history.selectTier(now.Add(-3*time.Hour), time.Minute, now)).To(Equal(1))
+		Expect(history.selectTier(now.Add(-time.Hour), 10*time.Minute, now)).To(Equal(1))
+
+		Expect(history.append(start, sample(1, 1))).To(Succeed())
+		Expect(history.append(now, sample(1, 1))).To(Succeed())
+
+		blocks, err := history.blocks(0)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(blocks).To(Equal([]int64{now.Truncate(time.Hour).Unix()}))
+		Expect(filepath.Join(dir, "5m")).To(BeADirectory())
+	})
+
+	It("should validate /metrics/history parameters", func() {
+		history, err := newMetricsHistory(dir, tiers)
+		Expect(err).NotTo(HaveOccurred())
+		reconciler := &KubedeckReconciler{history: history}
+		now := time.Now().Truncate(time.Minute)
+		Expect(history.append(now.Add(-2*time.Minute), sample(0.5, 100))).To(Succeed())
+
+		for _, query := range []string{"kind=deployment&name=x", "kind=pod", "kind=pod&name=a&from=2020-01-01T00:00:00Z&to=2019-01-01T00:00:00Z",
+			"kind=pod&name=a&step=-1m", "kind=pod&name=a&from=0&step=1m"} {
+			recorder := httptest.NewRecorder()
+			reconciler.handleMetricsHistoryRequest(recorder, httptest.NewRequest(http.MethodGet, "/metrics/history?"+query, nil))
+			Expect(recorder.Code).To(Equal(http.StatusBadRequest), query)
+		}
+
+		recorder := httptest.NewRecorder()
+		reconciler.handleMetricsHistoryRequest(recorder, httptest.NewRequest(http.MethodGet, "/metrics/history?kind=pod&name=default/web&step=1m", nil))
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+		var response struct {
+			Resolution string         `json:"resolution"`
+			Points     []HistoryPoint `json:"points"`
+		}
+		Expect(json.Unmarshal(recorder.Body.Bytes(), &response)).To(Succeed())
+		Expect(response.Resolution).To(Equal("raw"))
+		Expect(response.Points).To(HaveLen(1))
+
+		recorder = httptest.NewRecorder()
+		(&KubedeckReconciler{}).handleMetricsHistoryRequest(recorder, httptest.NewRequest(http.MethodGet, "/metrics/history?kind=pod&name=a", nil))
+		Expect(recorder.Code).To(Equal(http.StatusServiceUnavailable))
+	})
+})
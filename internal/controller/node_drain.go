@@ -0,0 +1,422 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ErrDrainStalled - вытеснение подов не продвигается дольше допустимого (обычно из-за PodDisruptionBudget)
+var ErrDrainStalled = errors.New("node drain stalled")
+
+const (
+	scaleInPhaseDraining  = "Draining"
+	scaleInPhaseDeleting  = "Deleting"
+	scaleInPhaseSucceeded = "Succeeded"
+	scaleInPhaseFailed    = "Failed"
+)
+
+var (
+	// drainPollInterval - период повторных попыток вытеснения подов
+	drainPollInterval = 5 * time.Second
+	// drainStallTimeout - сколько ждать уменьшения числа подов на нодах, прежде чем прервать уменьшение группы
+	drainStallTimeout = getEnvDuration("KUBEDECK_DRAIN_STALL_TIMEOUT", 5*time.Minute)
+)
+
+// ScaleInOperation - операция безопасного уменьшения группы нод
+type ScaleInOperation struct {
+	ID          string   `json:"id"`
+	Provider    string   `json:"provider"`
+	ClusterID   string   `json:"cluster_id"`
+	NodeGroupID string   `json:"group_id"`
+	FromNodes   int      `json:"from_nodes"`
+	ToNodes     int      `json:"to_nodes"`
+	Nodes       []string `json:"nodes"`
+	Phase       string   `json:"phase"`
+	Message     string   `json:"message,omitempty"`
+	PendingPods []string `json:"pending_pods,omitempty"`
+	// Force разрешает удалять ноды с подами без контроллера, которые никто не пересоздаст
+	Force bool `json:"force,omitempty"`
+	// CostImpact - изменение расходов, рассчитанное до начала операции
+	CostImpact *ScaleCostImpact `json:"cost_impact,omitempty"`
+	StartedAt  time.Time        `json:"started_at"`
+	FinishedAt *time.Time       `json:"finished_at,omitempty"`
+}
+
+func (op ScaleInOperation) operationID() string { return op.ID }
+
+func (op ScaleInOperation) finished() bool { return op.FinishedAt != nil }
+
+// scaleInOperations хранит операции уменьшения групп в памяти
+type scaleInOperations struct {
+	operationStore[ScaleInOperation]
+}
+
+// begin добавляет операцию, если у группы нет незавершенной операции уменьшения
+func (s *scaleInOperations) begin(op *ScaleInOperation) error {
+	s.Lock()
+	defer s.Unlock()
+	for _, other := range s.operations {
+		if other.FinishedAt == nil && other.Provider == op.Provider && other.ClusterID == op.ClusterID &&
+			other.NodeGroupID == op.NodeGroupID {
+			return fmt.Errorf("%w: node group %s is already being scaled in by operation %s",
+				ErrProviderConflict, op.NodeGroupID, other.ID)
+		}
+	}
+	s.appendLocked(op)
+	return nil
+}
+
+// startScaleIn выбирает наименее загруженные ноды группы и запускает их drain и удаление в фоне.
+// Без force ноды с подами без контроллера не выбираются. Одновременно у группы может идти только одна операция.
+func (r *KubedeckReconciler) startScaleIn(ctx context.Context, providerName string, deleter NodeDeleter,
+	clusterID string, group NodeGroup, nodeCount int, force bool, costImpact *ScaleCostImpact) (*ScaleInOperation, error) {
+	if len(group.NodeLabels) == 0 {
+		return nil, fmt.Errorf("%w: provider %s does not expose node labels for node group %s",
+			ErrProviderBadRequest, providerName, group.ID)
+	}
+
+	nodeList := &corev1.NodeList{}
+	if err := r.clientFor(ctx).List(ctx, nodeList, client.MatchingLabels(group.NodeLabels)); err != nil {
+		return nil, err
+	}
+	podList := &corev1.PodList{}
+	if err := r.clientFor(ctx).List(ctx, podList); err != nil {
+		return nil, err
+	}
+
+	count := group.NodeCount - nodeCount
+	if count > len(nodeList.Items) {
+		return nil, fmt.Errorf("%w: node group %s has %d nodes in the cluster, cannot remove %d",
+			ErrProviderBadRequest, group.ID, len(nodeList.Items), count)
+	}
+
+	candidates := nodeList.Items
+	if !force {
+		var unmanaged []string
+		candidates, unmanaged = nodesWithoutUnmanagedPods(nodeList.Items, podList.Items)
+		if count > len(candidates) {
+			return nil, fmt.Errorf("%w: node group %s cannot remove %d nodes, pods without a controller would be lost: %s "+
+				"(pass force=true to delete them)", ErrProviderBadRequest, group.ID, count, strings.Join(unmanaged, ", "))
+		}
+	}
+
+	nodes := selectNodesForScaleIn(candidates, podList.Items, count)
+	op := &ScaleInOperation{
+		ID:          string(uuid.NewUUID()),
+		Provider:    providerName,
+		ClusterID:   clusterID,
+		NodeGroupID: group.ID,
+		FromNodes:   group.NodeCount,
+		ToNodes:     nodeCount,
+		Phase:       scaleInPhaseDraining,
+		Force:       force,
+		CostImpact:  costImpact,
+		StartedAt:   time.Now(),
+	}
+	for _, node := range nodes {
+		op.Nodes = append(op.Nodes, node.Name)
+	}
+	if err := r.scaleIns.begin(op); err != nil {
+		return nil, err
+	}
+	snapshot := *op
+
+	go r.runScaleIn(context.WithoutCancel(ctx), op, deleter, nodes)
+	return &snapshot, nil
+}
+
+// resizeNodeGroup устанавливает размер группы. Уменьшение у провайдеров с NodeDeleter идет через drain
+// выбранных нод (возвращается запущенная операция), иначе провайдер удалил бы ноды вместе с подами.
+func (r *KubedeckReconciler) resizeNodeGroup(ctx context.Context, providerName string, provider ClusterProvider,
+	clusterID string, group NodeGroup, nodeCount int) (*ScaleInOperation, error) {
+	if deleter, ok := provider.(NodeDeleter); ok && nodeCount < group.NodeCount {
+		return r.startScaleIn(ctx, providerName, deleter, clusterID, group, nodeCount, false, nil)
+	}
+	return nil, provider.ScaleNodeGroup(ctx, clusterID, group.ID, nodeCount)
+}
+
+// runScaleIn выполняет cordon, вытеснение подов и удаление нод у провайдера.
+// При зависании вытеснения ноды возвращаются в работу, а группа не меняется.
+func (r *KubedeckReconciler) runScaleIn(ctx context.Context, op *ScaleInOperation, deleter NodeDeleter, nodes []corev1.Node) {
+	log := webServerLog.WithName("scale-in").WithValues("operation", op.ID, "group", op.NodeGroupID, "nodes", op.Nodes)
+	log.Info("Starting node group scale-in")
+
+	fail := func(err error) {
+		log.Error(err, "Node group scale-in failed")
+		r.scaleIns.update(op, func(op *ScaleInOperation) {
+			now := time.Now()
+			op.Phase = scaleInPhaseFailed
+			op.Message = err.Error()
+			op.FinishedAt = &now
+		})
+	}
+
+	cordoned, err := r.cordonNodes(ctx, nodes)
+	if err != nil {
+		r.uncordonNodes(ctx, cordoned)
+		fail(fmt.Errorf("failed to cordon nodes: %w", err))
+		return
+	}
+
+	if err := r.drainNodes(ctx, op, nodes, drainStallTimeout); err != nil {
+		r.uncordonNodes(ctx, cordoned)
+		fail(err)
+		return
+	}
+
+	r.scaleIns.update(op, func(op *ScaleInOperation) { op.Phase = scaleInPhaseDeleting })
+	if err := deleter.DeleteNodes(ctx, op.ClusterID, op.NodeGroupID, nodes); err != nil {
+		r.uncordonNodes(ctx, cordoned)
+		fail(fmt.Errorf("failed to delete nodes: %w", err))
+		return
+	}
+
+	r.scaleIns.update(op, func(op *ScaleInOperation) {
+		now := time.Now()
+		op.Phase = scaleInPhaseSucceeded
+		op.FinishedAt = &now
+	})
+	log.Info("Node group scale-in finished")
+}
+
+// nodesWithoutUnmanagedPods возвращает ноды без подов, у которых нет контроллера, и такие поды на остальных нодах
+func nodesWithoutUnmanagedPods(nodes []corev1.Node, pods []corev1.Pod) ([]corev1.Node, []string) {
+	names := make(map[string]bool, len(nodes))
+	for _, node := range nodes {
+		names[node.Name] = true
+	}
+	blocked := map[string]bool{}
+	var unmanaged []string
+	for _, pod := range pods {
+		if names[pod.Spec.NodeName] && isPodUnmanaged(pod) {
+			blocked[pod.Spec.NodeName] = true
+			unmanaged = append(unmanaged, pod.Namespace+"/"+pod.Name)
+		}
+	}
+
+	result := make([]corev1.Node, 0, len(nodes))
+	for _, node := range nodes {
+		if !blocked[node.Name] {
+			result = append(result, node)
+		}
+	}
+	return result, unmanaged
+}
+
+// selectNodesForScaleIn выбирает count нод для удаления: сначала неготовые,
+// затем с наименьшей долей запрошенных ресурсов, затем с наименьшим числом подов.
+func selectNodesForScaleIn(nodes []corev1.Node, pods []corev1.Pod, count int) []corev1.Node {
+	type candidate struct {
+		node  corev1.Node
+		ready bool
+		load  float64
+		pods  int
+	}
+
+	candidates := make([]candidate, 0, len(nodes))
+	for _, node := range nodes {
+		var cpu, memory resource.Quantity
+		podCount := 0
+		for _, pod := range pods {
+			if pod.Spec.NodeName != node.Name || isPodFinished(pod) {
+				continue
+			}
+			podCount++
+			for _, container := range pod.Spec.Containers {
+				cpu.Add(container.Resources.Requests.Cpu().DeepCopy())
+				memory.Add(container.Resources.Requests.Memory().DeepCopy())
+			}
+		}
+
+		load := 0.0
+		if allocCPU := node.Status.Allocatable.Cpu().AsApproximateFloat64(); allocCPU > 0 {
+			load = max(load, cpu.AsApproximateFloat64()/allocCPU)
+		}
+		if allocMem := node.Status.Allocatable.Memory().AsApproximateFloat64(); allocMem > 0 {
+			load = max(load, memory.AsApproximateFloat64()/allocMem)
+		}
+		candidates = append(candidates, candidate{node: node, ready: isNodeReady(node), load: load, pods: podCount})
+	}
+
+	sort.SliceStable(candidates, func(i, j int) bool {
+		a, b := candidates[i], candidates[j]
+		if a.ready != b.ready {
+			return !a.ready
+		}
+		if a.load != b.load {
+			return a.load < b.load
+		}
+		if a.pods != b.pods {
+			return a.pods < b.pods
+		}
+		return a.node.Name < b.node.Name
+	})
+
+	selected := make([]corev1.Node, 0, count)
+	for i := 0; i < count && i < len(candidates); i++ {
+		selected = append(selected, candidates[i].node)
+	}
+	return selected
+}
+
+// cordonNodes помечает ноды unschedulable и возвращает те, что были изменены
+func (r *KubedeckReconciler) cordonNodes(ctx context.Context, nodes []corev1.Node) ([]string, error) {
+	var cordoned []string
+	for _, node := range nodes {
+		if node.Spec.Unschedulable {
+			continue
+		}
+		patch := client.MergeFrom(node.DeepCopy())
+		node.Spec.Unschedulable = true
+		if err := r.clientFor(ctx).Patch(ctx, &node, patch); err != nil {
+			return cordoned, err
+		}
+		cordoned = append(cordoned, node.Name)
+	}
+	return cordoned, nil
+}
+
+// uncordonNodes возвращает ноды в работу после прерванного уменьшения группы
+func (r *KubedeckReconciler) uncordonNodes(ctx context.Context, names []string) {
+	for _, name := range names {
+		node := &corev1.Node{}
+		if err := r.clientFor(ctx).Get(ctx, client.ObjectKey{Name: name}, node); err != nil {
+			webServerLog.WithName("scale-in").Error(err, "Failed to uncordon node", "node", name)
+			continue
+		}
+		patch := client.MergeFrom(node.DeepCopy())
+		node.Spec.Unschedulable = false
+		if err := r.clientFor(ctx).Patch(ctx, node, patch); err != nil {
+			webServerLog.WithName("scale-in").Error(err, "Failed to uncordon node", "node", name)
+		}
+	}
+}
+
+// drainNodes вытесняет поды с нод через Eviction API, соблюдая PodDisruptionBudget.
+// Возвращает ErrDrainStalled, если число оставшихся подов не уменьшается в течение stallTimeout.
+func (r *KubedeckReconciler) drainNodes(ctx context.Context, op *ScaleInOperation, nodes []corev1.Node, stallTimeout time.Duration) error {
+	nodeNames := make(map[string]bool, len(nodes))
+	for _, node := range nodes {
+		nodeNames[node.Name] = true
+	}
+
+	lastProgress := time.Now()
+	remaining := -1
+
+	for {
+		podList := &corev1.PodList{}
+		if err := r.clientFor(ctx).List(ctx, podList); err != nil {
+			return err
+		}
+
+		var pending []corev1.Pod
+		for _, pod := range podList.Items {
+			if nodeNames[pod.Spec.NodeName] && isPodEvictable(pod) {
+				pending = append(pending, pod)
+			}
+		}
+
+		names := make([]string, 0, len(pending))
+		for _, pod := range pending {
+			names = append(names, pod.Namespace+"/"+pod.Name)
+		}
+		r.scaleIns.update(op, func(op *ScaleInOperation) { op.PendingPods = names })
+
+		if len(pending) == 0 {
+			return nil
+		}
+		// Под без контроллера мог появиться на ноде после выбора нод
+		if !op.Force {
+			for _, pod := range pending {
+				if isPodUnmanaged(pod) {
+					return fmt.Errorf("pod %s/%s has no controller and would not be recreated", pod.Namespace, pod.Name)
+				}
+			}
+		}
+		if remaining < 0 || len(pending) < remaining {
+			remaining = len(pending)
+			lastProgress = time.Now()
+		}
+		if time.Since(lastProgress) > stallTimeout {
+			return fmt.Errorf("%w: %d pods not evicted in %s: %s",
+				ErrDrainStalled, len(pending), stallTimeout, strings.Join(names, ", "))
+		}
+
+		for i := range pending {
+			pod := &pending[i]
+			if pod.DeletionTimestamp != nil {
+				continue
+			}
+			eviction := &policyv1.Eviction{ObjectMeta: metav1.ObjectMeta{Name: pod.Name, Namespace: pod.Namespace}}
+			err := r.clientFor(ctx).SubResource("eviction").Create(ctx, pod, eviction)
+			switch {
+			case err == nil, apierrors.IsNotFound(err):
+			case apierrors.IsTooManyRequests(err):
+				// PodDisruptionBudget не разрешает вытеснение сейчас, повторим позже
+			default:
+				return fmt.Errorf("failed to evict pod %s/%s: %w", pod.Namespace, pod.Name, err)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(drainPollInterval):
+		}
+	}
+}
+
+// isPodEvictable - под нужно вытеснить с ноды: не завершен, не статический и не управляется DaemonSet
+func isPodEvictable(pod corev1.Pod) bool {
+	if isPodFinished(pod) {
+		return false
+	}
+	if _, mirror := pod.Annotations[corev1.MirrorPodAnnotationKey]; mirror {
+		return false
+	}
+	for _, owner := range pod.OwnerReferences {
+		if owner.Kind == "DaemonSet" {
+			return false
+		}
+	}
+	return true
+}
+
+// isPodUnmanaged - под вытесняется без возврата: у него нет контроллера и он не статический
+func isPodUnmanaged(pod corev1.Pod) bool {
+	if !isPodEvictable(pod) {
+		return false
+	}
+	return metav1.GetControllerOf(&pod) == nil
+}
+
+func isPodFinished(pod corev1.Pod) bool {
+	return pod.Status.Phase == corev1.PodSucceeded || pod.Status.Phase == corev1.PodFailed
+}
+
+// handleCloudScaleOperationsRequest возвращает операции уменьшения групп нод (все или одну по {id})
+func (r *KubedeckReconciler) handleCloudScaleOperationsRequest(w http.ResponseWriter, req *http.Request) {
+	if id := req.PathValue("id"); id != "" {
+		op, ok := r.scaleIns.get(id)
+		if !ok {
+			http.Error(w, "Operation not found", http.StatusNotFound)
+			return
+		}
+		writeJsonResponse(w, op, "scale operation", nil)
+		return
+	}
+	writeJsonResponse(w, r.scaleIns.list(), "scale operations", nil)
+}
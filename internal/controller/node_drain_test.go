@@ -0,0 +1,216 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	policyv1 "k8s.io/api/policy/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/intstr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+var _ = Describe("Node group scale-in", func() {
+	var (
+		provider   *FakeProvider
+		reconciler *KubedeckReconciler
+	)
+
+	// newPod создает под ReplicaSet на ноде; поды без контроллера создаются через newBarePod
+	newBarePod := func(name, nodeName, cpu string) *corev1.Pod {
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: map[string]string{"app": name}},
+			Spec: corev1.PodSpec{
+				NodeName:                      nodeName,
+				TerminationGracePeriodSeconds: new(int64),
+				Containers:                    []corev1.Container{{Name: "app", Image: "nginx"}},
+			},
+		}
+		if cpu != "" {
+			pod.Spec.Containers[0].Resources.Requests = corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(cpu)}
+		}
+		Expect(k8sClient.Create(ctx, pod)).To(Succeed())
+		return pod
+	}
+	newPod := func(name, nodeName, cpu string) *corev1.Pod {
+		isController := true
+		pod := &corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: "default", Labels: map[string]string{"app": name},
+				OwnerReferences: []metav1.OwnerReference{{APIVersion: "apps/v1", Kind: "ReplicaSet", Name: name,
+					UID: types.UID("rs-" + name), Controller: &isController}}},
+			Spec: corev1.PodSpec{
+				NodeName:                      nodeName,
+				TerminationGracePeriodSeconds: new(int64),
+				Containers:                    []corev1.Container{{Name: "app", Image: "nginx"}},
+			},
+		}
+		if cpu != "" {
+			pod.Spec.Containers[0].Resources.Requests = corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(cpu)}
+		}
+		Expect(k8sClient.Create(ctx, pod)).To(Succeed())
+		return pod
+	}
+
+	getGroup := func(id string) NodeGroup {
+		groups, err := provider.GetNodeGroups(ctx, "fake-cluster-1")
+		Expect(err).NotTo(HaveOccurred())
+		for _, group := range groups {
+			if group.ID == id {
+				return group
+			}
+		}
+		Fail("node group " + id + " not found")
+		return NodeGroup{}
+	}
+
+	waitForOperation := func(id string) ScaleInOperation {
+		var op ScaleInOperation
+		Eventually(func() *time.Time {
+			op, _ = reconciler.scaleIns.get(id)
+			return op.FinishedAt
+		}).ShouldNot(BeNil())
+		return op
+	}
+
+	BeforeEach(func() {
+		DeferCleanup(func(poll, stall time.Duration) {
+			drainPollInterval, drainStallTimeout = poll, stall
+		}, drainPollInterval, drainStallTimeout)
+		drainPollInterval, drainStallTimeout = 20*time.Millisecond, 300*time.Millisecond
+
+		provider = NewFakeProvider(FakeProviderOptions{ScaleDelay: 10 * time.Millisecond, ManageNodes: true, Client: k8sClient})
+		Expect(provider.Start(ctx)).To(Succeed())
+		reconciler = &KubedeckReconciler{Client: k8sClient}
+	})
+
+	AfterEach(func() {
+		Expect(k8sClient.DeleteAllOf(ctx, &corev1.Pod{}, client.InNamespace("default"),
+			client.GracePeriodSeconds(0))).To(Succeed())
+		Expect(k8sClient.DeleteAllOf(ctx, &policyv1.PodDisruptionBudget{}, client.InNamespace("default"))).To(Succeed())
+		Expect(k8sClient.DeleteAllOf(ctx, &corev1.Node{}, client.HasLabels{FakeNodeGroupLabel})).To(Succeed())
+	})
+
+	It("should drain and delete the least loaded node", func() {
+		newPod("busy", "fake-general-0", "1")
+		light := newPod("light", "fake-general-1", "")
+
+		op, err := reconciler.startScaleIn(ctx, "fake", provider, "fake-cluster-1", getGroup("fake-ng-general"), 1, false, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(op.Nodes).To(Equal([]string{"fake-general-1"}))
+
+		finished := waitForOperation(op.ID)
+		Expect(finished.Phase).To(Equal(scaleInPhaseSucceeded))
+
+		err = k8sClient.Get(ctx, client.ObjectKeyFromObject(light), &corev1.Pod{})
+		Expect(apierrors.IsNotFound(err)).To(BeTrue())
+		Eventually(func() bool {
+			err := k8sClient.Get(ctx, client.ObjectKey{Name: "fake-general-1"}, &corev1.Node{})
+			return apierrors.IsNotFound(err)
+		}).Should(BeTrue())
+		Eventually(func() int { return getGroup("fake-ng-general").NodeCount }).Should(Equal(1))
+	})
+
+	It("should abort and uncordon when a PodDisruptionBudget blocks eviction", func() {
+		newPod("guarded", "fake-highmem-0", "")
+		minAvailable := intstr.FromInt32(1)
+		Expect(k8sClient.Create(ctx, &policyv1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{Name: "guarded", Namespace: "default"},
+			Spec: policyv1.PodDisruptionBudgetSpec{
+				MinAvailable: &minAvailable,
+				Selector:     &metav1.LabelSelector{MatchLabels: map[string]string{"app": "guarded"}},
+			},
+		})).To(Succeed())
+
+		op, err := reconciler.startScaleIn(ctx, "fake", provider, "fake-cluster-1", getGroup("fake-ng-highmem"), 0, false, nil)
+		Expect(err).NotTo(HaveOccurred())
+
+		finished := waitForOperation(op.ID)
+		Expect(finished.Phase).To(Equal(scaleInPhaseFailed))
+		Expect(finished.Message).To(ContainSubstring(ErrDrainStalled.Error()))
+		Expect(finished.PendingPods).To(Equal([]string{"default/guarded"}))
+
+		node := &corev1.Node{}
+		Expect(k8sClient.Get(ctx, client.ObjectKey{Name: "fake-highmem-0"}, node)).To(Succeed())
+		Expect(node.Spec.Unschedulable).To(BeFalse())
+		Expect(getGroup("fake-ng-highmem").NodeCount).To(Equal(1))
+	})
+
+	It("should keep nodes with pods without a controller unless forced", func() {
+		newBarePod("standalone", "fake-highmem-0", "")
+
+		_, err := reconciler.startScaleIn(ctx, "fake", provider, "fake-cluster-1", getGroup("fake-ng-highmem"), 0, false, nil)
+		Expect(errors.Is(err, ErrProviderBadRequest)).To(BeTrue())
+		Expect(err.Error()).To(ContainSubstring("default/standalone"))
+
+		// Из двух нод general выбирается нода без такого пода, даже если она загружена сильнее
+		newBarePod("scratch", "fake-general-1", "")
+		newPod("busy", "fake-general-0", "1")
+		op, err := reconciler.startScaleIn(ctx, "fake", provider, "fake-cluster-1", getGroup("fake-ng-general"), 1, false, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(op.Nodes).To(Equal([]string{"fake-general-0"}))
+		Expect(waitForOperation(op.ID).Phase).To(Equal(scaleInPhaseSucceeded))
+
+		op, err = reconciler.startScaleIn(ctx, "fake", provider, "fake-cluster-1", getGroup("fake-ng-highmem"), 0, true, nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(waitForOperation(op.ID).Phase).To(Equal(scaleInPhaseSucceeded))
+	})
+
+	It("should run one scale-in of a node group at a time", func() {
+		newPod("guarded", "fake-highmem-0", "")
+		minAvailable := intstr.FromInt32(1)
+		Expect(k8sClient.Create(ctx, &policyv1.PodDisruptionBudget{
+			ObjectMeta: metav1.ObjectMeta{Name: "guarded", Namespace: "default"},
+			Spec: policyv1.PodDisruptionBudgetSpec{
+				MinAvailable: &minAvailable,
+				Selector:     &metav1.LabelSelector{MatchLabels: map[string]string{"app": "guarded"}},
+			},
+		})).To(Succeed())
+
+		op, err := reconciler.startScaleIn(ctx, "fake", provider, "fake-cluster-1", getGroup("fake-ng-highmem"), 0, false, nil)
+		Expect(err).NotTo(HaveOccurred())
+		_, err = reconciler.startScaleIn(ctx, "fake", provider, "fake-cluster-1", getGroup("fake-ng-highmem"), 0, false, nil)
+		Expect(errors.Is(err, ErrProviderConflict)).To(BeTrue())
+
+		// Другие группы уменьшаются независимо
+		second, err := reconciler.startScaleIn(ctx, "fake", provider, "fake-cluster-1", getGroup("fake-ng-general"), 1, false, nil)
+		Expect(err).NotTo(HaveOccurred())
+		waitForOperation(second.ID)
+
+		Expect(waitForOperation(op.ID).Phase).To(Equal(scaleInPhaseFailed))
+		retry, err := reconciler.startScaleIn(ctx, "fake", provider, "fake-cluster-1", getGroup("fake-ng-highmem"), 0, false, nil)
+		Expect(err).NotTo(HaveOccurred())
+		waitForOperation(retry.ID)
+	})
+})
+
+var _ = Describe("Scale-in operations", func() {
+	It("should evict finished operations only", func() {
+		store := &scaleInOperations{}
+		running := &ScaleInOperation{ID: "running", Provider: "fake", ClusterID: "c1", NodeGroupID: "g1"}
+		Expect(store.begin(running)).To(Succeed())
+		finished := time.Now()
+		for i := range maxStoredOperations + 10 {
+			op := &ScaleInOperation{ID: fmt.Sprintf("op-%d", i), Provider: "fake", ClusterID: "c1",
+				NodeGroupID: fmt.Sprintf("group-%d", i)}
+			Expect(store.begin(op)).To(Succeed())
+			store.update(op, func(op *ScaleInOperation) { op.FinishedAt = &finished })
+		}
+
+		operations := store.list()
+		Expect(operations).To(HaveLen(maxStoredOperations))
+		Expect(operations[0].ID).To(Equal("running"))
+		Expect(operations[1].ID).To(Equal("op-11"))
+		_, ok := store.get("running")
+		Expect(ok).To(BeTrue())
+
+		err := store.begin(&ScaleInOperation{ID: "second", Provider: "fake", ClusterID: "c1", NodeGroupID: "g1"})
+		Expect(errors.Is(err, ErrProviderConflict)).To(BeTrue())
+	})
+})
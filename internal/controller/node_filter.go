@@ -0,0 +1,173 @@
+package controller
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+const (
+	// nodeRoleLabelPrefix - префикс меток ролей ноды: node-role.kubernetes.io/<role>
+	nodeRoleLabelPrefix = "node-role.kubernetes.io/"
+	// legacyNodeRoleLabel - устаревшая метка роли kubernetes.io/role=<role>
+	legacyNodeRoleLabel = "kubernetes.io/role"
+	// unassignedNodePool - пул нод без метки группы
+	unassignedNodePool = "unassigned"
+	// defaultExcludeTaints - по умолчанию в утилизации кластера не учитываются ноды с NoSchedule
+	defaultExcludeTaints = ":NoSchedule"
+)
+
+// defaultNodePoolLabels - метки групп нод провайдеров kubedeck и управляемых Kubernetes.
+// Метка TimeWeb берется из той же настройки, что и у провайдера, и пропускается, если не задана.
+var defaultNodePoolLabels = slices.DeleteFunc([]string{
+	yandexNodeGroupLabel,
+	timewebNodeGroupLabel,
+	magnumNodeGroupLabel,
+	FakeNodeGroupLabel,
+	"eks.amazonaws.com/nodegroup",
+	"cloud.google.com/gke-nodepool",
+	"kubernetes.azure.com/agentpool",
+}, func(label string) bool { return label == "" })
+
+// nodeFilterParams - параметры отбора нод и переменные окружения с их значениями по умолчанию
+var nodeFilterParams = []struct{ param, env, def string }{
+	{"nodeSelector", "KUBEDECK_METRICS_NODE_SELECTOR", ""},
+	{"excludeTaints", "KUBEDECK_METRICS_EXCLUDE_TAINTS", defaultExcludeTaints},
+	{"roles", "KUBEDECK_METRICS_NODE_ROLES", ""},
+	{"excludeRoles", "KUBEDECK_METRICS_EXCLUDE_NODE_ROLES", ""},
+	{"nodeGroups", "KUBEDECK_METRICS_NODE_GROUPS", ""},
+	{"excludeNodeGroups", "KUBEDECK_METRICS_EXCLUDE_NODE_GROUPS", ""},
+}
+
+// taintMatcher - taint в формате key[=value][:Effect]; пустые части совпадают с любым значением
+type taintMatcher struct {
+	key, value, effect string
+	hasValue           bool
+}
+
+func (m taintMatcher) matches(taint corev1.Taint) bool {
+	return (m.key == "" || m.key == taint.Key) &&
+		(!m.hasValue || m.value == taint.Value) &&
+		(m.effect == "" || m.effect == string(taint.Effect))
+}
+
+func parseTaintMatcher(spec string) (taintMatcher, error) {
+	var m taintMatcher
+	rest, effect, hasEffect := strings.Cut(spec, ":")
+	if hasEffect {
+		switch corev1.TaintEffect(effect) {
+		case corev1.TaintEffectNoSchedule, corev1.TaintEffectPreferNoSchedule, corev1.TaintEffectNoExecute:
+			m.effect = effect
+		default:
+			return m, fmt.Errorf("invalid taint effect %q in %q", effect, spec)
+		}
+	}
+	m.key, m.value, m.hasValue = strings.Cut(rest, "=")
+	if m.key == "" && m.effect == "" {
+		return m, fmt.Errorf("taint %q must have a key or an effect", spec)
+	}
+	return m, nil
+}
+
+// nodeFilter отбирает ноды, учитываемые в утилизации кластера. Нода учитывается, если подходит
+// под nodeSelector, имеет одну из roles и входит в одну из nodeGroups (пустые условия не проверяются),
+// и при этом не имеет исключенных taint, ролей и групп.
+type nodeFilter struct {
+	selector          labels.Selector
+	excludeTaints     []taintMatcher
+	roles             []string
+	excludeRoles      []string
+	nodeGroups        []string
+	excludeNodeGroups []string
+	poolLabels        []string
+}
+
+// parseNodeFilter разбирает параметры отбора нод из запроса. Отсутствующий параметр берется
+// из переменной окружения; пустой параметр снимает условие (excludeTaints= учитывает все ноды).
+func parseNodeFilter(query url.Values) (*nodeFilter, error) {
+	values := make(map[string]string, len(nodeFilterParams))
+	for _, p := range nodeFilterParams {
+		if query.Has(p.param) {
+			values[p.param] = query.Get(p.param)
+		} else {
+			values[p.param] = getEnvString(p.env, p.def)
+		}
+	}
+
+	selector, err := labels.Parse(values["nodeSelector"])
+	if err != nil {
+		return nil, fmt.Errorf("invalid nodeSelector: %w", err)
+	}
+	filter := &nodeFilter{
+		selector:          selector,
+		roles:             splitList(values["roles"]),
+		excludeRoles:      splitList(values["excludeRoles"]),
+		nodeGroups:        splitList(values["nodeGroups"]),
+		excludeNodeGroups: splitList(values["excludeNodeGroups"]),
+		poolLabels:        getEnvList("KUBEDECK_NODE_POOL_LABELS", defaultNodePoolLabels),
+	}
+	for _, spec := range splitList(values["excludeTaints"]) {
+		matcher, err := parseTaintMatcher(spec)
+		if err != nil {
+			return nil, fmt.Errorf("invalid excludeTaints: %w", err)
+		}
+		filter.excludeTaints = append(filter.excludeTaints, matcher)
+	}
+	return filter, nil
+}
+
+// includes возвращает true, если нода учитывается в утилизации кластера
+func (f *nodeFilter) includes(node corev1.Node) bool {
+	if !f.selector.Matches(labels.Set(node.Labels)) {
+		return false
+	}
+	for _, taint := range node.Spec.Taints {
+		for _, matcher := range f.excludeTaints {
+			if matcher.matches(taint) {
+				return false
+			}
+		}
+	}
+
+	roles := nodeRoles(node)
+	if len(f.roles) > 0 && !slices.ContainsFunc(roles, func(role string) bool { return slices.Contains(f.roles, role) }) {
+		return false
+	}
+	if slices.ContainsFunc(roles, func(role string) bool { return slices.Contains(f.excludeRoles, role) }) {
+		return false
+	}
+
+	pool := f.pool(node)
+	if len(f.nodeGroups) > 0 && !slices.Contains(f.nodeGroups, pool) {
+		return false
+	}
+	return !slices.Contains(f.excludeNodeGroups, pool)
+}
+
+// pool возвращает пул ноды по первой найденной метке группы нод
+func (f *nodeFilter) pool(node corev1.Node) string {
+	for _, label := range f.poolLabels {
+		if value := node.Labels[label]; value != "" {
+			return value
+		}
+	}
+	return unassignedNodePool
+}
+
+// nodeRoles возвращает роли ноды из меток node-role.kubernetes.io/<role> и kubernetes.io/role
+func nodeRoles(node corev1.Node) []string {
+	var roles []string
+	for label := range node.Labels {
+		if role, ok := strings.CutPrefix(label, nodeRoleLabelPrefix); ok && role != "" {
+			roles = append(roles, role)
+		}
+	}
+	if role := node.Labels[legacyNodeRoleLabel]; role != "" && !slices.Contains(roles, role) {
+		roles = append(roles, role)
+	}
+	return roles
+}
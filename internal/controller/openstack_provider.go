@@ -0,0 +1,338 @@
+package controller
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+const (
+	// openstackAPIMagnum - API container-infra OpenStack Magnum
+	openstackAPIMagnum = "magnum"
+	// openstackAPIMKS - API Selectel Managed Kubernetes
+	openstackAPIMKS = "mks"
+
+	// magnumAPIVersion - микроверсия Magnum: группы нод появились в 1.9
+	magnumAPIVersion = "container-infra latest"
+	// magnumNodeGroupLabel - метка, которую Magnum ставит на ноды группы
+	magnumNodeGroupLabel = "magnum.openstack.org/nodegroup"
+	// magnumPageSize - размер страницы списка кластеров Magnum
+	magnumPageSize = 100
+
+	// keystoneTokenMargin - токен Keystone обновляется заранее, чтобы не истек во время запроса
+	keystoneTokenMargin = 5 * time.Minute
+)
+
+// OpenStackProvider работает с managed Kubernetes на OpenStack: Magnum или Selectel MKS.
+// Запросы авторизуются токеном Keystone v3, который выпускается по паролю и обновляется до истечения.
+type OpenStackProvider struct {
+	// name - имя провайдера в ошибках и кластерах (openstack, selectel)
+	name string
+	api  string
+
+	authURL       string
+	username      string
+	password      string
+	userDomain    string
+	projectID     string
+	projectName   string
+	projectDomain string
+	region        string
+	// endpoint - базовый URL API кластеров. Пустое значение - endpoint container-infra из каталога Keystone.
+	endpoint string
+	client   *http.Client
+
+	mu           sync.Mutex
+	token        string
+	tokenExpires time.Time
+	// catalogEndpoint - endpoint из каталога последнего токена
+	catalogEndpoint string
+}
+
+// newOpenStackProviderFromEnv читает учетные данные OpenStack из переменных окружения
+func newOpenStackProviderFromEnv(name, api, defaultAuthURL string) *OpenStackProvider {
+	return &OpenStackProvider{
+		name:          name,
+		api:           api,
+		authURL:       getEnvString("OPENSTACK_AUTH_URL", defaultAuthURL),
+		username:      getEnvString("OPENSTACK_USERNAME", ""),
+		password:      getEnvString("OPENSTACK_PASSWORD", ""),
+		userDomain:    getEnvString("OPENSTACK_USER_DOMAIN_NAME", "Default"),
+		projectID:     getEnvString("OPENSTACK_PROJECT_ID", ""),
+		projectName:   getEnvString("OPENSTACK_PROJECT_NAME", ""),
+		projectDomain: getEnvString("OPENSTACK_PROJECT_DOMAIN_NAME", "Default"),
+		region:        getEnvString("OPENSTACK_REGION", ""),
+		endpoint:      getEnvString("OPENSTACK_ENDPOINT", ""),
+		client:        outboundHTTPClient,
+	}
+}
+
+// NewOpenStackProvider создает провайдер OpenStack Magnum
+func NewOpenStackProvider() *OpenStackProvider {
+	return newOpenStackProviderFromEnv("openstack", openstackAPIMagnum, "")
+}
+
+// NewSelectelProvider создает провайдер Selectel Managed Kubernetes. Endpoint по умолчанию строится по региону.
+func NewSelectelProvider() *OpenStackProvider {
+	p := newOpenStackProviderFromEnv("selectel", openstackAPIMKS, "https://cloud.api.selcloud.ru/identity/v3")
+	if p.region == "" {
+		p.region = "ru-1"
+	}
+	if p.endpoint == "" {
+		p.endpoint = fmt.Sprintf("https://%s.mks.selcloud.ru/v1", p.region)
+	}
+	return p
+}
+
+// authenticate возвращает действующий токен Keystone и endpoint API кластеров
+func (p *OpenStackProvider) authenticate(ctx context.Context) (string, string, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	if p.token != "" && time.Until(p.tokenExpires) > keystoneTokenMargin {
+		return p.token, p.apiEndpoint(), nil
+	}
+
+	project := map[string]interface{}{"id": p.projectID}
+	if p.projectID == "" {
+		project = map[string]interface{}{"name": p.projectName, "domain": map[string]string{"name": p.projectDomain}}
+	}
+	body, _ := json.Marshal(map[string]interface{}{
+		"auth": map[string]interface{}{
+			"identity": map[string]interface{}{
+				"methods": []string{"password"},
+				"password": map[string]interface{}{
+					"user": map[string]interface{}{
+						"name":     p.username,
+						"domain":   map[string]string{"name": p.userDomain},
+						"password": p.password,
+					},
+				},
+			},
+			"scope": map[string]interface{}{"project": project},
+		},
+	})
+
+	req, err := http.NewRequestWithContext(ctx, "POST", p.authURL+"/auth/tokens", bytes.NewReader(body))
+	if err != nil {
+		return "", "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return "", "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 400 {
+		return "", "", newProviderError(p.name, resp)
+	}
+
+	var result struct {
+		Token struct {
+			ExpiresAt time.Time `json:"expires_at"`
+			Catalog   []struct {
+				Type      string `json:"type"`
+				Endpoints []struct {
+					Interface string `json:"interface"`
+					Region    string `json:"region"`
+					URL       string `json:"url"`
+				} `json:"endpoints"`
+			} `json:"catalog"`
+		} `json:"token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return "", "", fmt.Errorf("failed to decode %s token response: %w", p.name, err)
+	}
+	token := resp.Header.Get("X-Subject-Token")
+	if token == "" {
+		return "", "", fmt.Errorf("%s identity API returned no X-Subject-Token", p.name)
+	}
+
+	p.catalogEndpoint = ""
+	for _, service := range result.Token.Catalog {
+		if service.Type != "container-infra" {
+			continue
+		}
+		for _, endpoint := range service.Endpoints {
+			if endpoint.Interface == "public" && (p.region == "" || endpoint.Region == p.region) {
+				p.catalogEndpoint = endpoint.URL
+				break
+			}
+		}
+	}
+
+	p.token = token
+	p.tokenExpires = result.Token.ExpiresAt
+	return p.token, p.apiEndpoint(), nil
+}
+
+// apiEndpoint возвращает заданный endpoint или endpoint из каталога. Вызывается под блокировкой.
+func (p *OpenStackProvider) apiEndpoint() string {
+	if p.endpoint != "" {
+		return p.endpoint
+	}
+	return p.catalogEndpoint
+}
+
+// invalidateToken сбрасывает токен, отозванный до истечения срока
+func (p *OpenStackProvider) invalidateToken(token string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.token == token {
+		p.token = ""
+	}
+}
+
+// do выполняет запрос к API кластеров. Ответ 401 на действующий по времени токен
+// означает, что его отозвали: токен выпускается заново и запрос повторяется один раз.
+func (p *OpenStackProvider) do(ctx context.Context, method, path string, body interface{}, out interface{}) error {
+	var data []byte
+	if body != nil {
+		data, _ = json.Marshal(body)
+	}
+
+	for attempt := 0; ; attempt++ {
+		token, endpoint, err := p.authenticate(ctx)
+		if err != nil {
+			return err
+		}
+		if endpoint == "" {
+			return fmt.Errorf("%w: %s endpoint is not configured and not found in the service catalog", ErrProviderUnavailable, p.name)
+		}
+
+		req, err := http.NewRequestWithContext(ctx, method, endpoint+path, bytes.NewReader(data))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-Auth-Token", token)
+		if p.api == openstackAPIMagnum {
+			req.Header.Set("OpenStack-API-Version", magnumAPIVersion)
+		}
+
+		err = doProviderRequest(p.client, p.name, req, out)
+		if attempt == 0 && errors.Is(err, ErrProviderUnauthorized) {
+			p.invalidateToken(token)
+			continue
+		}
+		return err
+	}
+}
+
+func (p *OpenStackProvider) ListClusters(ctx context.Context) ([]Cluster, error) {
+	if p.api == openstackAPIMKS {
+		var result struct {
+			Clusters []struct {
+				ID     string `json:"id"`
+				Name   string `json:"name"`
+				Status string `json:"status"`
+			} `json:"clusters"`
+		}
+		if err := p.do(ctx, "GET", "/clusters", nil, &result); err != nil {
+			return nil, err
+		}
+
+		clusters := make([]Cluster, 0, len(result.Clusters))
+		for _, c := range result.Clusters {
+			clusters = append(clusters, Cluster{ID: c.ID, Name: c.Name, Status: c.Status, Provider: p.name})
+		}
+		return clusters, nil
+	}
+
+	// Magnum возвращает списки постранично, следующая страница запрашивается с marker последнего кластера
+	clusters := []Cluster{}
+	for marker := ""; ; {
+		query := url.Values{"limit": {fmt.Sprint(magnumPageSize)}}
+		if marker != "" {
+			query.Set("marker", marker)
+		}
+
+		var result struct {
+			Clusters []struct {
+				UUID   string `json:"uuid"`
+				Name   string `json:"name"`
+				Status string `json:"status"`
+			} `json:"clusters"`
+		}
+		if err := p.do(ctx, "GET", "/clusters?"+query.Encode(), nil, &result); err != nil {
+			return nil, err
+		}
+
+		for _, c := range result.Clusters {
+			clusters = append(clusters, Cluster{ID: c.UUID, Name: c.Name, Status: c.Status, Provider: p.name})
+		}
+		if len(result.Clusters) < magnumPageSize {
+			return clusters, nil
+		}
+		marker = result.Clusters[len(result.Clusters)-1].UUID
+	}
+}
+
+func (p *OpenStackProvider) GetNodeGroups(ctx context.Context, clusterID string) ([]NodeGroup, error) {
+	if p.api == openstackAPIMKS {
+		var result struct {
+			NodeGroups []struct {
+				ID    string        `json:"id"`
+				Name  string        `json:"name"`
+				Nodes []interface{} `json:"nodes"`
+			} `json:"nodegroups"`
+		}
+		if err := p.do(ctx, "GET", "/clusters/"+clusterID+"/nodegroups", nil, &result); err != nil {
+			return nil, err
+		}
+
+		groups := make([]NodeGroup, 0, len(result.NodeGroups))
+		for _, g := range result.NodeGroups {
+			// У групп MKS может не быть имени
+			name := g.Name
+			if name == "" {
+				name = g.ID
+			}
+			groups = append(groups, NodeGroup{ID: g.ID, Name: name, NodeCount: len(g.Nodes)})
+		}
+		return groups, nil
+	}
+
+	var result struct {
+		NodeGroups []struct {
+			UUID      string `json:"uuid"`
+			Name      string `json:"name"`
+			Role      string `json:"role"`
+			NodeCount int    `json:"node_count"`
+		} `json:"nodegroups"`
+	}
+	if err := p.do(ctx, "GET", "/clusters/"+clusterID+"/nodegroups", nil, &result); err != nil {
+		return nil, err
+	}
+
+	groups := make([]NodeGroup, 0, len(result.NodeGroups))
+	for _, g := range result.NodeGroups {
+		// Группу control plane Magnum масштабировать не позволяет
+		if g.Role == "master" {
+			continue
+		}
+		groups = append(groups, NodeGroup{
+			ID:         g.UUID,
+			Name:       g.Name,
+			NodeCount:  g.NodeCount,
+			NodeLabels: map[string]string{magnumNodeGroupLabel: g.Name},
+		})
+	}
+	return groups, nil
+}
+
+func (p *OpenStackProvider) ScaleNodeGroup(ctx context.Context, clusterID, groupID string, nodeCount int) error {
+	if p.api == openstackAPIMKS {
+		return p.do(ctx, "POST", "/clusters/"+clusterID+"/nodegroups/"+groupID+"/resize",
+			map[string]interface{}{"nodegroup": map[string]int{"desired": nodeCount}}, nil)
+	}
+	return p.do(ctx, "POST", "/clusters/"+clusterID+"/actions/resize",
+		map[string]interface{}{"node_count": nodeCount, "nodegroup": groupID}, nil)
+}
@@ -0,0 +1,163 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+// openstackStandIn - httptest-замена Keystone и API кластеров
+type openstackStandIn struct {
+	*httptest.Server
+	tokens  int
+	revoked bool
+	resized map[string]interface{}
+	api     http.HandlerFunc
+}
+
+func newOpenStackStandIn(api http.HandlerFunc) *openstackStandIn {
+	s := &openstackStandIn{api: api}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, req *http.Request) {
+		if req.URL.Path == "/identity/v3/auth/tokens" {
+			var body struct {
+				Auth struct {
+					Identity struct {
+						Password struct {
+							User struct {
+								Name     string `json:"name"`
+								Password string `json:"password"`
+							} `json:"user"`
+						} `json:"password"`
+					} `json:"identity"`
+				} `json:"auth"`
+			}
+			Expect(json.NewDecoder(req.Body).Decode(&body)).To(Succeed())
+			if body.Auth.Identity.Password.User.Password != "secret" {
+				w.WriteHeader(http.StatusUnauthorized)
+				_, _ = w.Write([]byte(`{"error":{"code":401,"message":"The request you have made requires authentication.","title":"Unauthorized"}}`))
+				return
+			}
+			s.tokens++
+			s.revoked = false
+			w.Header().Set("X-Subject-Token", fmt.Sprintf("token-%d", s.tokens))
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{"token": map[string]interface{}{
+				"expires_at": time.Now().Add(time.Hour),
+				"catalog": []map[string]interface{}{{
+					"type":      "container-infra",
+					"endpoints": []map[string]string{{"interface": "public", "region": "ru-1", "url": s.URL + "/magnum/v1"}},
+				}},
+			}})
+			return
+		}
+
+		if s.revoked || req.Header.Get("X-Auth-Token") != fmt.Sprintf("token-%d", s.tokens) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if req.Method == http.MethodPost {
+			Expect(json.NewDecoder(req.Body).Decode(&s.resized)).To(Succeed())
+			w.WriteHeader(http.StatusAccepted)
+			return
+		}
+		s.api(w, req)
+	}))
+	return s
+}
+
+func (s *openstackStandIn) provider(api string) *OpenStackProvider {
+	return &OpenStackProvider{
+		name: "openstack", api: api, authURL: s.URL + "/identity/v3",
+		username: "robot", password: "secret", userDomain: "Default", projectID: "project", region: "ru-1",
+		client: s.Client(),
+	}
+}
+
+var _ = Describe("OpenStack provider", func() {
+	It("should list and resize Magnum node groups through the catalog endpoint", func() {
+		standIn := newOpenStackStandIn(func(w http.ResponseWriter, req *http.Request) {
+			Expect(req.Header.Get("OpenStack-API-Version")).To(Equal(magnumAPIVersion))
+			switch req.URL.Path {
+			case "/magnum/v1/clusters":
+				var clusters []map[string]string
+				if req.URL.Query().Get("marker") == "" {
+					for i := range magnumPageSize {
+						clusters = append(clusters, map[string]string{"uuid": fmt.Sprintf("c-%d", i), "name": "k8s", "status": "CREATE_COMPLETE"})
+					}
+				} else {
+					clusters = append(clusters, map[string]string{"uuid": "c-last", "status": "UPDATE_IN_PROGRESS"})
+				}
+				_ = json.NewEncoder(w).Encode(map[string]interface{}{"clusters": clusters})
+			case "/magnum/v1/clusters/c-1/nodegroups":
+				_, _ = w.Write([]byte(`{"nodegroups":[{"uuid":"ng-master","name":"default-master","role":"master","node_count":3},
+					{"uuid":"ng-worker","name":"default-worker","role":"worker","node_count":2}]}`))
+			default:
+				http.NotFound(w, req)
+			}
+		})
+		defer standIn.Close()
+
+		provider := standIn.provider(openstackAPIMagnum)
+		clusters, err := provider.ListClusters(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(clusters).To(HaveLen(magnumPageSize + 1))
+		Expect(clusters[magnumPageSize].ID).To(Equal("c-last"))
+
+		groups, err := provider.GetNodeGroups(ctx, "c-1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(groups).To(Equal([]NodeGroup{{ID: "ng-worker", Name: "default-worker", NodeCount: 2,
+			NodeLabels: map[string]string{magnumNodeGroupLabel: "default-worker"}}}))
+
+		Expect(provider.ScaleNodeGroup(ctx, "c-1", "ng-worker", 4)).To(Succeed())
+		Expect(standIn.resized).To(Equal(map[string]interface{}{"node_count": float64(4), "nodegroup": "ng-worker"}))
+
+		// Токен переиспользуется между запросами
+		Expect(standIn.tokens).To(Equal(1))
+	})
+
+	It("should resize Selectel MKS node groups and reissue revoked tokens", func() {
+		standIn := newOpenStackStandIn(func(w http.ResponseWriter, req *http.Request) {
+			switch req.URL.Path {
+			case "/mks/v1/clusters":
+				_, _ = w.Write([]byte(`{"clusters":[{"id":"c-1","name":"prod","status":"ACTIVE"}]}`))
+			case "/mks/v1/clusters/c-1/nodegroups":
+				_, _ = w.Write([]byte(`{"nodegroups":[{"id":"ng-1","nodes":[{"id":"n-1"},{"id":"n-2"},{"id":"n-3"}]}]}`))
+			default:
+				http.NotFound(w, req)
+			}
+		})
+		defer standIn.Close()
+
+		provider := standIn.provider(openstackAPIMKS)
+		provider.endpoint = standIn.URL + "/mks/v1"
+
+		clusters, err := provider.ListClusters(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(clusters).To(Equal([]Cluster{{ID: "c-1", Name: "prod", Status: "ACTIVE", Provider: "openstack"}}))
+
+		standIn.revoked = true
+		groups, err := provider.GetNodeGroups(ctx, "c-1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(groups).To(Equal([]NodeGroup{{ID: "ng-1", Name: "ng-1", NodeCount: 3}}))
+		Expect(standIn.tokens).To(Equal(2))
+
+		Expect(provider.ScaleNodeGroup(ctx, "c-1", "ng-1", 5)).To(Succeed())
+		Expect(standIn.resized).To(Equal(map[string]interface{}{"nodegroup": map[string]interface{}{"desired": float64(5)}}))
+	})
+
+	It("should report rejected credentials as unauthorized", func() {
+		standIn := newOpenStackStandIn(func(w http.ResponseWriter, req *http.Request) {})
+		defer standIn.Close()
+
+		provider := standIn.provider(openstackAPIMagnum)
+		provider.password = "wrong"
+		_, err := provider.ListClusters(ctx)
+		Expect(providerErrorStatus(err)).To(Equal(http.StatusUnauthorized))
+		Expect(err).To(MatchError(ContainSubstring("requires authentication")))
+	})
+})
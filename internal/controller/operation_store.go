@@ -0,0 +1,67 @@
+package controller
+
+import "sync"
+
+// maxStoredOperations - сколько операций хранится для API; незавершенные операции не вытесняются
+const maxStoredOperations = 100
+
+// storedOperation - длительная операция, отдаваемая через API
+type storedOperation interface {
+	operationID() string
+	finished() bool
+}
+
+// operationStore хранит операции в памяти. Сверх maxStoredOperations вытесняются завершенные операции,
+// начиная с самых старых: идущие остаются видимыми в API, пока не закончатся.
+type operationStore[T storedOperation] struct {
+	sync.RWMutex
+	operations []*T
+}
+
+func (s *operationStore[T]) add(op *T) {
+	s.Lock()
+	defer s.Unlock()
+	s.appendLocked(op)
+}
+
+// appendLocked добавляет операцию и вытесняет лишние завершенные; вызывается под блокировкой
+func (s *operationStore[T]) appendLocked(op *T) {
+	s.operations = append(s.operations, op)
+	for i := 0; len(s.operations) > maxStoredOperations && i < len(s.operations); {
+		if !(*s.operations[i]).finished() {
+			i++
+			continue
+		}
+		s.operations = append(s.operations[:i], s.operations[i+1:]...)
+	}
+}
+
+// update изменяет операцию под блокировкой
+func (s *operationStore[T]) update(op *T, fn func(op *T)) {
+	s.Lock()
+	defer s.Unlock()
+	fn(op)
+}
+
+// list возвращает копии операций
+func (s *operationStore[T]) list() []T {
+	s.RLock()
+	defer s.RUnlock()
+	result := make([]T, 0, len(s.operations))
+	for _, op := range s.operations {
+		result = append(result, *op)
+	}
+	return result
+}
+
+func (s *operationStore[T]) get(id string) (T, bool) {
+	s.RLock()
+	defer s.RUnlock()
+	for _, op := range s.operations {
+		if (*op).operationID() == id {
+			return *op, true
+		}
+	}
+	var empty T
+	return empty, false
+}
@@ -0,0 +1,184 @@
+package controller
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Typed provider errors. Use errors.Is to check the kind of a *ProviderError.
+var (
+	ErrProviderUnauthorized = errors.New("provider rejected credentials")
+	ErrProviderNotFound     = errors.New("provider resource not found")
+	ErrProviderRateLimited  = errors.New("provider rate limit exceeded")
+	ErrProviderConflict     = errors.New("provider resource conflict")
+	ErrProviderUnavailable  = errors.New("provider is unavailable")
+	ErrProviderBadRequest   = errors.New("provider rejected request")
+)
+
+// maxProviderErrorBody limits how much of an error response body is kept in the message
+const maxProviderErrorBody = 4096
+
+// ProviderError describes a non-successful HTTP response from a cloud provider API
+type ProviderError struct {
+	Provider   string
+	StatusCode int
+	Message    string
+	// RetryAfter is set from the Retry-After header of rate-limited responses
+	RetryAfter time.Duration
+	kind       error
+}
+
+func (e *ProviderError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("%s API returned status %d", e.Provider, e.StatusCode)
+	}
+	return fmt.Sprintf("%s API returned status %d: %s", e.Provider, e.StatusCode, e.Message)
+}
+
+func (e *ProviderError) Unwrap() error {
+	return e.kind
+}
+
+// newProviderError builds a *ProviderError from an unsuccessful response.
+// The caller remains responsible for closing resp.Body.
+func newProviderError(provider string, resp *http.Response) error {
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxProviderErrorBody))
+
+	perr := &ProviderError{
+		Provider:   provider,
+		StatusCode: resp.StatusCode,
+		Message:    providerErrorMessage(body),
+		RetryAfter: parseRetryAfter(resp.Header.Get("Retry-After")),
+	}
+
+	switch {
+	case resp.StatusCode == http.StatusUnauthorized || resp.StatusCode == http.StatusForbidden:
+		perr.kind = ErrProviderUnauthorized
+	case resp.StatusCode == http.StatusNotFound:
+		perr.kind = ErrProviderNotFound
+	case resp.StatusCode == http.StatusTooManyRequests:
+		perr.kind = ErrProviderRateLimited
+	case resp.StatusCode == http.StatusConflict:
+		perr.kind = ErrProviderConflict
+	case resp.StatusCode >= 500:
+		perr.kind = ErrProviderUnavailable
+	default:
+		perr.kind = ErrProviderBadRequest
+	}
+
+	return perr
+}
+
+// providerErrorMessage extracts a human readable message from an error body.
+// TimeWeb and Yandex Cloud both return JSON objects with a "message" field,
+// Keystone nests it in "error" and Magnum returns a list of "errors" with "detail".
+func providerErrorMessage(body []byte) string {
+	var parsed struct {
+		Message json.RawMessage `json:"message"`
+		Error   struct {
+			Message string `json:"message"`
+		} `json:"error"`
+		Errors []struct {
+			Detail string `json:"detail"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(body, &parsed); err == nil {
+		if parsed.Error.Message != "" {
+			return parsed.Error.Message
+		}
+		if len(parsed.Errors) > 0 && parsed.Errors[0].Detail != "" {
+			return parsed.Errors[0].Detail
+		}
+	}
+	if len(parsed.Message) > 0 {
+		var msg string
+		if err := json.Unmarshal(parsed.Message, &msg); err == nil {
+			return msg
+		}
+		// TimeWeb sometimes returns a list of messages
+		var msgs []string
+		if err := json.Unmarshal(parsed.Message, &msgs); err == nil {
+			return strings.Join(msgs, "; ")
+		}
+	}
+	return strings.TrimSpace(string(body))
+}
+
+// parseRetryAfter parses a Retry-After header given either in seconds or as an HTTP date
+func parseRetryAfter(value string) time.Duration {
+	if value == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		if d := time.Until(t); d > 0 {
+			return d
+		}
+	}
+	return 0
+}
+
+// doProviderRequest executes req and decodes a successful JSON response into out.
+// A *[]byte out receives the raw body. Responses with status >= 400 are returned as *ProviderError.
+// The outcome of every call is counted in kubedeck_provider_requests_total.
+func doProviderRequest(httpClient *http.Client, provider string, req *http.Request, out interface{}) (err error) {
+	defer func() {
+		providerRequestsTotal.WithLabelValues(provider, providerOutcome(err)).Inc()
+	}()
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 400 {
+		return newProviderError(provider, resp)
+	}
+
+	if out == nil {
+		return nil
+	}
+	if raw, ok := out.(*[]byte); ok {
+		body, err := io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read %s response: %w", provider, err)
+		}
+		*raw = body
+		return nil
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("failed to decode %s response: %w", provider, err)
+	}
+	return nil
+}
+
+// providerErrorStatus maps a provider error to the HTTP status returned by the /cloud/* handlers
+func providerErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, ErrProviderUnauthorized):
+		return http.StatusUnauthorized
+	case errors.Is(err, ErrProviderNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, ErrProviderRateLimited):
+		return http.StatusTooManyRequests
+	case errors.Is(err, ErrProviderConflict):
+		return http.StatusConflict
+	case errors.Is(err, ErrProviderBadRequest):
+		return http.StatusBadRequest
+	case errors.Is(err, ErrProviderUnavailable):
+		return http.StatusBadGateway
+	case errors.Is(err, ErrCircuitOpen):
+		return http.StatusServiceUnavailable
+	default:
+		return http.StatusInternalServerError
+	}
+}
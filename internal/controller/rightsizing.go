@@ -0,0 +1,555 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+)
+
+// Источники рекомендаций ресурсов для /analyze/resources и Telegram
+const (
+	recommenderLLM         = "llm"
+	recommenderRightsizing = "rightsizing"
+)
+
+const (
+	mebibyte = 1024 * 1024
+	// Нижние границы рекомендаций, как у VPA: меньшие значения не дают планировщику смысла
+	minRecommendedCPUMilli = 10
+	minRecommendedMemoryMi = 16
+	// oomBumpRatio и oomBumpMi увеличивают лимит памяти контейнера после OOMKilled
+	oomBumpRatio = 1.2
+	oomBumpMi    = 100
+	// throttleBumpRatio увеличивает лимит CPU контейнера, упирающегося в лимит
+	throttleBumpRatio = 1.25
+	// nearLimitRatio - доля лимита, при которой контейнер считается упирающимся в лимит
+	nearLimitRatio = 0.9
+)
+
+// Причины рекомендации контейнера в ContainerRecommendation.Reasons
+const (
+	reasonOOMKilled             = "oom_killed"
+	reasonCPUThrottled          = "cpu_throttled"
+	reasonMemoryNearLimit       = "memory_near_limit"
+	reasonCPUUnderRequested     = "cpu_under_requested"
+	reasonMemoryUnderRequested  = "memory_under_requested"
+	reasonCPUOverProvisioned    = "cpu_over_provisioned"
+	reasonMemoryOverProvisioned = "memory_over_provisioned"
+	reasonMissingRequests       = "missing_requests"
+	reasonMissingLimits         = "missing_limits"
+)
+
+// criticalReasons - причины, при которых рекомендация получает статус critical
+var criticalReasons = map[string]bool{
+	reasonOOMKilled:            true,
+	reasonCPUThrottled:         true,
+	reasonMemoryNearLimit:      true,
+	reasonCPUUnderRequested:    true,
+	reasonMemoryUnderRequested: true,
+}
+
+// ContainerRecommendation - рекомендация встроенного рекомендателя для контейнера.
+// CPU в милликорах, память в Mi; -1 - значение менять не нужно.
+type ContainerRecommendation struct {
+	Name                 string   `json:"name"`
+	CPURequest           int      `json:"cpuRequest"`
+	MemoryRequest        int      `json:"memoryRequest"`
+	CPULimit             int      `json:"cpuLimit"`
+	MemoryLimit          int      `json:"memoryLimit"`
+	CurrentCPURequest    int      `json:"currentCpuRequest"`
+	CurrentMemoryRequest int      `json:"currentMemoryRequest"`
+	CurrentCPULimit      int      `json:"currentCpuLimit"`
+	CurrentMemoryLimit   int      `json:"currentMemoryLimit"`
+	CPUUsage             string   `json:"cpuUsage"`
+	MemoryUsage          string   `json:"memoryUsage"`
+	CPUPeak              string   `json:"cpuPeak"`
+	MemoryPeak           string   `json:"memoryPeak"`
+	Samples              int      `json:"samples"`
+	Reasons              []string `json:"reasons,omitempty"`
+}
+
+// rightsizingSettings - параметры встроенного рекомендателя
+type rightsizingSettings struct {
+	// window - за какой период берется история использования
+	window time.Duration
+	// Перцентили использования для запросов CPU и памяти (0-100)
+	cpuPercentile, memoryPercentile float64
+	// margin - запас сверху к перцентилям и пикам
+	margin float64
+	// tolerance - относительное изменение, ниже которого значение не меняется
+	tolerance float64
+	// minSamples - минимум точек истории контейнера для рекомендации
+	minSamples int
+}
+
+func rightsizingSettingsFromEnv() rightsizingSettings {
+	return rightsizingSettings{
+		window:           getEnvDuration("KUBEDECK_RIGHTSIZING_WINDOW", 7*24*time.Hour),
+		cpuPercentile:    getEnvFloat("KUBEDECK_RIGHTSIZING_CPU_PERCENTILE", 90),
+		memoryPercentile: getEnvFloat("KUBEDECK_RIGHTSIZING_MEMORY_PERCENTILE", 95),
+		margin:           getEnvFloat("KUBEDECK_RIGHTSIZING_MARGIN", 0.15),
+		tolerance:        getEnvFloat("KUBEDECK_RIGHTSIZING_TOLERANCE", 0.2),
+		minSamples:       int(getEnvFloat("KUBEDECK_RIGHTSIZING_MIN_SAMPLES", 60)),
+	}
+}
+
+// containerUsage - точки истории контейнера по всем подам рабочей нагрузки
+type containerUsage struct {
+	cpu, cpuMax, memory, memoryMax []float64
+}
+
+// workloadUsage - рабочая нагрузка с текущими подами и историей использования
+type workloadUsage struct {
+	namespace, kind, name string
+	pods                  []corev1.Pod
+	containers            map[string]*containerUsage
+	// Суммарное использование всех подов на каждый момент истории: CPU в ядрах, память в байтах
+	totals map[int64]*[2]float64
+}
+
+// percentile возвращает перцентиль p (0-100) методом ближайшего ранга
+func percentile(values []float64, p float64) float64 {
+	if len(values) == 0 {
+		return 0
+	}
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	return sorted[min(max(rank, 1), len(sorted))-1]
+}
+
+// roundUp округляет вверх до целого, не считая погрешность вычислений с плавающей точкой
+func roundUp(value float64) int {
+	return int(math.Ceil(math.Round(value*1000) / 1000))
+}
+
+// resourceRecommendations возвращает рекомендации ресурсов от выбранного источника.
+// narrative поручает LLM только текст message для результата встроенного рекомендателя.
+func (r *KubedeckReconciler) resourceRecommendations(ctx context.Context, engine string, narrative bool) (*ResourceRecommendationResponse, error) {
+	if engine == recommenderRightsizing {
+		recommendation, err := r.rightsizingRecommendations(ctx, rightsizingSettingsFromEnv(), time.Now())
+		if err != nil || !narrative {
+			return recommendation, err
+		}
+		if message, err := requestLLMNarrative(ctx, recommendation); err != nil {
+			webServerLog.Error(err, "Failed to get LLM narrative, keeping the built-in message")
+		} else {
+			recommendation.Message = message
+		}
+		return recommendation, nil
+	}
+
+	podResourceData, err := r.collectPodResourceData(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to collect pod resource data: %w", err)
+	}
+	return r.getLLMResourceRecommendations(ctx, podResourceData)
+}
+
+// rightsizingRecommendations рассчитывает запросы, лимиты и реплики рабочих нагрузок по истории использования
+// без обращения к LLM. Результат детерминирован для одной и той же истории и одних и тех же подов.
+func (r *KubedeckReconciler) rightsizingRecommendations(ctx context.Context, settings rightsizingSettings, now time.Time) (*ResourceRecommendationResponse, error) {
+	if r.history == nil {
+		return nil, errors.New("metrics history is disabled, rightsizing needs KUBEDECK_METRICS_HISTORY")
+	}
+
+	pods, err := r.podsSnapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+	replicaSets, err := r.replicaSetsByName(ctx, corev1.NamespaceAll)
+	if err != nil {
+		return nil, err
+	}
+
+	workloads, err := r.workloadUsage(pods, replicaSets, now.Add(-settings.window), now)
+	if err != nil {
+		return nil, err
+	}
+	return settings.recommend(workloads), nil
+}
+
+// workloadUsage группирует работающие поды по рабочим нагрузкам и собирает историю их контейнеров.
+// История подов, которых уже нет, относится к нагрузке по префиксу имени пода.
+func (r *KubedeckReconciler) workloadUsage(pods []corev1.Pod, replicaSets map[string]appsv1.ReplicaSet, from, to time.Time) ([]*workloadUsage, error) {
+	type workloadKey struct{ namespace, kind, name string }
+	workloads := map[workloadKey]*workloadUsage{}
+	byPod := map[string]*workloadUsage{}
+	byNamespace := map[string][]*workloadUsage{}
+	for _, pod := range pods {
+		if pod.Status.Phase != corev1.PodRunning {
+			continue
+		}
+		kind, name := workloadOf(pod, replicaSets)
+		key := workloadKey{pod.Namespace, kind, name}
+		w, ok := workloads[key]
+		if !ok {
+			w = &workloadUsage{namespace: pod.Namespace, kind: kind, name: name,
+				containers: map[string]*containerUsage{}, totals: map[int64]*[2]float64{}}
+			workloads[key] = w
+			byNamespace[pod.Namespace] = append(byNamespace[pod.Namespace], w)
+		}
+		w.pods = append(w.pods, pod)
+		byPod[pod.Namespace+"/"+pod.Name] = w
+	}
+
+	// ownerOf находит нагрузку пода: текущий под или самое длинное имя нагрузки, с которого начинается имя пода
+	ownerOf := func(namespace, pod string) *workloadUsage {
+		if w, ok := byPod[namespace+"/"+pod]; ok {
+			return w
+		}
+		var owner *workloadUsage
+		for _, w := range byNamespace[namespace] {
+			if w.kind != "Pod" && strings.HasPrefix(pod, w.name+"-") && (owner == nil || len(w.name) > len(owner.name)) {
+				owner = w
+			}
+		}
+		return owner
+	}
+
+	tier := r.history.selectTier(from, 0, to)
+	prefix := historyKindContainer + "/"
+	err := r.history.scan(tier, from, to, func(key string) bool { return strings.HasPrefix(key, prefix) },
+		func(key string, p HistoryPoint) {
+			parts := strings.SplitN(strings.TrimPrefix(key, prefix), "/", 3)
+			if len(parts) != 3 {
+				return
+			}
+			w := ownerOf(parts[0], parts[1])
+			if w == nil {
+				return
+			}
+			usage, ok := w.containers[parts[2]]
+			if !ok {
+				usage = &containerUsage{}
+				w.containers[parts[2]] = usage
+			}
+			usage.cpu = append(usage.cpu, p.CPU)
+			usage.cpuMax = append(usage.cpuMax, p.CPUMax)
+			usage.memory = append(usage.memory, p.Memory)
+			usage.memoryMax = append(usage.memoryMax, p.MemoryMax)
+
+			total, ok := w.totals[p.Time.Unix()]
+			if !ok {
+				total = &[2]float64{}
+				w.totals[p.Time.Unix()] = total
+			}
+			total[0] += p.CPU
+			total[1] += p.Memory
+		})
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]*workloadUsage, 0, len(workloads))
+	for _, w := range workloads {
+		result = append(result, w)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if a.namespace != b.namespace {
+			return a.namespace < b.namespace
+		}
+		if a.kind != b.kind {
+			return a.kind < b.kind
+		}
+		return a.name < b.name
+	})
+	return result, nil
+}
+
+// recommend рассчитывает рекомендации нагрузок. В ответ попадают только нагрузки, требующие изменений.
+func (s rightsizingSettings) recommend(workloads []*workloadUsage) *ResourceRecommendationResponse {
+	response := &ResourceRecommendationResponse{Namespaces: map[string][]PodResourceInfo{}}
+	var lines []string
+	critical, warning := 0, 0
+	for _, w := range workloads {
+		info, ok := s.recommendWorkload(w)
+		if !ok {
+			continue
+		}
+		response.Namespaces[w.namespace] = append(response.Namespaces[w.namespace], info)
+		if info.Status == "critical" {
+			critical++
+		} else {
+			warning++
+		}
+		lines = append(lines, workloadSummary(w, info))
+	}
+
+	response.Message = fmt.Sprintf("Встроенный анализ истории использования за %s: изменений требуют %d нагрузок "+
+		"(критических: %d, предупреждений: %d).", s.window, critical+warning, critical, warning)
+	if len(lines) > 0 {
+		response.Message += " " + strings.Join(lines, " ")
+	}
+	return response
+}
+
+// recommendWorkload рассчитывает рекомендацию нагрузки. Значения пода относятся к первому контейнеру,
+// который обновляет /updatefromfront, рекомендации всех контейнеров - в Containers.
+func (s rightsizingSettings) recommendWorkload(w *workloadUsage) (PodResourceInfo, bool) {
+	spec := w.pods[0].Spec
+	info := PodResourceInfo{
+		Name:          w.pods[0].Name,
+		ResourceName:  w.name,
+		ResourceType:  w.kind,
+		CPURequest:    -1,
+		MemoryRequest: -1,
+		CPULimit:      -1,
+		MemoryLimit:   -1,
+		Replicas:      -1,
+	}
+
+	var cpuUsage, memoryUsage, cpuLimits, memoryLimits float64
+	var recommendedCPU, recommendedMemory float64
+	allRecommended := true
+	for i, container := range spec.Containers {
+		usage := w.containers[container.Name]
+		if usage == nil || len(usage.cpu) < s.minSamples {
+			allRecommended = false
+			continue
+		}
+		recommendation, cpuRequest, memoryRequest := s.recommendContainer(container, usage, w.pods)
+		recommendedCPU += cpuRequest
+		recommendedMemory += memoryRequest
+		cpuUsage += percentile(usage.cpu, s.cpuPercentile)
+		memoryUsage += percentile(usage.memory, s.memoryPercentile)
+		cpuLimits += container.Resources.Limits.Cpu().AsApproximateFloat64()
+		memoryLimits += container.Resources.Limits.Memory().AsApproximateFloat64()
+
+		if len(recommendation.Reasons) == 0 {
+			continue
+		}
+		info.Containers = append(info.Containers, recommendation)
+		if i == 0 {
+			info.CPURequest = recommendation.CPURequest
+			info.MemoryRequest = recommendation.MemoryRequest
+			info.CPULimit = recommendation.CPULimit
+			info.MemoryLimit = recommendation.MemoryLimit
+		}
+		for _, reason := range recommendation.Reasons {
+			if criticalReasons[reason] {
+				info.Status = "critical"
+			}
+		}
+	}
+
+	if allRecommended && (w.kind == "Deployment" || w.kind == "StatefulSet") {
+		if replicas := s.recommendReplicas(w, recommendedCPU, recommendedMemory); replicas != len(w.pods) {
+			info.Replicas = replicas
+		}
+	}
+	if len(info.Containers) == 0 && info.Replicas < 0 {
+		return info, false
+	}
+	if info.Status == "" {
+		info.Status = "warning"
+	}
+
+	info.CPUUsage = resource.NewMilliQuantity(int64(math.Round(cpuUsage*1000)), resource.DecimalSI).String()
+	info.MemoryUsage = resource.NewQuantity(int64(memoryUsage), resource.BinarySI).String()
+	if cpuLimits > 0 {
+		info.CPUPercentage = cpuUsage / cpuLimits * 100
+	}
+	if memoryLimits > 0 {
+		info.MemPercentage = memoryUsage / memoryLimits * 100
+	}
+	return info, true
+}
+
+// recommendContainer рассчитывает запросы и лимиты контейнера. Возвращает также рекомендуемые запросы
+// CPU в ядрах и памяти в байтах для расчета реплик.
+func (s rightsizingSettings) recommendContainer(container corev1.Container, usage *containerUsage, pods []corev1.Pod) (ContainerRecommendation, float64, float64) {
+	requests, limits := container.Resources.Requests, container.Resources.Limits
+	rec := ContainerRecommendation{
+		Name:                 container.Name,
+		CurrentCPURequest:    int(requests.Cpu().MilliValue()),
+		CurrentMemoryRequest: int(requests.Memory().Value() / mebibyte),
+		CurrentCPULimit:      int(limits.Cpu().MilliValue()),
+		CurrentMemoryLimit:   int(limits.Memory().Value() / mebibyte),
+		Samples:              len(usage.cpu),
+	}
+
+	cpu := percentile(usage.cpu, s.cpuPercentile)
+	memory := percentile(usage.memory, s.memoryPercentile)
+	cpuPeak := percentile(usage.cpuMax, 99)
+	memoryPeak := percentile(usage.memoryMax, 100)
+	rec.CPUUsage = resource.NewMilliQuantity(int64(math.Round(cpu*1000)), resource.DecimalSI).String()
+	rec.MemoryUsage = resource.NewQuantity(int64(memory), resource.BinarySI).String()
+	rec.CPUPeak = resource.NewMilliQuantity(int64(math.Round(cpuPeak*1000)), resource.DecimalSI).String()
+	rec.MemoryPeak = resource.NewQuantity(int64(memoryPeak), resource.BinarySI).String()
+
+	cpuRequest := max(roundUp(cpu*(1+s.margin)*1000), minRecommendedCPUMilli)
+	memoryRequest := max(roundUp(memory*(1+s.margin)/mebibyte), minRecommendedMemoryMi)
+	cpuLimit := max(roundUp(cpuPeak*(1+s.margin)*1000), cpuRequest)
+	memoryLimit := max(roundUp(memoryPeak*(1+s.margin)/mebibyte), memoryRequest)
+
+	var reasons []string
+	_, hasCPULimit := limits[corev1.ResourceCPU]
+	_, hasMemoryLimit := limits[corev1.ResourceMemory]
+
+	// Контейнер, убитый по памяти, получает лимит больше прежнего, а запрос не уменьшается
+	if containerOOMKilled(container.Name, pods) {
+		reasons = append(reasons, reasonOOMKilled)
+		if hasMemoryLimit {
+			memoryLimit = max(memoryLimit, roundUp(float64(rec.CurrentMemoryLimit)*oomBumpRatio),
+				rec.CurrentMemoryLimit+oomBumpMi)
+		}
+		memoryRequest = max(memoryRequest, rec.CurrentMemoryRequest)
+	} else if hasMemoryLimit && memoryPeak >= nearLimitRatio*limits.Memory().AsApproximateFloat64() {
+		reasons = append(reasons, reasonMemoryNearLimit)
+	}
+	// Пики CPU у лимита означают троттлинг
+	if hasCPULimit && percentile(usage.cpuMax, 95) >= nearLimitRatio*limits.Cpu().AsApproximateFloat64() {
+		reasons = append(reasons, reasonCPUThrottled)
+		cpuLimit = max(cpuLimit, roundUp(float64(rec.CurrentCPULimit)*throttleBumpRatio))
+	}
+
+	reasons = append(reasons, s.requestReasons(rec.CurrentCPURequest, cpuRequest, reasonCPUUnderRequested, reasonCPUOverProvisioned)...)
+	reasons = append(reasons, s.requestReasons(rec.CurrentMemoryRequest, memoryRequest, reasonMemoryUnderRequested, reasonMemoryOverProvisioned)...)
+	if requests.Cpu().IsZero() || requests.Memory().IsZero() {
+		reasons = append(reasons, reasonMissingRequests)
+	}
+	if !hasCPULimit || !hasMemoryLimit {
+		reasons = append(reasons, reasonMissingLimits)
+	}
+	rec.Reasons = reasons
+
+	rec.CPURequest = s.change(rec.CurrentCPURequest, cpuRequest)
+	rec.MemoryRequest = s.change(rec.CurrentMemoryRequest, memoryRequest)
+	rec.CPULimit = s.change(rec.CurrentCPULimit, cpuLimit)
+	rec.MemoryLimit = s.change(rec.CurrentMemoryLimit, memoryLimit)
+	return rec, float64(cpuRequest) / 1000, float64(memoryRequest) * mebibyte
+}
+
+// requestReasons сравнивает текущий запрос с рекомендуемым с учетом tolerance
+func (s rightsizingSettings) requestReasons(current, recommended int, under, over string) []string {
+	switch {
+	case current == 0:
+		return nil
+	case float64(recommended) > float64(current)*(1+s.tolerance):
+		return []string{under}
+	case float64(recommended) < float64(current)*(1-s.tolerance):
+		return []string{over}
+	}
+	return nil
+}
+
+// change возвращает рекомендуемое значение или -1, если оно отличается от текущего не больше чем на tolerance
+func (s rightsizingSettings) change(current, recommended int) int {
+	if current > 0 && math.Abs(float64(recommended-current)) <= float64(current)*s.tolerance {
+		return -1
+	}
+	return recommended
+}
+
+// recommendReplicas возвращает число реплик, которые с рекомендуемыми запросами обслуживают
+// 99-й перцентиль суммарного использования нагрузки. Нагрузка из нескольких реплик не сокращается до одной.
+func (s rightsizingSettings) recommendReplicas(w *workloadUsage, podCPU, podMemory float64) int {
+	current := len(w.pods)
+	if len(w.totals) < s.minSamples || podCPU <= 0 || podMemory <= 0 {
+		return current
+	}
+	var cpu, memory []float64
+	for _, total := range w.totals {
+		cpu = append(cpu, total[0])
+		memory = append(memory, total[1])
+	}
+	replicas := max(int(math.Ceil(percentile(cpu, 99)/podCPU)), int(math.Ceil(percentile(memory, 99)/podMemory)), 1)
+	if current > 1 {
+		replicas = max(replicas, 2)
+	}
+	return replicas
+}
+
+// containerOOMKilled возвращает true, если контейнер хотя бы одного пода завершался по OOMKilled
+func containerOOMKilled(name string, pods []corev1.Pod) bool {
+	for _, pod := range pods {
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.Name != name {
+				continue
+			}
+			for _, terminated := range []*corev1.ContainerStateTerminated{status.State.Terminated, status.LastTerminationState.Terminated} {
+				if terminated != nil && terminated.Reason == "OOMKilled" {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// workloadSummary описывает рекомендацию нагрузки одним предложением для message
+func workloadSummary(w *workloadUsage, info PodResourceInfo) string {
+	var changes []string
+	for _, c := range info.Containers {
+		var parts []string
+		for _, field := range []struct {
+			name            string
+			current, target int
+			unit            string
+		}{
+			{"CPU request", c.CurrentCPURequest, c.CPURequest, "m"},
+			{"CPU limit", c.CurrentCPULimit, c.CPULimit, "m"},
+			{"Memory request", c.CurrentMemoryRequest, c.MemoryRequest, "Mi"},
+			{"Memory limit", c.CurrentMemoryLimit, c.MemoryLimit, "Mi"},
+		} {
+			if field.target >= 0 {
+				parts = append(parts, fmt.Sprintf("%s %d%s -> %d%s", field.name, field.current, field.unit, field.target, field.unit))
+			}
+		}
+		if len(parts) > 0 {
+			changes = append(changes, fmt.Sprintf("контейнер %s (CPU %s, память %s): %s", c.Name, c.CPUUsage, c.MemoryUsage,
+				strings.Join(parts, ", ")))
+		}
+	}
+	if info.Replicas >= 0 {
+		changes = append(changes, fmt.Sprintf("реплик %d -> %d", len(w.pods), info.Replicas))
+	}
+	return fmt.Sprintf("%s %s/%s: %s.", w.kind, w.namespace, w.name, strings.Join(changes, "; "))
+}
+
+// requestLLMNarrative просит LLM описать готовые рекомендации. Числа рекомендаций LLM не меняет.
+func requestLLMNarrative(ctx context.Context, recommendation *ResourceRecommendationResponse) (string, error) {
+	data, err := json.Marshal(recommendation.Namespaces)
+	if err != nil {
+		return "", err
+	}
+	prompt := "Ниже рекомендации по ресурсам подов Kubernetes, рассчитанные по перцентилям истории использования. " +
+		"Кратко опишите их для инженеров: какие нагрузки и почему требуют изменений, начиная с критических. " +
+		"Не меняйте и не добавляйте числа. Ответьте только текстом на русском языке в профессиональном техническом стиле.\n\n" +
+		string(data)
+	return callLLM(ctx, prompt)
+}
+
+// recommenderParams возвращает источник рекомендаций и признак narrative из параметров engine и narrative
+// или из KUBEDECK_RECOMMENDER и KUBEDECK_RIGHTSIZING_NARRATIVE
+func recommenderParams(req *http.Request) (string, bool, error) {
+	engine := getEnvString("KUBEDECK_RECOMMENDER", recommenderLLM)
+	if value := req.URL.Query().Get("engine"); value != "" {
+		engine = value
+	}
+	if engine != recommenderLLM && engine != recommenderRightsizing {
+		return "", false, fmt.Errorf("engine must be %s or %s", recommenderLLM, recommenderRightsizing)
+	}
+
+	narrative := getEnvBool("KUBEDECK_RIGHTSIZING_NARRATIVE", false)
+	if value := req.URL.Query().Get("narrative"); value != "" {
+		var err error
+		if narrative, err = strconv.ParseBool(value); err != nil {
+			return "", false, fmt.Errorf("narrative must be true or false")
+		}
+	}
+	return engine, narrative, nil
+}
@@ -0,0 +1,155 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Rightsizing", func() {
+	settings := rightsizingSettings{window: 24 * time.Hour, cpuPercentile: 90, memoryPercentile: 95,
+		margin: 0.15, tolerance: 0.2, minSamples: 3}
+
+	container := func(name, cpuRequest, memRequest, cpuLimit, memLimit string) corev1.Container {
+		return corev1.Container{Name: name, Resources: corev1.ResourceRequirements{
+			Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(cpuRequest), corev1.ResourceMemory: resource.MustParse(memRequest)},
+			Limits:   corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(cpuLimit), corev1.ResourceMemory: resource.MustParse(memLimit)},
+		}}
+	}
+	runningPod := func(name string, containers ...corev1.Container) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name},
+			Spec:       corev1.PodSpec{Containers: containers},
+			Status:     corev1.PodStatus{Phase: corev1.PodRunning},
+		}
+	}
+	// constant возвращает n одинаковых точек использования
+	constant := func(n int, cpu, memoryMi float64) *containerUsage {
+		usage := &containerUsage{}
+		for range n {
+			usage.cpu = append(usage.cpu, cpu)
+			usage.cpuMax = append(usage.cpuMax, cpu)
+			usage.memory = append(usage.memory, memoryMi*mebibyte)
+			usage.memoryMax = append(usage.memoryMax, memoryMi*mebibyte)
+		}
+		return usage
+	}
+
+	It("should compute nearest-rank percentiles", func() {
+		values := []float64{5, 1, 4, 2, 3, 6, 7, 8, 9, 10}
+		Expect(percentile(values, 90)).To(Equal(9.0))
+		Expect(percentile(values, 100)).To(Equal(10.0))
+		Expect(percentile(values, 0)).To(Equal(1.0))
+		Expect(percentile(nil, 50)).To(BeZero())
+	})
+
+	It("should shrink over-provisioned containers and keep replicas that fit the load", func() {
+		app := container("app", "1", "512Mi", "2", "1Gi")
+		w := &workloadUsage{namespace: "default", kind: "Deployment", name: "web",
+			pods:       []corev1.Pod{runningPod("web-1-a", app), runningPod("web-1-b", app)},
+			containers: map[string]*containerUsage{"app": constant(5, 0.2, 200)},
+			totals:     map[int64]*[2]float64{},
+		}
+		for i := range 5 {
+			w.totals[int64(i)] = &[2]float64{0.4, 400 * mebibyte}
+		}
+
+		info, ok := settings.recommendWorkload(w)
+		Expect(ok).To(BeTrue())
+		Expect(info.Status).To(Equal("warning"))
+		Expect(info.ResourceName).To(Equal("web"))
+		Expect(info.CPURequest).To(Equal(230))
+		Expect(info.MemoryRequest).To(Equal(230))
+		Expect(info.CPULimit).To(Equal(230))
+		Expect(info.MemoryLimit).To(Equal(230))
+		Expect(info.Replicas).To(Equal(-1))
+		Expect(info.Containers[0].Reasons).To(ConsistOf(reasonCPUOverProvisioned, reasonMemoryOverProvisioned))
+		Expect(info.CPUUsage).To(Equal("200m"))
+		Expect(info.CPUPercentage).To(BeNumerically("~", 10, 1e-9))
+
+		// Те же поды с запросами по использованию не требуют изменений
+		fitted := container("app", "230m", "230Mi", "230m", "230Mi")
+		w.pods = []corev1.Pod{runningPod("web-1-a", fitted), runningPod("web-1-b", fitted)}
+		w.containers["app"] = constant(5, 0.19, 190)
+		_, ok = settings.recommendWorkload(w)
+		Expect(ok).To(BeFalse())
+	})
+
+	It("should raise limits after OOMKilled and when CPU is throttled", func() {
+		worker := container("worker", "100m", "200Mi", "200m", "256Mi")
+		pod := runningPod("worker-0", worker)
+		pod.Status.ContainerStatuses = []corev1.ContainerStatus{{Name: "worker", LastTerminationState: corev1.ContainerState{
+			Terminated: &corev1.ContainerStateTerminated{Reason: "OOMKilled", ExitCode: 137},
+		}}}
+		w := &workloadUsage{namespace: "default", kind: "StatefulSet", name: "worker", pods: []corev1.Pod{pod},
+			containers: map[string]*containerUsage{"worker": constant(5, 0.195, 100)}, totals: map[int64]*[2]float64{}}
+
+		info, ok := settings.recommendWorkload(w)
+		Expect(ok).To(BeTrue())
+		Expect(info.Status).To(Equal("critical"))
+		recommendation := info.Containers[0]
+		Expect(recommendation.Reasons).To(ContainElements(reasonOOMKilled, reasonCPUThrottled, reasonCPUUnderRequested))
+		// Лимит памяти: max(256Mi * 1.2, 256Mi + 100Mi); запрос памяти не уменьшается после OOM
+		Expect(recommendation.MemoryLimit).To(Equal(356))
+		Expect(recommendation.MemoryRequest).To(Equal(-1))
+		// Лимит CPU: 200m * 1.25
+		Expect(recommendation.CPULimit).To(Equal(250))
+		Expect(recommendation.CPURequest).To(Equal(225))
+		// Истории подов недостаточно для реплик
+		Expect(info.Replicas).To(Equal(-1))
+	})
+
+	It("should attribute history of replaced pods to the workload by name prefix", func() {
+		dir, err := os.MkdirTemp("", "kubedeck-rightsizing")
+		Expect(err).NotTo(HaveOccurred())
+		DeferCleanup(os.RemoveAll, dir)
+		history, err := newMetricsHistory(dir, []historyTier{
+			{Name: "raw", Resolution: time.Minute, Retention: 24 * time.Hour, block: time.Hour},
+		})
+		Expect(err).NotTo(HaveOccurred())
+
+		now := time.Now().Truncate(time.Minute)
+		point := HistoryPoint{CPU: 0.1, CPUMax: 0.1, Memory: 64 * mebibyte, MemoryMax: 64 * mebibyte}
+		for i := range 3 {
+			Expect(history.append(now.Add(time.Duration(i-10)*time.Minute), map[string]HistoryPoint{
+				historyKey(historyKindContainer, "default/web-5d8f-old/app"):   point,
+				historyKey(historyKindContainer, "default/web-api-7c9d-x/app"): point,
+				historyKey(historyKindContainer, "default/gone-1/app"):         point,
+			})).To(Succeed())
+		}
+
+		isController := true
+		owned := func(name, owner string) corev1.Pod {
+			pod := runningPod(name, container("app", "100m", "64Mi", "100m", "64Mi"))
+			pod.OwnerReferences = []metav1.OwnerReference{{Kind: "StatefulSet", Name: owner, Controller: &isController}}
+			return pod
+		}
+		reconciler := &KubedeckReconciler{history: history}
+		workloads, err := reconciler.workloadUsage([]corev1.Pod{owned("web-0", "web"), owned("web-api-0", "web-api")},
+			nil, now.Add(-time.Hour), now)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(workloads).To(HaveLen(2))
+		Expect(workloads[0].name).To(Equal("web"))
+		Expect(workloads[0].containers["app"].cpu).To(HaveLen(3))
+		Expect(workloads[1].name).To(Equal("web-api"))
+		Expect(workloads[1].containers["app"].cpu).To(HaveLen(3))
+		Expect(workloads[1].totals).To(HaveLen(3))
+	})
+
+	It("should validate the engine and reject rightsizing without history", func() {
+		reconciler := &KubedeckReconciler{}
+		for query, status := range map[string]int{"engine=oracle": http.StatusBadRequest, "narrative=maybe": http.StatusBadRequest,
+			"engine=rightsizing": http.StatusInternalServerError} {
+			recorder := httptest.NewRecorder()
+			reconciler.handleResourceAnalysisRequest(recorder, httptest.NewRequest(http.MethodGet, "/analyze/resources?"+query, nil))
+			Expect(recorder.Code).To(Equal(status), query)
+		}
+	})
+})
@@ -0,0 +1,326 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/robfig/cron/v3"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrlv1 "nikcorp.ru/kubedeck/api/v1"
+	logf "sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	// scheduleStartingDeadline - запуск, пропущенный дольше этого времени (например, при рестарте), не выполняется
+	scheduleStartingDeadline = 5 * time.Minute
+
+	// maxMissedSchedules ограничивает перебор пропущенных запусков
+	maxMissedSchedules = 1000
+)
+
+var scheduleParser = cron.NewParser(cron.Minute | cron.Hour | cron.Dom | cron.Month | cron.Dow | cron.Descriptor)
+
+// parseScalingSchedule разбирает cron-выражение и часовой пояс расписания
+func parseScalingSchedule(schedule ctrlv1.ScalingSchedule) (cron.Schedule, *time.Location, error) {
+	location := time.UTC
+	if schedule.TimeZone != "" {
+		loc, err := time.LoadLocation(schedule.TimeZone)
+		if err != nil {
+			return nil, nil, fmt.Errorf("invalid time zone %q: %w", schedule.TimeZone, err)
+		}
+		location = loc
+	}
+
+	sched, err := scheduleParser.Parse(schedule.Schedule)
+	if err != nil {
+		return nil, nil, fmt.Errorf("invalid schedule %q: %w", schedule.Schedule, err)
+	}
+
+	if scheduleAction(schedule) == ctrlv1.ScalingScheduleActionScale && schedule.NodeCount == nil {
+		return nil, nil, fmt.Errorf("nodeCount is required for the Scale action")
+	}
+	return sched, location, nil
+}
+
+// scheduleAction возвращает действие расписания с учетом значения по умолчанию
+func scheduleAction(schedule ctrlv1.ScalingSchedule) ctrlv1.ScalingScheduleAction {
+	if schedule.Action == "" {
+		return ctrlv1.ScalingScheduleActionScale
+	}
+	return schedule.Action
+}
+
+// lastScheduledTime возвращает последний запуск расписания в интервале (since, now] или нулевое время
+func lastScheduledTime(sched cron.Schedule, location *time.Location, since, now time.Time) time.Time {
+	var last time.Time
+	for t, i := sched.Next(since.In(location)), 0; !t.After(now) && i < maxMissedSchedules; t, i = sched.Next(t), i+1 {
+		last = t
+	}
+	return last
+}
+
+// scheduledRun - наступивший запуск расписания. Запуск записывается в статус до изменения группы нод,
+// поэтому повторная обработка объекта (например, после конфликта обновления) не масштабирует группу еще раз.
+type scheduledRun struct {
+	schedule  ctrlv1.ScalingSchedule
+	scheduled time.Time
+	provider  ClusterProvider
+	current   int
+	target    int
+	// done - запуск завершился при планировании, result и err содержат его результат
+	done   bool
+	result string
+	err    error
+	// scaled - запись ScaledNodeGroups, добавленная (Scale) или удаленная (Restore) запуском, для отката при ошибке
+	scaled *ctrlv1.ScaledNodeGroup
+}
+
+// reconcileScalingSchedules отмечает в статусе наступившие расписания и возвращает их запуски
+// вместе со временем до следующего запуска. Возвращает true, если статус изменился.
+func (r *KubedeckReconciler) reconcileScalingSchedules(ctx context.Context, kubedeck *ctrlv1.Kubedeck, now time.Time) (time.Duration, []scheduledRun, bool) {
+	log := logf.FromContext(ctx)
+
+	var requeueAfter time.Duration
+	var runs []scheduledRun
+	changed := false
+
+	for _, schedule := range kubedeck.Spec.ScalingSchedules {
+		status := findScheduleStatus(&kubedeck.Status, schedule.Name)
+
+		sched, location, err := parseScalingSchedule(schedule)
+		if err != nil {
+			if status.LastError != err.Error() {
+				status.LastError = err.Error()
+				changed = true
+			}
+			continue
+		}
+		if schedule.Suspend {
+			continue
+		}
+
+		since := kubedeck.CreationTimestamp.Time
+		if status.LastScheduleTime != nil {
+			since = status.LastScheduleTime.Time
+		}
+
+		if scheduled := lastScheduledTime(sched, location, since, now); !scheduled.IsZero() {
+			status.LastScheduleTime = &metav1.Time{Time: scheduled}
+			status.LastResult, status.LastError = "", ""
+			changed = true
+
+			if now.Sub(scheduled) > scheduleStartingDeadline {
+				status.LastResult = "skipped: missed starting deadline"
+				log.Info("Scaling schedule missed its starting deadline", "schedule", schedule.Name, "scheduledAt", scheduled)
+			} else {
+				run := r.planScheduledRun(ctx, kubedeck, schedule, scheduled, now)
+				if run.done {
+					status.LastResult = run.result
+					if run.err != nil {
+						status.LastError = run.err.Error()
+					}
+				} else {
+					status.LastResult = fmt.Sprintf("scaling %d -> %d", run.current, run.target)
+				}
+				runs = append(runs, run)
+			}
+		}
+
+		next := sched.Next(now.In(location)).Sub(now)
+		if requeueAfter == 0 || next < requeueAfter {
+			requeueAfter = next
+		}
+	}
+
+	// Удаляем статусы расписаний, которых больше нет в spec
+	schedules := kubedeck.Status.Schedules[:0]
+	for _, status := range kubedeck.Status.Schedules {
+		for _, schedule := range kubedeck.Spec.ScalingSchedules {
+			if schedule.Name == status.Name {
+				schedules = append(schedules, status)
+				break
+			}
+		}
+	}
+	if len(schedules) != len(kubedeck.Status.Schedules) {
+		changed = true
+	}
+	kubedeck.Status.Schedules = schedules
+
+	return requeueAfter, runs, changed
+}
+
+// planScheduledRun определяет целевой размер группы и сразу обновляет ScaledNodeGroups:
+// исходный размер запоминается для Restore, а Restore забирает свою запись.
+func (r *KubedeckReconciler) planScheduledRun(ctx context.Context, kubedeck *ctrlv1.Kubedeck, schedule ctrlv1.ScalingSchedule,
+	scheduled, now time.Time) scheduledRun {
+	run := scheduledRun{schedule: schedule, scheduled: scheduled}
+	finish := func(result string, err error) scheduledRun {
+		run.done, run.result, run.err = true, result, err
+		return run
+	}
+
+	ref := schedule.NodeGroup
+	provider, _, err := r.getClusterProvider(ref.Provider)
+	if err != nil {
+		return finish("failed", err)
+	}
+
+	current, err := nodeGroupSize(ctx, provider, ref)
+	if err != nil {
+		return finish("failed", err)
+	}
+	run.provider, run.current = provider, current
+
+	groups := kubedeck.Status.ScaledNodeGroups
+	scaled := findScaledNodeGroup(groups, ref)
+
+	switch scheduleAction(schedule) {
+	case ctrlv1.ScalingScheduleActionRestore:
+		if scaled < 0 {
+			return finish("nothing to restore", nil)
+		}
+		entry := groups[scaled]
+		run.target = int(entry.PreviousNodeCount)
+		run.scaled = &entry
+		kubedeck.Status.ScaledNodeGroups = append(groups[:scaled], groups[scaled+1:]...)
+	default:
+		run.target = int(*schedule.NodeCount)
+		// Запоминаем только первый размер, чтобы цепочка расписаний (3 -> 1 -> 0) восстанавливалась до 3
+		if scaled < 0 {
+			entry := ctrlv1.ScaledNodeGroup{
+				NodeGroupReference: ref,
+				PreviousNodeCount:  int32(current),
+				Schedule:           schedule.Name,
+				ScaledAt:           metav1.Time{Time: now},
+			}
+			run.scaled = &entry
+			kubedeck.Status.ScaledNodeGroups = append(groups, entry)
+		}
+	}
+
+	if run.target == current {
+		return finish(fmt.Sprintf("scaled %d -> %d", current, current), nil)
+	}
+	return run
+}
+
+// runScheduledScaling изменяет размер группы нод по запуску, уже сохраненному в статусе
+func (r *KubedeckReconciler) runScheduledScaling(ctx context.Context, run scheduledRun) (string, error) {
+	if run.done {
+		return run.result, run.err
+	}
+
+	ref := run.schedule.NodeGroup
+	change := ScaleChange{Provider: ref.Provider, ClusterID: ref.ClusterID, NodeGroupID: ref.NodeGroupID, Current: run.current, Target: run.target}
+	if err := r.checkAutomatedScaling(ctx, run.provider, change); err != nil {
+		return "blocked by guardrails", err
+	}
+
+	// Уменьшение у провайдеров с NodeDeleter идет через drain выбранных нод, как у автоскейлера
+	if _, ok := run.provider.(NodeDeleter); ok && run.target < run.current {
+		group, err := findNodeGroup(ctx, run.provider, ref)
+		if err != nil {
+			return "failed", err
+		}
+		groupCtx, err := r.nodeGroupContext(ctx, ref.Provider, ref.ClusterID)
+		if err != nil {
+			return "failed", err
+		}
+		op, err := r.resizeNodeGroup(groupCtx, ref.Provider, run.provider, ref.ClusterID, *group, run.target)
+		if err != nil {
+			return "failed", err
+		}
+		if op != nil {
+			return fmt.Sprintf("scaling in %d -> %d (operation %s)", run.current, run.target, op.ID), nil
+		}
+		return fmt.Sprintf("scaled %d -> %d", run.current, run.target), nil
+	}
+
+	if err := run.provider.ScaleNodeGroup(ctx, ref.ClusterID, ref.NodeGroupID, run.target); err != nil {
+		return "failed", err
+	}
+	return fmt.Sprintf("scaled %d -> %d", run.current, run.target), nil
+}
+
+// applyScheduleResult записывает результат запуска в статус расписания. Если группа не изменилась,
+// изменение ScaledNodeGroups откатывается. Возвращает false, если запуск в статусе уже другой.
+func applyScheduleResult(kubedeck *ctrlv1.Kubedeck, run scheduledRun, result string, err error) bool {
+	status := findScheduleStatus(&kubedeck.Status, run.schedule.Name)
+	if status.LastScheduleTime == nil || !status.LastScheduleTime.Time.Equal(run.scheduled) {
+		return false
+	}
+
+	status.LastResult, status.LastError = result, ""
+	if err == nil {
+		return true
+	}
+	status.LastError = err.Error()
+
+	if run.scaled != nil {
+		groups := kubedeck.Status.ScaledNodeGroups
+		scaled := findScaledNodeGroup(groups, run.scaled.NodeGroupReference)
+		switch {
+		case scheduleAction(run.schedule) == ctrlv1.ScalingScheduleActionRestore && scaled < 0:
+			kubedeck.Status.ScaledNodeGroups = append(groups, *run.scaled)
+		case scheduleAction(run.schedule) != ctrlv1.ScalingScheduleActionRestore && scaled >= 0 &&
+			groups[scaled].Schedule == run.scaled.Schedule && groups[scaled].ScaledAt.Unix() == run.scaled.ScaledAt.Unix():
+			kubedeck.Status.ScaledNodeGroups = append(groups[:scaled], groups[scaled+1:]...)
+		}
+	}
+	return true
+}
+
+// nodeGroupSize возвращает текущий размер группы нод у провайдера
+func nodeGroupSize(ctx context.Context, provider ClusterProvider, ref ctrlv1.NodeGroupReference) (int, error) {
+	group, err := findNodeGroup(ctx, provider, ref)
+	if err != nil {
+		return 0, err
+	}
+	return group.NodeCount, nil
+}
+
+// findNodeGroup возвращает группу нод провайдера по ссылке
+func findNodeGroup(ctx context.Context, provider ClusterProvider, ref ctrlv1.NodeGroupReference) (*NodeGroup, error) {
+	groups, err := provider.GetNodeGroups(ctx, ref.ClusterID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range groups {
+		if groups[i].ID == ref.NodeGroupID {
+			return &groups[i], nil
+		}
+	}
+	return nil, fmt.Errorf("%w: node group %s not found in cluster %s", ErrProviderNotFound, ref.NodeGroupID, ref.ClusterID)
+}
+
+// notifyScheduledScaling отправляет в Telegram результат планового масштабирования
+func (r *KubedeckReconciler) notifyScheduledScaling(schedule ctrlv1.ScalingSchedule, result string, err error) {
+	ref := schedule.NodeGroup
+	message := fmt.Sprintf("🕒 *Плановое масштабирование* `%s`\nГруппа нод: `%s/%s/%s`\nДействие: %s\nРезультат: %s",
+		schedule.Name, ref.Provider, ref.ClusterID, ref.NodeGroupID, scheduleAction(schedule), result)
+	if err != nil {
+		message += fmt.Sprintf("\n❌ Ошибка: `%s`", err.Error())
+	}
+	r.broadcastTelegramMessage(message)
+}
+
+func findScheduleStatus(status *ctrlv1.KubedeckStatus, name string) *ctrlv1.ScalingScheduleStatus {
+	for i := range status.Schedules {
+		if status.Schedules[i].Name == name {
+			return &status.Schedules[i]
+		}
+	}
+	status.Schedules = append(status.Schedules, ctrlv1.ScalingScheduleStatus{Name: name})
+	return &status.Schedules[len(status.Schedules)-1]
+}
+
+func findScaledNodeGroup(groups []ctrlv1.ScaledNodeGroup, ref ctrlv1.NodeGroupReference) int {
+	for i := range groups {
+		if groups[i].NodeGroupReference == ref {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,139 @@
+package controller
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrlv1 "nikcorp.ru/kubedeck/api/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var _ = Describe("Scaling schedules", func() {
+	It("should scale down at night, restore in the morning and skip missed runs", func() {
+		provider := NewFakeProvider(FakeProviderOptions{ScaleDelay: time.Millisecond})
+		groupNode := func(name string) *corev1.Node {
+			return &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: name, Labels: map[string]string{FakeNodeGroupLabel: "fake-ng-general"}}}
+		}
+		reconciler := &KubedeckReconciler{
+			Client:    fake.NewClientBuilder().WithObjects(groupNode("fake-general-0"), groupNode("fake-general-1")).Build(),
+			providers: map[string]ClusterProvider{"fake": provider},
+		}
+
+		ref := ctrlv1.NodeGroupReference{Provider: "fake", ClusterID: "fake-cluster-1", NodeGroupID: "fake-ng-general"}
+		zero := int32(0)
+		kubedeck := &ctrlv1.Kubedeck{
+			ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.NewTime(time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC))},
+			Spec: ctrlv1.KubedeckSpec{ScalingSchedules: []ctrlv1.ScalingSchedule{
+				{Name: "night", Schedule: "0 22 * * *", TimeZone: "Europe/Moscow", NodeGroup: ref, NodeCount: &zero},
+				{Name: "morning", Schedule: "0 8 * * *", TimeZone: "Europe/Moscow", NodeGroup: ref,
+					Action: ctrlv1.ScalingScheduleActionRestore},
+			}},
+		}
+		groupSize := func() int {
+			size, err := nodeGroupSize(ctx, provider, ref)
+			Expect(err).NotTo(HaveOccurred())
+			return size
+		}
+		// reconcile повторяет Reconcile без API сервера: запуски отмечаются в статусе, затем выполняются
+		reconcile := func(now time.Time) (time.Duration, bool) {
+			requeue, runs, changed := reconciler.reconcileScalingSchedules(ctx, kubedeck, now)
+			for _, run := range runs {
+				result, err := reconciler.runScheduledScaling(ctx, run)
+				Expect(applyScheduleResult(kubedeck, run, result, err)).To(BeTrue())
+			}
+			return requeue, changed
+		}
+
+		By("running the night schedule at 22:00 MSK")
+		requeue, changed := reconcile(time.Date(2026, 1, 5, 19, 1, 0, 0, time.UTC))
+		Expect(changed).To(BeTrue())
+		Expect(requeue).To(Equal(9*time.Hour + 59*time.Minute))
+		Expect(kubedeck.Status.ScaledNodeGroups).To(HaveLen(1))
+		Expect(kubedeck.Status.ScaledNodeGroups[0].PreviousNodeCount).To(BeEquivalentTo(2))
+
+		By("draining the nodes before the provider removes them")
+		operations := reconciler.scaleIns.list()
+		Expect(operations).To(HaveLen(1))
+		Expect(operations[0].Nodes).To(ConsistOf("fake-general-0", "fake-general-1"))
+		Expect(kubedeck.Status.Schedules[0].LastResult).To(Equal(
+			fmt.Sprintf("scaling in 2 -> 0 (operation %s)", operations[0].ID)))
+		Eventually(func() string {
+			op, _ := reconciler.scaleIns.get(operations[0].ID)
+			return op.Phase
+		}).Should(Equal(scaleInPhaseSucceeded))
+		node := &corev1.Node{}
+		Expect(reconciler.Client.Get(ctx, client.ObjectKey{Name: "fake-general-0"}, node)).To(Succeed())
+		Expect(node.Spec.Unschedulable).To(BeTrue())
+		Eventually(groupSize).Should(Equal(0))
+
+		By("restoring the previous size at 08:00 MSK")
+		_, changed = reconcile(time.Date(2026, 1, 6, 5, 0, 30, 0, time.UTC))
+		Expect(changed).To(BeTrue())
+		Expect(kubedeck.Status.Schedules[1].LastResult).To(Equal("scaled 0 -> 2"))
+		Expect(kubedeck.Status.ScaledNodeGroups).To(BeEmpty())
+		Eventually(groupSize).Should(Equal(2))
+
+		By("skipping a run missed by more than the starting deadline")
+		_, changed = reconcile(time.Date(2026, 1, 6, 19, 30, 0, 0, time.UTC))
+		Expect(changed).To(BeTrue())
+		Expect(kubedeck.Status.Schedules[0].LastResult).To(ContainSubstring("skipped"))
+		Expect(groupSize()).To(Equal(2))
+	})
+
+	It("should report invalid schedules in status", func() {
+		kubedeck := &ctrlv1.Kubedeck{Spec: ctrlv1.KubedeckSpec{ScalingSchedules: []ctrlv1.ScalingSchedule{
+			{Name: "broken", Schedule: "every night", NodeGroup: ctrlv1.NodeGroupReference{Provider: "fake"}},
+		}}}
+		_, runs, changed := (&KubedeckReconciler{}).reconcileScalingSchedules(ctx, kubedeck, time.Now())
+		Expect(changed).To(BeTrue())
+		Expect(runs).To(BeEmpty())
+		Expect(kubedeck.Status.Schedules[0].LastError).To(ContainSubstring("invalid schedule"))
+	})
+
+	It("should record a run before scaling and roll the status back when scaling fails", func() {
+		provider := NewFakeProvider(FakeProviderOptions{ScaleDelay: time.Millisecond})
+		reconciler := &KubedeckReconciler{providers: map[string]ClusterProvider{"fake": provider}}
+
+		ref := ctrlv1.NodeGroupReference{Provider: "fake", ClusterID: "fake-cluster-1", NodeGroupID: "fake-ng-general"}
+		one := int32(1)
+		kubedeck := &ctrlv1.Kubedeck{
+			ObjectMeta: metav1.ObjectMeta{CreationTimestamp: metav1.NewTime(time.Date(2026, 1, 5, 12, 0, 0, 0, time.UTC))},
+			Spec: ctrlv1.KubedeckSpec{ScalingSchedules: []ctrlv1.ScalingSchedule{
+				{Name: "night", Schedule: "0 22 * * *", NodeGroup: ref, NodeCount: &one},
+				{Name: "morning", Schedule: "0 8 * * *", NodeGroup: ref, Action: ctrlv1.ScalingScheduleActionRestore},
+			}},
+		}
+
+		now := time.Date(2026, 1, 5, 22, 0, 30, 0, time.UTC)
+		_, runs, changed := reconciler.reconcileScalingSchedules(ctx, kubedeck, now)
+		Expect(changed).To(BeTrue())
+		Expect(runs).To(HaveLen(1))
+		Expect(kubedeck.Status.Schedules[0].LastResult).To(Equal("scaling 2 -> 1"))
+		Expect(kubedeck.Status.ScaledNodeGroups).To(HaveLen(1))
+
+		By("not firing again when the object is processed once more, e.g. after a status conflict")
+		_, again, changed := reconciler.reconcileScalingSchedules(ctx, kubedeck.DeepCopy(), now)
+		Expect(again).To(BeEmpty())
+		Expect(changed).To(BeFalse())
+
+		By("dropping the remembered size when the group was not scaled")
+		Expect(applyScheduleResult(kubedeck, runs[0], "failed", errors.New("quota exceeded"))).To(BeTrue())
+		Expect(kubedeck.Status.Schedules[0].LastError).To(Equal("quota exceeded"))
+		Expect(kubedeck.Status.ScaledNodeGroups).To(BeEmpty())
+
+		By("keeping the remembered size when a restore fails")
+		kubedeck.Status.ScaledNodeGroups = []ctrlv1.ScaledNodeGroup{{NodeGroupReference: ref, PreviousNodeCount: 3, Schedule: "night"}}
+		_, runs, _ = reconciler.reconcileScalingSchedules(ctx, kubedeck, time.Date(2026, 1, 6, 8, 0, 30, 0, time.UTC))
+		Expect(runs).To(HaveLen(1))
+		Expect(kubedeck.Status.ScaledNodeGroups).To(BeEmpty())
+		Expect(applyScheduleResult(kubedeck, runs[0], "failed", errors.New("quota exceeded"))).To(BeTrue())
+		Expect(kubedeck.Status.ScaledNodeGroups).To(HaveLen(1))
+		Expect(kubedeck.Status.ScaledNodeGroups[0].PreviousNodeCount).To(BeEquivalentTo(3))
+	})
+})
@@ -0,0 +1,597 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/cluster"
+)
+
+const (
+	// localClusterName - кластер, в котором запущен kubedeck
+	localClusterName = "local"
+
+	// allClusters - значение параметра cluster для запроса ко всем кластерам
+	allClusters = "all"
+
+	// targetClusterTTL - через сколько kubeconfig кластера запрашивается у провайдера заново
+	// (например, чтобы подхватить обновленный IAM токен Yandex Cloud)
+	targetClusterTTL = time.Hour
+
+	// clusterSecretLabel - метка Secret с kubeconfig кластера, значение метки - имя кластера
+	clusterSecretLabel = "kubedeck.nikcorp.ru/cluster"
+
+	// clusterProbeTimeout - таймаут проверки доступности кластера
+	clusterProbeTimeout = 5 * time.Second
+
+	// fanOutTimeout - таймаут запроса к одному кластеру при запросе ко всем кластерам
+	fanOutTimeout = 15 * time.Second
+
+	// kubeconfigFetchTimeout - таймаут получения kubeconfig у провайдера, общий для ожидающих его запросов
+	kubeconfigFetchTimeout = time.Minute
+
+	targetClusterSourceProvider = "provider"
+	targetClusterSourceSecret   = "secret"
+)
+
+// clusterSyncInterval - период чтения Secret с kubeconfig и проверки доступности кластеров
+var clusterSyncInterval = getEnvDuration("KUBEDECK_CLUSTER_SYNC_INTERVAL", 30*time.Second)
+
+// KubeconfigProvider - провайдер, выдающий kubeconfig своих кластеров
+type KubeconfigProvider interface {
+	GetKubeconfig(ctx context.Context, clusterID string) ([]byte, error)
+}
+
+// TargetCluster - кластер, с которым kubedeck работает по kubeconfig провайдера или Secret
+type TargetCluster struct {
+	// Name - имя кластера в параметре cluster: "<provider>/<clusterID>" или имя из метки Secret
+	Name string `json:"name"`
+	// Source - откуда получен kubeconfig: provider или secret
+	Source    string    `json:"source"`
+	Provider  string    `json:"provider,omitempty"`
+	ClusterID string    `json:"cluster_id,omitempty"`
+	Secret    string    `json:"secret,omitempty"`
+	Server    string    `json:"server"`
+	FetchedAt time.Time `json:"fetched_at"`
+
+	// Результат последней проверки доступности
+	Healthy   bool       `json:"healthy"`
+	Version   string     `json:"version,omitempty"`
+	LastProbe *time.Time `json:"last_probe,omitempty"`
+	Error     string     `json:"error,omitempty"`
+
+	config *rest.Config
+	client client.Client
+	// Клиенты логов и metrics-server создаются один раз при регистрации
+	clientset kubernetes.Interface
+	metrics   MetricsBackend
+	// resourceVersion Secret, из которого создан клиент; stop останавливает кеш кластера
+	resourceVersion string
+	stop            context.CancelFunc
+}
+
+// unhealthy возвращает true, если последняя проверка кластера завершилась ошибкой
+func (c *TargetCluster) unhealthy() bool {
+	return c.LastProbe != nil && !c.Healthy
+}
+
+// targetClusterName возвращает имя кластера провайдера для параметра cluster
+func targetClusterName(provider, clusterID string) string {
+	return provider + "/" + clusterID
+}
+
+// targetClusters - реестр кластеров: кластеры провайдеров подключаются по первому запросу,
+// кластеры из Secret - фоновой синхронизацией
+type targetClusters struct {
+	sync.Mutex
+	clusters map[string]*TargetCluster
+	// fetches объединяет одновременные запросы kubeconfig одного кластера
+	fetches singleflight.Group
+}
+
+// list возвращает копии зарегистрированных кластеров, отсортированные по имени
+func (t *targetClusters) list() []TargetCluster {
+	t.Lock()
+	defer t.Unlock()
+	result := make([]TargetCluster, 0, len(t.clusters))
+	for _, cluster := range t.clusters {
+		result = append(result, *cluster)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result
+}
+
+// get возвращает кластер по имени
+func (t *targetClusters) get(name string) *TargetCluster {
+	t.Lock()
+	defer t.Unlock()
+	return t.clusters[name]
+}
+
+// cached возвращает кластер из Secret или кластер провайдера, kubeconfig которого еще не устарел
+func (t *targetClusters) cached(name string) *TargetCluster {
+	t.Lock()
+	defer t.Unlock()
+	cluster, ok := t.clusters[name]
+	if ok && (cluster.Source == targetClusterSourceSecret || time.Since(cluster.FetchedAt) < targetClusterTTL) {
+		return cluster
+	}
+	return nil
+}
+
+// store регистрирует кластер провайдера, если пока запрашивался kubeconfig, его не заменили
+// кластером из Secret или более свежим kubeconfig. Возвращает зарегистрированный кластер.
+func (t *targetClusters) store(cluster *TargetCluster) *TargetCluster {
+	t.Lock()
+	defer t.Unlock()
+	old, ok := t.clusters[cluster.Name]
+	if ok && (old.Source == targetClusterSourceSecret || old.FetchedAt.After(cluster.FetchedAt)) {
+		return old
+	}
+	if ok && old.stop != nil {
+		old.stop()
+	}
+	if t.clusters == nil {
+		t.clusters = make(map[string]*TargetCluster)
+	}
+	t.clusters[cluster.Name] = cluster
+	return cluster
+}
+
+// set регистрирует кластер, останавливая кеш замененного
+func (t *targetClusters) set(cluster *TargetCluster) {
+	t.Lock()
+	defer t.Unlock()
+	if t.clusters == nil {
+		t.clusters = make(map[string]*TargetCluster)
+	}
+	if old, ok := t.clusters[cluster.Name]; ok && old.stop != nil {
+		old.stop()
+	}
+	t.clusters[cluster.Name] = cluster
+}
+
+// remove удаляет кластер и останавливает его кеш
+func (t *targetClusters) remove(name string) {
+	t.Lock()
+	defer t.Unlock()
+	if old, ok := t.clusters[name]; ok && old.stop != nil {
+		old.stop()
+	}
+	delete(t.clusters, name)
+}
+
+// setHealth сохраняет результат проверки, если кластер не был заменен за время проверки
+func (t *targetClusters) setHealth(cluster *TargetCluster, version string, err error) {
+	t.Lock()
+	defer t.Unlock()
+	current, ok := t.clusters[cluster.Name]
+	if !ok || current.config != cluster.config {
+		return
+	}
+	now := time.Now()
+	current.LastProbe = &now
+	current.Healthy = err == nil
+	current.Version = version
+	current.Error = ""
+	if err != nil {
+		current.Error = err.Error()
+	}
+}
+
+// getTargetCluster возвращает кластер по имени: зарегистрированный из Secret или кластер провайдера
+// "<provider>/<clusterID>", для которого при необходимости запрашивается kubeconfig.
+// Для пустого имени и "local" возвращает nil: запрос выполняется в кластере kubedeck.
+func (r *KubedeckReconciler) getTargetCluster(ctx context.Context, name string) (*TargetCluster, int, error) {
+	if name == "" || name == localClusterName {
+		return nil, http.StatusOK, nil
+	}
+	if name == allClusters {
+		return nil, http.StatusBadRequest, fmt.Errorf("cluster=%s is not supported by this endpoint", allClusters)
+	}
+	if cluster := r.targets.cached(name); cluster != nil {
+		return cluster, http.StatusOK, nil
+	}
+
+	providerName, clusterID, ok := strings.Cut(name, "/")
+	if !ok || clusterID == "" {
+		return nil, http.StatusNotFound, fmt.Errorf("unknown cluster %q, expected a registered cluster or <provider>/<cluster id>", name)
+	}
+	provider, status, err := r.getClusterProvider(providerName)
+	if err != nil {
+		return nil, status, err
+	}
+	kubeconfigs, ok := provider.(KubeconfigProvider)
+	if !ok {
+		return nil, http.StatusBadRequest, fmt.Errorf("provider %s does not provide kubeconfigs", providerName)
+	}
+
+	// kubeconfig запрашивается без блокировки списка кластеров: запрос к провайдеру с повторами
+	// не должен задерживать остальные кластеры. Одновременные запросы одного кластера объединяются,
+	// а запрос продолжается, даже если вызвавший его запрос отменен.
+	result := r.targets.fetches.DoChan(name, func() (interface{}, error) {
+		if cluster := r.targets.cached(name); cluster != nil {
+			return targetClusterFetch{cluster: cluster, status: http.StatusOK}, nil
+		}
+		fetchCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), kubeconfigFetchTimeout)
+		defer cancel()
+
+		cluster, status, err := r.newProviderCluster(fetchCtx, name, providerName, clusterID, kubeconfigs)
+		if err != nil {
+			return targetClusterFetch{status: status, err: err}, nil
+		}
+		if registered := r.targets.store(cluster); registered != cluster {
+			return targetClusterFetch{cluster: registered, status: http.StatusOK}, nil
+		}
+		webServerLog.Info("Registered target cluster", "cluster", name, "server", cluster.Server)
+		return targetClusterFetch{cluster: cluster, status: http.StatusOK}, nil
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, http.StatusGatewayTimeout, fmt.Errorf("failed to get kubeconfig of cluster %s: %w", name, ctx.Err())
+	case res := <-result:
+		fetch := res.Val.(targetClusterFetch)
+		return fetch.cluster, fetch.status, fetch.err
+	}
+}
+
+// targetClusterFetch - результат получения kubeconfig, общий для объединенных запросов
+type targetClusterFetch struct {
+	cluster *TargetCluster
+	status  int
+	err     error
+}
+
+// newProviderCluster запрашивает kubeconfig кластера у провайдера и создает клиентов кластера
+func (r *KubedeckReconciler) newProviderCluster(ctx context.Context, name, providerName, clusterID string,
+	kubeconfigs KubeconfigProvider) (*TargetCluster, int, error) {
+	kubeconfig, err := kubeconfigs.GetKubeconfig(ctx, clusterID)
+	if err != nil {
+		return nil, providerErrorStatus(err), fmt.Errorf("failed to get kubeconfig of cluster %s: %w", name, err)
+	}
+	config, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
+	if err != nil {
+		return nil, http.StatusBadGateway, fmt.Errorf("invalid kubeconfig of cluster %s: %w", name, err)
+	}
+	c, err := client.New(config, client.Options{Scheme: r.Client.Scheme()})
+	if err != nil {
+		return nil, http.StatusBadGateway, fmt.Errorf("failed to create client for cluster %s: %w", name, err)
+	}
+	clientset, metrics, err := newClusterClients(config)
+	if err != nil {
+		return nil, http.StatusBadGateway, fmt.Errorf("failed to create clients for cluster %s: %w", name, err)
+	}
+
+	return &TargetCluster{
+		Name:      name,
+		Source:    targetClusterSourceProvider,
+		Provider:  providerName,
+		ClusterID: clusterID,
+		Server:    config.Host,
+		FetchedAt: time.Now(),
+		Healthy:   true,
+		config:    config,
+		client:    c,
+		clientset: clientset,
+		metrics:   metrics,
+	}, http.StatusOK, nil
+}
+
+type targetClusterKey struct{}
+
+// withTargetCluster сохраняет кластер в контексте; nil означает кластер kubedeck
+func withTargetCluster(ctx context.Context, cluster *TargetCluster) context.Context {
+	if cluster == nil {
+		return ctx
+	}
+	return context.WithValue(ctx, targetClusterKey{}, cluster)
+}
+
+// clientFor возвращает клиент кластера из контекста или клиент кластера kubedeck
+func (r *KubedeckReconciler) clientFor(ctx context.Context) client.Client {
+	if cluster, ok := ctx.Value(targetClusterKey{}).(*TargetCluster); ok {
+		return cluster.client
+	}
+	return r.Client
+}
+
+// clientsetFor возвращает clientset кластера из контекста или кластера kubedeck
+func (r *KubedeckReconciler) clientsetFor(ctx context.Context) (kubernetes.Interface, error) {
+	if cluster, ok := ctx.Value(targetClusterKey{}).(*TargetCluster); ok {
+		if cluster.clientset != nil {
+			return cluster.clientset, nil
+		}
+	} else if r.clientset != nil {
+		return r.clientset, nil
+	}
+	return kubernetes.NewForConfig(r.configFor(ctx))
+}
+
+// newClusterClients создает clientset и источник metrics-server кластера
+func newClusterClients(config *rest.Config) (kubernetes.Interface, MetricsBackend, error) {
+	clientset, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	metrics, err := newMetricsServerBackend(config)
+	if err != nil {
+		return nil, nil, err
+	}
+	return clientset, metrics, nil
+}
+
+// configFor возвращает rest.Config кластера из контекста или кластера kubedeck
+func (r *KubedeckReconciler) configFor(ctx context.Context) *rest.Config {
+	if cluster, ok := ctx.Value(targetClusterKey{}).(*TargetCluster); ok {
+		return cluster.config
+	}
+	return r.Config
+}
+
+// withCluster направляет запрос в кластер из параметра cluster
+func (r *KubedeckReconciler) withCluster(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		cluster, status, err := r.getTargetCluster(req.Context(), req.URL.Query().Get("cluster"))
+		if err != nil {
+			webServerLog.Error(err, "Failed to resolve target cluster")
+			http.Error(w, err.Error(), status)
+			return
+		}
+		next(w, req.WithContext(withTargetCluster(req.Context(), cluster)))
+	}
+}
+
+// ClusterResult - ответ одного кластера при запросе с cluster=all
+type ClusterResult struct {
+	Cluster string      `json:"cluster"`
+	Data    interface{} `json:"data,omitempty"`
+	Error   string      `json:"error,omitempty"`
+}
+
+// inClusters выполняет fetch в кластере из параметра cluster и пишет ответ.
+// С cluster=all fetch выполняется параллельно в кластере kubedeck и всех зарегистрированных кластерах,
+// ответ - список ClusterResult; недоступные кластеры возвращаются с ошибкой без запроса.
+func (r *KubedeckReconciler) inClusters(w http.ResponseWriter, req *http.Request, resourceName string,
+	fetch func(ctx context.Context) (interface{}, error)) {
+	name := req.URL.Query().Get("cluster")
+	if name != allClusters {
+		cluster, status, err := r.getTargetCluster(req.Context(), name)
+		if err != nil {
+			webServerLog.Error(err, "Failed to resolve target cluster")
+			http.Error(w, err.Error(), status)
+			return
+		}
+		data, err := fetch(withTargetCluster(req.Context(), cluster))
+		writeJsonResponse(w, data, resourceName, err)
+		return
+	}
+
+	writeJsonResponse(w, r.fanOut(req.Context(), fetch), resourceName, nil)
+}
+
+// fanOut выполняет fetch во всех кластерах
+func (r *KubedeckReconciler) fanOut(ctx context.Context, fetch func(ctx context.Context) (interface{}, error)) []ClusterResult {
+	clusters := r.targets.list()
+	results := make([]ClusterResult, len(clusters)+1)
+
+	var wg sync.WaitGroup
+	run := func(i int, cluster *TargetCluster) {
+		defer wg.Done()
+		results[i].Cluster = localClusterName
+		if cluster != nil {
+			results[i].Cluster = cluster.Name
+			if cluster.unhealthy() {
+				results[i].Error = "cluster is unhealthy: " + cluster.Error
+				return
+			}
+		}
+
+		clusterCtx, cancel := context.WithTimeout(withTargetCluster(ctx, cluster), fanOutTimeout)
+		defer cancel()
+		data, err := fetch(clusterCtx)
+		if err != nil {
+			results[i].Error = err.Error()
+			return
+		}
+		results[i].Data = data
+	}
+
+	wg.Add(len(results))
+	go run(0, nil)
+	for i := range clusters {
+		go run(i+1, &clusters[i])
+	}
+	wg.Wait()
+	return results
+}
+
+// nodeGroupContext направляет работу с нодами группы в кластер провайдера, если провайдер выдает kubeconfig.
+// Для остальных провайдеров (fake) ноды ищутся в кластере kubedeck.
+func (r *KubedeckReconciler) nodeGroupContext(ctx context.Context, providerName, clusterID string) (context.Context, error) {
+	if _, ok := r.providers[providerName].(KubeconfigProvider); !ok {
+		return ctx, nil
+	}
+	cluster, _, err := r.getTargetCluster(ctx, targetClusterName(providerName, clusterID))
+	if err != nil {
+		return nil, err
+	}
+	return withTargetCluster(ctx, cluster), nil
+}
+
+// nonLeaderRunnable запускает функцию на всех репликах: веб-сервер с параметром cluster работает на каждой
+type nonLeaderRunnable func(ctx context.Context) error
+
+func (f nonLeaderRunnable) Start(ctx context.Context) error { return f(ctx) }
+
+func (f nonLeaderRunnable) NeedLeaderElection() bool { return false }
+
+// runClusterRegistry синхронизирует кластеры из Secret и проверяет доступность всех кластеров
+func (r *KubedeckReconciler) runClusterRegistry(ctx context.Context, reader client.Reader) error {
+	log := webServerLog.WithName("cluster-registry")
+	namespace := getEnvString("KUBEDECK_CLUSTER_SECRETS_NAMESPACE", "")
+
+	ticker := time.NewTicker(clusterSyncInterval)
+	defer ticker.Stop()
+	for {
+		if err := r.syncClusterSecrets(ctx, reader, namespace); err != nil {
+			log.Error(err, "Failed to sync cluster secrets")
+		}
+		r.probeClusters(ctx)
+
+		select {
+		case <-ctx.Done():
+			for _, cluster := range r.targets.list() {
+				r.targets.remove(cluster.Name)
+			}
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// syncClusterSecrets регистрирует кластеры из Secret с меткой clusterSecretLabel.
+// Клиент пересоздается при изменении Secret, кластеры удаленных Secret удаляются из реестра.
+func (r *KubedeckReconciler) syncClusterSecrets(ctx context.Context, reader client.Reader, namespace string) error {
+	log := webServerLog.WithName("cluster-registry")
+
+	secrets := &corev1.SecretList{}
+	if err := reader.List(ctx, secrets, client.InNamespace(namespace), client.HasLabels{clusterSecretLabel}); err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool, len(secrets.Items))
+	for _, secret := range secrets.Items {
+		name := secret.Labels[clusterSecretLabel]
+		if name == "" {
+			name = secret.Name
+		}
+		if name == localClusterName || name == allClusters || strings.Contains(name, "/") || seen[name] {
+			log.Info("Skipping cluster secret with a reserved or duplicate cluster name", "secret", secret.Namespace+"/"+secret.Name, "cluster", name)
+			continue
+		}
+		seen[name] = true
+
+		if existing := r.targets.get(name); existing != nil && existing.resourceVersion == secret.ResourceVersion {
+			continue
+		}
+		cluster, err := r.newSecretCluster(ctx, name, secret)
+		if err != nil {
+			log.Error(err, "Failed to register cluster", "secret", secret.Namespace+"/"+secret.Name, "cluster", name)
+			continue
+		}
+		r.targets.set(cluster)
+		log.Info("Registered cluster", "cluster", name, "server", cluster.Server)
+	}
+
+	for _, cluster := range r.targets.list() {
+		if cluster.Source == targetClusterSourceSecret && !seen[cluster.Name] {
+			r.targets.remove(cluster.Name)
+			log.Info("Removed cluster", "cluster", cluster.Name)
+		}
+	}
+	return nil
+}
+
+// newSecretCluster создает кластер с кешем объектов по kubeconfig из ключа kubeconfig или value Secret
+func (r *KubedeckReconciler) newSecretCluster(ctx context.Context, name string, secret corev1.Secret) (*TargetCluster, error) {
+	kubeconfig, ok := secret.Data["kubeconfig"]
+	if !ok {
+		kubeconfig, ok = secret.Data["value"]
+	}
+	if !ok {
+		return nil, fmt.Errorf("secret has neither kubeconfig nor value key")
+	}
+
+	config, err := clientcmd.RESTConfigFromKubeConfig(kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("invalid kubeconfig: %w", err)
+	}
+	remote, err := cluster.New(config, func(o *cluster.Options) { o.Scheme = r.Client.Scheme() })
+	if err != nil {
+		return nil, err
+	}
+	clientset, metrics, err := newClusterClients(config)
+	if err != nil {
+		return nil, err
+	}
+
+	// Кеш кластера живет, пока кластер зарегистрирован
+	clusterCtx, stop := context.WithCancel(ctx)
+	go func() {
+		if err := remote.Start(clusterCtx); err != nil {
+			webServerLog.WithName("cluster-registry").Error(err, "Cluster cache stopped", "cluster", name)
+		}
+	}()
+
+	return &TargetCluster{
+		Name:            name,
+		Source:          targetClusterSourceSecret,
+		Secret:          secret.Namespace + "/" + secret.Name,
+		Server:          config.Host,
+		FetchedAt:       time.Now(),
+		config:          config,
+		client:          remote.GetClient(),
+		clientset:       clientset,
+		metrics:         metrics,
+		resourceVersion: secret.ResourceVersion,
+		stop:            stop,
+	}, nil
+}
+
+// probeClusters проверяет доступность зарегистрированных кластеров запросом версии API сервера
+func (r *KubedeckReconciler) probeClusters(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, cluster := range r.targets.list() {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			version, err := probeCluster(ctx, cluster.config)
+			r.targets.setHealth(&cluster, version, err)
+		}()
+	}
+	wg.Wait()
+}
+
+// probeCluster возвращает версию API сервера кластера
+func probeCluster(ctx context.Context, config *rest.Config) (string, error) {
+	config = rest.CopyConfig(config)
+	config.Timeout = clusterProbeTimeout
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		return "", err
+	}
+
+	probeCtx, cancel := context.WithTimeout(ctx, clusterProbeTimeout)
+	defer cancel()
+	if _, err := discoveryClient.RESTClient().Get().AbsPath("/readyz").DoRaw(probeCtx); err != nil {
+		return "", fmt.Errorf("readyz: %w", err)
+	}
+	version, err := discoveryClient.ServerVersion()
+	if err != nil {
+		return "", err
+	}
+	return version.GitVersion, nil
+}
+
+// handleClustersRequest возвращает кластер kubedeck и зарегистрированные кластеры с результатом проверки доступности
+func (r *KubedeckReconciler) handleClustersRequest(w http.ResponseWriter, req *http.Request) {
+	clusters := []TargetCluster{{Name: localClusterName, Source: localClusterName, Healthy: true}}
+	if r.Config != nil {
+		clusters[0].Server = r.Config.Host
+	}
+	clusters = append(clusters, r.targets.list()...)
+	writeJsonResponse(w, clusters, "clusters", nil)
+}
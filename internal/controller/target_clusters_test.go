@@ -0,0 +1,216 @@
+package controller
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/tools/clientcmd"
+	clientcmdapi "k8s.io/client-go/tools/clientcmd/api"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+// kubeconfigFakeProvider - fake провайдер, выдающий kubeconfig тестового кластера
+type kubeconfigFakeProvider struct {
+	*FakeProvider
+	kubeconfig []byte
+	fetches    int
+}
+
+func (p *kubeconfigFakeProvider) GetKubeconfig(ctx context.Context, clusterID string) ([]byte, error) {
+	p.fetches++
+	return p.kubeconfig, nil
+}
+
+// blockingKubeconfigProvider - fake провайдер, отдающий kubeconfig только после release
+type blockingKubeconfigProvider struct {
+	*FakeProvider
+	release chan struct{}
+	fetches atomic.Int32
+}
+
+func (p *blockingKubeconfigProvider) GetKubeconfig(ctx context.Context, clusterID string) ([]byte, error) {
+	p.fetches.Add(1)
+	<-p.release
+	return []byte("apiVersion: v1\nkind: Config\nclusters:\n- name: c\n  cluster:\n    server: https://127.0.0.1:6443\n" +
+		"contexts:\n- name: c\n  context:\n    cluster: c\ncurrent-context: c\n"), nil
+}
+
+// envtestKubeconfig возвращает kubeconfig тестового кластера
+func envtestKubeconfig() []byte {
+	config := clientcmdapi.NewConfig()
+	config.Clusters["envtest"] = &clientcmdapi.Cluster{Server: cfg.Host, CertificateAuthorityData: cfg.CAData}
+	config.AuthInfos["envtest"] = &clientcmdapi.AuthInfo{ClientCertificateData: cfg.CertData, ClientKeyData: cfg.KeyData}
+	config.Contexts["envtest"] = &clientcmdapi.Context{Cluster: "envtest", AuthInfo: "envtest"}
+	config.CurrentContext = "envtest"
+	kubeconfig, err := clientcmd.Write(*config)
+	Expect(err).NotTo(HaveOccurred())
+	return kubeconfig
+}
+
+var _ = Describe("Target clusters", func() {
+	It("should reject unknown clusters and providers without kubeconfigs", func() {
+		reconciler := &KubedeckReconciler{providers: map[string]ClusterProvider{"fake": NewFakeProvider(FakeProviderOptions{})}}
+
+		cluster, status, err := reconciler.getTargetCluster(ctx, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cluster).To(BeNil())
+
+		_, status, err = reconciler.getTargetCluster(ctx, "fake")
+		Expect(err).To(HaveOccurred())
+		Expect(status).To(Equal(http.StatusNotFound))
+
+		_, status, err = reconciler.getTargetCluster(ctx, "fake/fake-cluster-1")
+		Expect(err).To(MatchError(ContainSubstring("does not provide kubeconfigs")))
+		Expect(status).To(Equal(http.StatusBadRequest))
+
+		_, status, _ = reconciler.getTargetCluster(ctx, "unknown/1")
+		Expect(status).To(Equal(http.StatusBadRequest))
+
+		recorder := httptest.NewRecorder()
+		reconciler.withCluster(func(w http.ResponseWriter, req *http.Request) {
+			Fail("handler must not be called for an unknown cluster")
+		})(recorder, httptest.NewRequest(http.MethodGet, "/pods?cluster=fake/fake-cluster-1", nil))
+		Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+	})
+
+	It("should fetch a kubeconfig once without blocking other clusters", func() {
+		provider := &blockingKubeconfigProvider{FakeProvider: NewFakeProvider(FakeProviderOptions{}), release: make(chan struct{})}
+		reconciler := &KubedeckReconciler{Client: fake.NewClientBuilder().Build(), providers: map[string]ClusterProvider{"remote": provider}}
+		reconciler.targets.set(&TargetCluster{Name: "onprem", Source: targetClusterSourceSecret})
+
+		results := make(chan *TargetCluster, 2)
+		for range 2 {
+			go func() {
+				defer GinkgoRecover()
+				cluster, _, err := reconciler.getTargetCluster(ctx, "remote/cluster-1")
+				Expect(err).NotTo(HaveOccurred())
+				results <- cluster
+			}()
+		}
+
+		// Пока провайдер отвечает, остальные кластеры доступны
+		Eventually(provider.fetches.Load).Should(BeEquivalentTo(1))
+		cluster, _, err := reconciler.getTargetCluster(ctx, "onprem")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cluster.Name).To(Equal("onprem"))
+		Expect(reconciler.targets.list()).To(HaveLen(1))
+
+		close(provider.release)
+		first, second := <-results, <-results
+		Expect(first).To(BeIdenticalTo(second))
+		Expect(first.Server).To(Equal("https://127.0.0.1:6443"))
+		Expect(provider.fetches.Load()).To(BeEquivalentTo(1))
+
+		cached, _, err := reconciler.getTargetCluster(ctx, "remote/cluster-1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(cached).To(BeIdenticalTo(first))
+	})
+
+	It("should fan out to all clusters and skip unhealthy ones", func() {
+		reconciler := &KubedeckReconciler{}
+		probed := time.Now()
+		reconciler.targets.set(&TargetCluster{Name: "onprem", Source: targetClusterSourceSecret, Healthy: true, LastProbe: &probed})
+		reconciler.targets.set(&TargetCluster{Name: "broken", Source: targetClusterSourceSecret, LastProbe: &probed, Error: "connection refused"})
+
+		fetch := func(ctx context.Context) (interface{}, error) {
+			if cluster, ok := ctx.Value(targetClusterKey{}).(*TargetCluster); ok {
+				return cluster.Name, nil
+			}
+			return localClusterName, nil
+		}
+
+		recorder := httptest.NewRecorder()
+		reconciler.inClusters(recorder, httptest.NewRequest(http.MethodGet, "/pods?cluster=all", nil), "pods", fetch)
+		Expect(recorder.Code).To(Equal(http.StatusOK))
+
+		var results []ClusterResult
+		Expect(json.Unmarshal(recorder.Body.Bytes(), &results)).To(Succeed())
+		Expect(results).To(Equal([]ClusterResult{
+			{Cluster: "local", Data: "local"},
+			{Cluster: "broken", Error: "cluster is unhealthy: connection refused"},
+			{Cluster: "onprem", Data: "onprem"},
+		}))
+
+		recorder = httptest.NewRecorder()
+		reconciler.inClusters(recorder, httptest.NewRequest(http.MethodGet, "/pods?cluster=onprem", nil), "pods", fetch)
+		Expect(recorder.Body.String()).To(Equal(`"onprem"`))
+
+		recorder = httptest.NewRecorder()
+		reconciler.withCluster(func(w http.ResponseWriter, req *http.Request) {
+			Fail("handler must not be called with cluster=all")
+		})(recorder, httptest.NewRequest(http.MethodGet, "/logs?cluster=all", nil))
+		Expect(recorder.Code).To(Equal(http.StatusBadRequest))
+	})
+
+	It("should register clusters from labeled kubeconfig secrets", func() {
+		reconciler := &KubedeckReconciler{Client: k8sClient}
+		secret := &corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{Name: "onprem-kubeconfig", Namespace: "default", Labels: map[string]string{clusterSecretLabel: "onprem"}},
+			Data:       map[string][]byte{"kubeconfig": envtestKubeconfig()},
+		}
+		Expect(k8sClient.Create(ctx, secret)).To(Succeed())
+		defer func() { _ = k8sClient.Delete(ctx, secret) }()
+
+		registryCtx, stop := context.WithCancel(ctx)
+		defer stop()
+		Expect(reconciler.syncClusterSecrets(registryCtx, k8sClient, "default")).To(Succeed())
+		reconciler.probeClusters(registryCtx)
+
+		clusters := reconciler.targets.list()
+		Expect(clusters).To(HaveLen(1))
+		Expect(clusters[0].Name).To(Equal("onprem"))
+		Expect(clusters[0].Secret).To(Equal("default/onprem-kubeconfig"))
+		Expect(clusters[0].Healthy).To(BeTrue())
+		Expect(clusters[0].Version).NotTo(BeEmpty())
+
+		namespaces := &corev1.NamespaceList{}
+		target, _, err := reconciler.getTargetCluster(ctx, "onprem")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(reconciler.clientFor(withTargetCluster(ctx, target)).List(ctx, namespaces)).To(Succeed())
+		Expect(namespaces.Items).NotTo(BeEmpty())
+
+		// Удаленный Secret убирает кластер из реестра
+		Expect(k8sClient.Delete(ctx, secret)).To(Succeed())
+		Expect(reconciler.syncClusterSecrets(registryCtx, k8sClient, "default")).To(Succeed())
+		Expect(reconciler.targets.list()).To(BeEmpty())
+	})
+
+	It("should route requests with the cluster parameter to the provider cluster", func() {
+		kubeconfig := envtestKubeconfig()
+
+		provider := &kubeconfigFakeProvider{FakeProvider: NewFakeProvider(FakeProviderOptions{}), kubeconfig: kubeconfig}
+		reconciler := &KubedeckReconciler{Client: k8sClient, providers: map[string]ClusterProvider{"remote": provider}}
+
+		var listed int
+		handler := reconciler.withCluster(func(w http.ResponseWriter, req *http.Request) {
+			Expect(reconciler.clientFor(req.Context())).NotTo(BeIdenticalTo(k8sClient))
+			namespaces := &corev1.NamespaceList{}
+			Expect(reconciler.clientFor(req.Context()).List(req.Context(), namespaces)).To(Succeed())
+			listed = len(namespaces.Items)
+		})
+		for range 2 {
+			recorder := httptest.NewRecorder()
+			handler(recorder, httptest.NewRequest(http.MethodGet, "/namespaces?cluster=remote/cluster-1", nil))
+			Expect(recorder.Code).To(Equal(http.StatusOK))
+		}
+		Expect(listed).To(BeNumerically(">", 0))
+
+		// kubeconfig запрашивается один раз и кешируется
+		Expect(provider.fetches).To(Equal(1))
+		targets := reconciler.targets.list()
+		Expect(targets).To(HaveLen(1))
+		Expect(targets[0].Name).To(Equal("remote/cluster-1"))
+		Expect(targets[0].Server).To(Equal(cfg.Host))
+
+		// Без параметра запрос выполняется в кластере kubedeck
+		Expect(reconciler.clientFor(ctx)).To(BeIdenticalTo(k8sClient))
+	})
+})
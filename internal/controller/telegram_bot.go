@@ -273,6 +273,7 @@ func (r *KubedeckReconciler) StartTelegramBot(ctx context.Context) {
 
 		// Немедленная первая проверка после запуска
 		r.checkResourcesAndSendAlerts(ctx, tracker)
+		r.checkAnomaliesAndSendAlerts(ctx, tracker)
 
 		for {
 			select {
@@ -290,6 +291,7 @@ func (r *KubedeckReconciler) StartTelegramBot(ctx context.Context) {
 					log.Info("Updated check interval", "newInterval", checkInterval)
 				}
 				r.checkResourcesAndSendAlerts(ctx, tracker)
+				r.checkAnomaliesAndSendAlerts(ctx, tracker)
 			}
 		}
 	}()
@@ -313,17 +315,11 @@ func (r *KubedeckReconciler) StartTelegramBot(ctx context.Context) {
 func (r *KubedeckReconciler) checkResourcesAndSendAlerts(ctx context.Context, tracker *AlertTracker) {
 	log := webServerLog.WithName("resource-checker")
 
-	// Получаем данные о ресурсах
-	podResourceData, err := r.collectPodResourceData(ctx)
+	// Получаем рекомендации от источника из KUBEDECK_RECOMMENDER
+	engine := getEnvString("KUBEDECK_RECOMMENDER", recommenderLLM)
+	recommendation, err := r.resourceRecommendations(ctx, engine, getEnvBool("KUBEDECK_RIGHTSIZING_NARRATIVE", false))
 	if err != nil {
-		log.Error(err, "Failed to collect pod resource data")
-		return
-	}
-
-	// Отправляем данные на анализ в LLM
-	recommendation, err := r.getLLMResourceRecommendations(ctx, podResourceData)
-	if err != nil {
-		log.Error(err, "Failed to get LLM recommendations")
+		log.Error(err, "Failed to get resource recommendations", "engine", engine)
 		return
 	}
 
@@ -357,6 +353,7 @@ func (r *KubedeckReconciler) checkResourcesAndSendAlerts(ctx context.Context, tr
 
 	for _, chatID := range chatIDs {
 		err := sendTelegramSummaryMessage(chatID, message, token)
+		telegramMessagesTotal.WithLabelValues("alert", resultLabel(err)).Inc()
 		if err != nil {
 			log.Error(err, "Failed to send Telegram summary alert", "chatID", chatID)
 		} else {
@@ -365,6 +362,99 @@ func (r *KubedeckReconciler) checkResourcesAndSendAlerts(ctx context.Context, tr
 	}
 }
 
+// checkAnomaliesAndSendAlerts ищет аномалии в истории метрик и отправляет одно сообщение о новых находках
+// с важностью не ниже KUBEDECK_ANOMALY_ALERT_SEVERITY. Повторы подавляются трекером алертов.
+func (r *KubedeckReconciler) checkAnomaliesAndSendAlerts(ctx context.Context, tracker *AlertTracker) {
+	log := webServerLog.WithName("anomaly-checker")
+	if r.history == nil {
+		return
+	}
+
+	report, err := r.anomalies(ctx, anomalySettingsFromEnv(), time.Now())
+	if err != nil {
+		log.Error(err, "Failed to detect anomalies")
+		return
+	}
+	severity := getEnvString("KUBEDECK_ANOMALY_ALERT_SEVERITY", severityWarning)
+	if _, ok := severityRank[severity]; !ok {
+		severity = severityWarning
+	}
+	report.filter("", severity)
+
+	var findings []AnomalyFinding
+	for _, f := range report.Findings {
+		subject := f.Workload
+		if f.Pod != "" {
+			subject = f.Pod + "/" + f.Container
+		}
+		if tracker.ShouldSendAlert(f.Namespace, "anomaly/"+f.Kind+"/"+f.Resource+"/"+subject) {
+			findings = append(findings, f)
+		}
+	}
+	if len(findings) == 0 {
+		log.Info("No new anomalies found")
+		return
+	}
+
+	r.broadcastTelegram("alert", formatAnomalyAlertMessage(findings))
+	log.Info("Sent Telegram anomaly alert", "anomalies", len(findings))
+}
+
+// formatAnomalyAlertMessage форматирует сообщение о находках детектора аномалий (до 10 штук)
+func formatAnomalyAlertMessage(findings []AnomalyFinding) string {
+	var sb strings.Builder
+	sb.WriteString("*Аномалии использования ресурсов Kubernetes*\n\n")
+	sb.WriteString(fmt.Sprintf("*Обнаружено:* %d новых аномалий\n", len(findings)))
+	sb.WriteString(fmt.Sprintf("*Время сканирования:* %s\n\n", time.Now().Format("2006-01-02 15:04:05 MST")))
+
+	titles := map[string]string{
+		anomalySpike:      "Всплеск",
+		anomalyMemoryLeak: "Утечка памяти",
+		anomalyIdle:       "Простой",
+	}
+	for i, f := range findings {
+		if i == 10 {
+			sb.WriteString(fmt.Sprintf("...и еще %d\n", len(findings)-i))
+			break
+		}
+		level := "Предупреждение"
+		if f.Severity == severityCritical {
+			level = "Критично"
+		} else if f.Severity == severityInfo {
+			level = "Информация"
+		}
+		sb.WriteString(fmt.Sprintf("*%s (%s):* %s\n", titles[f.Kind], level, f.Message))
+	}
+	return sb.String()
+}
+
+// broadcastTelegramMessage отправляет уведомление во все настроенные чаты
+func (r *KubedeckReconciler) broadcastTelegramMessage(message string) {
+	r.broadcastTelegram("notification", message)
+}
+
+// broadcastTelegram отправляет сообщение типа messageType (alert, notification) во все настроенные чаты
+func (r *KubedeckReconciler) broadcastTelegram(messageType, message string) {
+	log := webServerLog.WithName("telegram-bot")
+	if r.TelegramBotSettings == nil {
+		return
+	}
+
+	token := r.TelegramBotSettings.GetToken()
+	chatIDs := r.TelegramBotSettings.GetChatIDs()
+	if len(chatIDs) == 0 {
+		chatIDs = ChatIDs
+	}
+
+	for _, chatID := range chatIDs {
+		err := sendTelegramSummaryMessage(chatID, message, token)
+		telegramMessagesTotal.WithLabelValues(messageType, resultLabel(err)).Inc()
+		if err != nil {
+			log.Error(err, "Failed to send Telegram message", "chatID", chatID)
+		}
+	}
+}
+
 // formatTechnicalAlertMessage форматирует техническое сообщение со всеми алертами и детальной информацией
 func formatTechnicalAlertMessage(recommendation *ResourceRecommendationResponse, totalPods int) string {
 	var sb strings.Builder
@@ -466,7 +556,7 @@ func sendTelegramSummaryMessage(chatID int64, text string, token string) error {
 	}
 
 	// Отправляем запрос к API Telegram
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(jsonData))
+	resp, err := outboundHTTPClient.Post(url, "application/json", bytes.NewReader(jsonData))
 	if err != nil {
 		return fmt.Errorf("failed to send telegram message: %w", err)
 	}
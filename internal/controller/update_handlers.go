@@ -81,7 +81,7 @@ func (h *KubedeckReconciler) HandleUpdateForFront(w http.ResponseWriter, r *http
 		if payload.Resource.Replicas >= 0 && replicas != nil {
 			*replicas = payload.Resource.Replicas
 		}
-		if err := h.Client.Update(ctx, obj); err != nil {
+		if err := h.clientFor(ctx).Update(ctx, obj); err != nil {
 			h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to update "+payload.Resource.ResourceType, err)
 			return
 		}
@@ -91,7 +91,7 @@ func (h *KubedeckReconciler) HandleUpdateForFront(w http.ResponseWriter, r *http
 	switch payload.Resource.ResourceType {
 	case "Deployment":
 		var d appsv1.Deployment
-		if err := h.Client.Get(ctx, client.ObjectKey{Namespace: payload.Namespace, Name: payload.Resource.Name}, &d); err != nil {
+		if err := h.clientFor(ctx).Get(ctx, client.ObjectKey{Namespace: payload.Namespace, Name: payload.Resource.Name}, &d); err != nil {
 			h.writeErrorResponse(w, http.StatusNotFound, "Deployment not found", err)
 			return
 		}
@@ -99,7 +99,7 @@ func (h *KubedeckReconciler) HandleUpdateForFront(w http.ResponseWriter, r *http
 
 	case "ReplicaSet":
 		var d appsv1.Deployment
-		if err := h.Client.Get(ctx, client.ObjectKey{Namespace: payload.Namespace, Name: payload.Resource.Name}, &d); err != nil {
+		if err := h.clientFor(ctx).Get(ctx, client.ObjectKey{Namespace: payload.Namespace, Name: payload.Resource.Name}, &d); err != nil {
 			h.writeErrorResponse(w, http.StatusNotFound, "Deployment not found for ReplicaSet", err)
 			return
 		}
@@ -107,7 +107,7 @@ func (h *KubedeckReconciler) HandleUpdateForFront(w http.ResponseWriter, r *http
 
 	case "StatefulSet":
 		var s appsv1.StatefulSet
-		if err := h.Client.Get(ctx, client.ObjectKey{Namespace: payload.Namespace, Name: payload.Resource.Name}, &s); err != nil {
+		if err := h.clientFor(ctx).Get(ctx, client.ObjectKey{Namespace: payload.Namespace, Name: payload.Resource.Name}, &s); err != nil {
 			h.writeErrorResponse(w, http.StatusNotFound, "StatefulSet not found", err)
 			return
 		}
@@ -115,7 +115,7 @@ func (h *KubedeckReconciler) HandleUpdateForFront(w http.ResponseWriter, r *http
 
 	case "DaemonSet":
 		var d appsv1.DaemonSet
-		if err := h.Client.Get(ctx, client.ObjectKey{Namespace: payload.Namespace, Name: payload.Resource.Name}, &d); err != nil {
+		if err := h.clientFor(ctx).Get(ctx, client.ObjectKey{Namespace: payload.Namespace, Name: payload.Resource.Name}, &d); err != nil {
 			h.writeErrorResponse(w, http.StatusNotFound, "DaemonSet not found", err)
 			return
 		}
@@ -180,7 +180,7 @@ func (h *KubedeckReconciler) HandleCreatePod(w http.ResponseWriter, req *http.Re
 	// pod.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Pod")) // Может быть полезно, если клиент не шлет GVK
 
 	webServerLog.Info("Attempting to create pod", "namespace", pod.Namespace, "name", pod.GenerateName+pod.Name)
-	if err := h.Client.Create(ctx, &pod); err != nil {
+	if err := h.clientFor(ctx).Create(ctx, &pod); err != nil {
 		h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to create pod", err)
 		return
 	}
@@ -213,7 +213,7 @@ func (h *KubedeckReconciler) HandleUpdatePod(w http.ResponseWriter, req *http.Re
 	// updatedPod.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Pod"))
 
 	webServerLog.Info("Attempting to update pod", "namespace", updatedPod.Namespace, "name", updatedPod.Name, "resourceVersion", updatedPod.ResourceVersion)
-	if err := h.Client.Update(ctx, &updatedPod); err != nil {
+	if err := h.clientFor(ctx).Update(ctx, &updatedPod); err != nil {
 		h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to update pod", err)
 		return
 	}
@@ -240,7 +240,7 @@ func (h *KubedeckReconciler) HandleDeletePod(w http.ResponseWriter, req *http.Re
 
 	webServerLog.Info("Attempting to delete pod", "namespace", targetNamespace, "name", podName)
 	// Можно добавить &client.DeleteOptions{} для указания PropagationPolicy и др.
-	if err := h.Client.Delete(ctx, podToDelete); err != nil {
+	if err := h.clientFor(ctx).Delete(ctx, podToDelete); err != nil {
 		h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to delete pod", err)
 		return
 	}
@@ -271,7 +271,7 @@ func (h *KubedeckReconciler) HandleCreateConfigMap(w http.ResponseWriter, req *h
 	// cm.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("ConfigMap"))
 
 	webServerLog.Info("Attempting to create ConfigMap", "namespace", cm.Namespace, "name", cm.Name)
-	if err := h.Client.Create(ctx, &cm); err != nil {
+	if err := h.clientFor(ctx).Create(ctx, &cm); err != nil {
 		h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to create ConfigMap", err)
 		return
 	}
@@ -304,7 +304,7 @@ func (h *KubedeckReconciler) HandleUpdateConfigMap(w http.ResponseWriter, req *h
 	// updatedCm.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("ConfigMap"))
 
 	webServerLog.Info("Attempting to update ConfigMap", "namespace", updatedCm.Namespace, "name", updatedCm.Name)
-	if err := h.Client.Update(ctx, &updatedCm); err != nil {
+	if err := h.clientFor(ctx).Update(ctx, &updatedCm); err != nil {
 		h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to update ConfigMap", err)
 		return
 	}
@@ -327,7 +327,7 @@ func (h *KubedeckReconciler) HandleDeleteConfigMap(w http.ResponseWriter, req *h
 	// cmToDelete.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("ConfigMap"))
 
 	webServerLog.Info("Attempting to delete ConfigMap", "namespace", targetNamespace, "name", cmName)
-	if err := h.Client.Delete(ctx, cmToDelete); err != nil {
+	if err := h.clientFor(ctx).Delete(ctx, cmToDelete); err != nil {
 		h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to delete ConfigMap", err)
 		return
 	}
@@ -358,7 +358,7 @@ func (h *KubedeckReconciler) HandleCreateDeployment(w http.ResponseWriter, req *
 	// deploy.SetGroupVersionKind(appsv1.SchemeGroupVersion.WithKind("Deployment"))
 
 	webServerLog.Info("Attempting to create Deployment", "namespace", deploy.Namespace, "name", deploy.Name)
-	if err := h.Client.Create(ctx, &deploy); err != nil {
+	if err := h.clientFor(ctx).Create(ctx, &deploy); err != nil {
 		h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to create Deployment", err)
 		return
 	}
@@ -395,7 +395,7 @@ func (h *KubedeckReconciler) HandleUpdateDeployment(w http.ResponseWriter, r *ht
 
 	ctx := r.Context()
 	var deployment appsv1.Deployment
-	if err := h.Client.Get(ctx, client.ObjectKey{Namespace: payload.Namespace, Name: payload.Resource.Name}, &deployment); err != nil {
+	if err := h.clientFor(ctx).Get(ctx, client.ObjectKey{Namespace: payload.Namespace, Name: payload.Resource.Name}, &deployment); err != nil {
 		h.writeErrorResponse(w, http.StatusNotFound, "Deployment not found", err)
 		return
 	}
@@ -412,7 +412,7 @@ func (h *KubedeckReconciler) HandleUpdateDeployment(w http.ResponseWriter, r *ht
 	}
 	deployment.Spec.Replicas = &payload.Resource.Replicas
 
-	if err := h.Client.Update(ctx, &deployment); err != nil {
+	if err := h.clientFor(ctx).Update(ctx, &deployment); err != nil {
 		h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to update Deployment", err)
 		return
 	}
@@ -435,7 +435,7 @@ func (h *KubedeckReconciler) HandleDeleteDeployment(w http.ResponseWriter, req *
 	// deployToDelete.SetGroupVersionKind(appsv1.SchemeGroupVersion.WithKind("Deployment"))
 
 	webServerLog.Info("Attempting to delete Deployment", "namespace", targetNamespace, "name", deployName)
-	if err := h.Client.Delete(ctx, deployToDelete); err != nil {
+	if err := h.clientFor(ctx).Delete(ctx, deployToDelete); err != nil {
 		h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to delete Deployment", err)
 		return
 	}
@@ -462,7 +462,7 @@ func (h *KubedeckReconciler) HandleCreatePersistentVolume(w http.ResponseWriter,
 	obj.ResourceVersion = ""
 	obj.UID = ""
 
-	if err := h.Client.Create(ctx, &obj); err != nil {
+	if err := h.clientFor(ctx).Create(ctx, &obj); err != nil {
 		h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to create PersistentVolume", err)
 		return
 	}
@@ -493,7 +493,7 @@ func (h *KubedeckReconciler) HandleUpdatePersistentVolume(w http.ResponseWriter,
 	obj.Namespace = ns
 	obj.Name = name
 
-	if err := h.Client.Update(ctx, &obj); err != nil {
+	if err := h.clientFor(ctx).Update(ctx, &obj); err != nil {
 		h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to update PersistentVolume", err)
 		return
 	}
@@ -515,7 +515,7 @@ func (h *KubedeckReconciler) HandleDeletePersistentVolume(w http.ResponseWriter,
 			Namespace: ns,
 		},
 	}
-	if err := h.Client.Delete(ctx, obj); err != nil {
+	if err := h.clientFor(ctx).Delete(ctx, obj); err != nil {
 		h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to delete PersistentVolume", err)
 		return
 	}
@@ -538,7 +538,7 @@ func (h *KubedeckReconciler) HandleCreateNamespace(w http.ResponseWriter, req *h
 	// ns.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Namespace"))
 
 	webServerLog.Info("Attempting to create Namespace", "name", ns.Name)
-	if err := h.Client.Create(ctx, &ns); err != nil {
+	if err := h.clientFor(ctx).Create(ctx, &ns); err != nil {
 		h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to create Namespace", err)
 		return
 	}
@@ -568,7 +568,7 @@ func (h *KubedeckReconciler) HandleUpdateNamespace(w http.ResponseWriter, req *h
 	// updatedNs.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Namespace"))
 
 	webServerLog.Info("Attempting to update Namespace", "name", updatedNs.Name)
-	if err := h.Client.Update(ctx, &updatedNs); err != nil {
+	if err := h.clientFor(ctx).Update(ctx, &updatedNs); err != nil {
 		h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to update Namespace", err)
 		return
 	}
@@ -589,7 +589,7 @@ func (h *KubedeckReconciler) HandleDeleteNamespace(w http.ResponseWriter, req *h
 	// nsToDelete.SetGroupVersionKind(corev1.SchemeGroupVersion.WithKind("Namespace"))
 
 	webServerLog.Info("Attempting to delete Namespace", "name", nsName)
-	if err := h.Client.Delete(ctx, nsToDelete); err != nil {
+	if err := h.clientFor(ctx).Delete(ctx, nsToDelete); err != nil {
 		h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to delete Namespace", err)
 		return
 	}
@@ -616,7 +616,7 @@ func (h *KubedeckReconciler) HandleCreateDaemonSet(w http.ResponseWriter, req *h
 	obj.ResourceVersion = ""
 	obj.UID = ""
 
-	if err := h.Client.Create(ctx, &obj); err != nil {
+	if err := h.clientFor(ctx).Create(ctx, &obj); err != nil {
 		h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to create DaemonSet", err)
 		return
 	}
@@ -643,7 +643,7 @@ func (h *KubedeckReconciler) HandleUpdateDaemonSet(w http.ResponseWriter, r *htt
 
 	ctx := r.Context()
 	var ds appsv1.DaemonSet
-	if err := h.Client.Get(ctx, client.ObjectKey{Namespace: payload.Namespace, Name: payload.Resource.Name}, &ds); err != nil {
+	if err := h.clientFor(ctx).Get(ctx, client.ObjectKey{Namespace: payload.Namespace, Name: payload.Resource.Name}, &ds); err != nil {
 		h.writeErrorResponse(w, http.StatusNotFound, "DaemonSet not found", err)
 		return
 	}
@@ -660,7 +660,7 @@ func (h *KubedeckReconciler) HandleUpdateDaemonSet(w http.ResponseWriter, r *htt
 		}
 	}
 
-	if err := h.Client.Update(ctx, &ds); err != nil {
+	if err := h.clientFor(ctx).Update(ctx, &ds); err != nil {
 		h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to update DaemonSet", err)
 		return
 	}
@@ -682,7 +682,7 @@ func (h *KubedeckReconciler) HandleDeleteDaemonSet(w http.ResponseWriter, req *h
 			Namespace: ns,
 		},
 	}
-	if err := h.Client.Delete(ctx, obj); err != nil {
+	if err := h.clientFor(ctx).Delete(ctx, obj); err != nil {
 		h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to delete DaemonSet", err)
 		return
 	}
@@ -709,7 +709,7 @@ func (h *KubedeckReconciler) HandleCreateStatefulSet(w http.ResponseWriter, req
 	obj.ResourceVersion = ""
 	obj.UID = ""
 
-	if err := h.Client.Create(ctx, &obj); err != nil {
+	if err := h.clientFor(ctx).Create(ctx, &obj); err != nil {
 		h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to create StatefulSet", err)
 		return
 	}
@@ -737,7 +737,7 @@ func (h *KubedeckReconciler) HandleUpdateStatefulSet(w http.ResponseWriter, r *h
 
 	ctx := r.Context()
 	var sts appsv1.StatefulSet
-	if err := h.Client.Get(ctx, client.ObjectKey{Namespace: payload.Namespace, Name: payload.Resource.Name}, &sts); err != nil {
+	if err := h.clientFor(ctx).Get(ctx, client.ObjectKey{Namespace: payload.Namespace, Name: payload.Resource.Name}, &sts); err != nil {
 		h.writeErrorResponse(w, http.StatusNotFound, "StatefulSet not found", err)
 		return
 	}
@@ -755,7 +755,7 @@ func (h *KubedeckReconciler) HandleUpdateStatefulSet(w http.ResponseWriter, r *h
 	}
 	sts.Spec.Replicas = &payload.Resource.Replicas
 
-	if err := h.Client.Update(ctx, &sts); err != nil {
+	if err := h.clientFor(ctx).Update(ctx, &sts); err != nil {
 		h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to update StatefulSet", err)
 		return
 	}
@@ -777,7 +777,7 @@ func (h *KubedeckReconciler) HandleDeleteStatefulSet(w http.ResponseWriter, req
 			Namespace: ns,
 		},
 	}
-	if err := h.Client.Delete(ctx, obj); err != nil {
+	if err := h.clientFor(ctx).Delete(ctx, obj); err != nil {
 		h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to delete StatefulSet", err)
 		return
 	}
@@ -804,7 +804,7 @@ func (h *KubedeckReconciler) HandleCreatePersistentVolumeClaim(w http.ResponseWr
 	obj.ResourceVersion = ""
 	obj.UID = ""
 
-	if err := h.Client.Create(ctx, &obj); err != nil {
+	if err := h.clientFor(ctx).Create(ctx, &obj); err != nil {
 		h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to create PersistentVolumeClaim", err)
 		return
 	}
@@ -835,7 +835,7 @@ func (h *KubedeckReconciler) HandleUpdatePersistentVolumeClaim(w http.ResponseWr
 	obj.Namespace = ns
 	obj.Name = name
 
-	if err := h.Client.Update(ctx, &obj); err != nil {
+	if err := h.clientFor(ctx).Update(ctx, &obj); err != nil {
 		h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to update PersistentVolumeClaim", err)
 		return
 	}
@@ -857,7 +857,7 @@ func (h *KubedeckReconciler) HandleDeletePersistentVolumeClaim(w http.ResponseWr
 			Namespace: ns,
 		},
 	}
-	if err := h.Client.Delete(ctx, obj); err != nil {
+	if err := h.clientFor(ctx).Delete(ctx, obj); err != nil {
 		h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to delete PersistentVolumeClaim", err)
 		return
 	}
@@ -884,7 +884,7 @@ func (h *KubedeckReconciler) HandleCreateService(w http.ResponseWriter, req *htt
 	obj.ResourceVersion = ""
 	obj.UID = ""
 
-	if err := h.Client.Create(ctx, &obj); err != nil {
+	if err := h.clientFor(ctx).Create(ctx, &obj); err != nil {
 		h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to create Service", err)
 		return
 	}
@@ -915,7 +915,7 @@ func (h *KubedeckReconciler) HandleUpdateService(w http.ResponseWriter, req *htt
 	obj.Namespace = ns
 	obj.Name = name
 
-	if err := h.Client.Update(ctx, &obj); err != nil {
+	if err := h.clientFor(ctx).Update(ctx, &obj); err != nil {
 		h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to update Service", err)
 		return
 	}
@@ -937,7 +937,7 @@ func (h *KubedeckReconciler) HandleDeleteService(w http.ResponseWriter, req *htt
 			Namespace: ns,
 		},
 	}
-	if err := h.Client.Delete(ctx, obj); err != nil {
+	if err := h.clientFor(ctx).Delete(ctx, obj); err != nil {
 		h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to delete Service", err)
 		return
 	}
@@ -964,7 +964,7 @@ func (h *KubedeckReconciler) HandleCreateIngress(w http.ResponseWriter, req *htt
 	obj.ResourceVersion = ""
 	obj.UID = ""
 
-	if err := h.Client.Create(ctx, &obj); err != nil {
+	if err := h.clientFor(ctx).Create(ctx, &obj); err != nil {
 		h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to create Ingress", err)
 		return
 	}
@@ -995,7 +995,7 @@ func (h *KubedeckReconciler) HandleUpdateIngress(w http.ResponseWriter, req *htt
 	obj.Namespace = ns
 	obj.Name = name
 
-	if err := h.Client.Update(ctx, &obj); err != nil {
+	if err := h.clientFor(ctx).Update(ctx, &obj); err != nil {
 		h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to update Ingress", err)
 		return
 	}
@@ -1017,7 +1017,7 @@ func (h *KubedeckReconciler) HandleDeleteIngress(w http.ResponseWriter, req *htt
 			Namespace: ns,
 		},
 	}
-	if err := h.Client.Delete(ctx, obj); err != nil {
+	if err := h.clientFor(ctx).Delete(ctx, obj); err != nil {
 		h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to delete Ingress", err)
 		return
 	}
@@ -1044,7 +1044,7 @@ func (h *KubedeckReconciler) HandleCreateStorageClass(w http.ResponseWriter, req
 	obj.ResourceVersion = ""
 	obj.UID = ""
 
-	if err := h.Client.Create(ctx, &obj); err != nil {
+	if err := h.clientFor(ctx).Create(ctx, &obj); err != nil {
 		h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to create StorageClass", err)
 		return
 	}
@@ -1075,7 +1075,7 @@ func (h *KubedeckReconciler) HandleUpdateStorageClass(w http.ResponseWriter, req
 	obj.Namespace = ns
 	obj.Name = name
 
-	if err := h.Client.Update(ctx, &obj); err != nil {
+	if err := h.clientFor(ctx).Update(ctx, &obj); err != nil {
 		h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to update StorageClass", err)
 		return
 	}
@@ -1097,7 +1097,7 @@ func (h *KubedeckReconciler) HandleDeleteStorageClass(w http.ResponseWriter, req
 			Namespace: ns,
 		},
 	}
-	if err := h.Client.Delete(ctx, obj); err != nil {
+	if err := h.clientFor(ctx).Delete(ctx, obj); err != nil {
 		h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to delete StorageClass", err)
 		return
 	}
@@ -1127,7 +1127,7 @@ func (h *KubedeckReconciler) HandleUpdateNode(w http.ResponseWriter, req *http.R
 
 	node.Name = name
 
-	if err := h.Client.Update(ctx, &node); err != nil {
+	if err := h.clientFor(ctx).Update(ctx, &node); err != nil {
 		h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to update Node", err)
 		return
 	}
@@ -1146,7 +1146,7 @@ func (h *KubedeckReconciler) HandleDeleteNode(w http.ResponseWriter, req *http.R
 			Name: name,
 		},
 	}
-	if err := h.Client.Delete(ctx, node); err != nil {
+	if err := h.clientFor(ctx).Delete(ctx, node); err != nil {
 		h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to delete Node", err)
 		return
 	}
@@ -1173,7 +1173,7 @@ func (h *KubedeckReconciler) HandleCreateReplicaSet(w http.ResponseWriter, req *
 	rs.ResourceVersion = ""
 	rs.UID = ""
 
-	if err := h.Client.Create(ctx, &rs); err != nil {
+	if err := h.clientFor(ctx).Create(ctx, &rs); err != nil {
 		h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to create ReplicaSet", err)
 		return
 	}
@@ -1201,7 +1201,7 @@ func (h *KubedeckReconciler) HandleUpdateReplicaSet(w http.ResponseWriter, r *ht
 
 	ctx := r.Context()
 	var rs appsv1.ReplicaSet
-	if err := h.Client.Get(ctx, client.ObjectKey{Namespace: payload.Namespace, Name: payload.Resource.Name}, &rs); err != nil {
+	if err := h.clientFor(ctx).Get(ctx, client.ObjectKey{Namespace: payload.Namespace, Name: payload.Resource.Name}, &rs); err != nil {
 		h.writeErrorResponse(w, http.StatusNotFound, "ReplicaSet not found", err)
 		return
 	}
@@ -1218,7 +1218,7 @@ func (h *KubedeckReconciler) HandleUpdateReplicaSet(w http.ResponseWriter, r *ht
 	}
 	rs.Spec.Replicas = &payload.Resource.Replicas
 
-	if err := h.Client.Update(ctx, &rs); err != nil {
+	if err := h.clientFor(ctx).Update(ctx, &rs); err != nil {
 		h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to update ReplicaSet", err)
 		return
 	}
@@ -1240,7 +1240,7 @@ func (h *KubedeckReconciler) HandleDeleteReplicaSet(w http.ResponseWriter, req *
 			Namespace: ns,
 		},
 	}
-	if err := h.Client.Delete(ctx, rs); err != nil {
+	if err := h.clientFor(ctx).Delete(ctx, rs); err != nil {
 		h.writeErrorResponse(w, http.StatusInternalServerError, "Failed to delete ReplicaSet", err)
 		return
 	}
@@ -0,0 +1,294 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ResourceRollup - использование, запросы и лимиты группы подов
+type ResourceRollup struct {
+	Pods        int    `json:"pods"`
+	CPU         string `json:"cpu"`
+	Memory      string `json:"memory"`
+	CPURequests string `json:"cpuRequests"`
+	MemRequests string `json:"memRequests"`
+	CPULimits   string `json:"cpuLimits"`
+	MemLimits   string `json:"memLimits"`
+	// Использование относительно запросов, в процентах
+	CPURequestUtilization float64 `json:"cpuRequestUtilization"`
+	MemRequestUtilization float64 `json:"memRequestUtilization"`
+}
+
+// WorkloadMetrics - метрики контроллера подов: Deployment, StatefulSet, DaemonSet, Job.
+// Поды без контроллера представлены как Pod.
+type WorkloadMetrics struct {
+	Namespace string `json:"namespace"`
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	ResourceRollup
+}
+
+// NamespaceMetrics - метрики всех подов namespace
+type NamespaceMetrics struct {
+	Namespace string `json:"namespace"`
+	ResourceRollup
+}
+
+// rollupTotals накапливает ресурсы группы подов
+type rollupTotals struct {
+	pods                     int
+	cpu, memory              resource.Quantity
+	cpuRequests, memRequests resource.Quantity
+	cpuLimits, memLimits     resource.Quantity
+}
+
+func (t *rollupTotals) addPod(pod corev1.Pod, usage corev1.ResourceList) {
+	requests, limits := podResources(pod)
+	t.pods++
+	t.cpu.Add(*usage.Cpu())
+	t.memory.Add(*usage.Memory())
+	t.cpuRequests.Add(*requests.Cpu())
+	t.memRequests.Add(*requests.Memory())
+	t.cpuLimits.Add(*limits.Cpu())
+	t.memLimits.Add(*limits.Memory())
+}
+
+func (t *rollupTotals) rollup() ResourceRollup {
+	return ResourceRollup{
+		Pods:                  t.pods,
+		CPU:                   t.cpu.String(),
+		Memory:                t.memory.String(),
+		CPURequests:           t.cpuRequests.String(),
+		MemRequests:           t.memRequests.String(),
+		CPULimits:             t.cpuLimits.String(),
+		MemLimits:             t.memLimits.String(),
+		CPURequestUtilization: calculateUtilization(t.cpu, t.cpuRequests),
+		MemRequestUtilization: calculateUtilization(t.memory, t.memRequests),
+	}
+}
+
+// rollupSortKeys - значения параметра sort и соответствующие величины
+var rollupSortKeys = map[string]func(t *rollupTotals) resource.Quantity{
+	"cpu":         func(t *rollupTotals) resource.Quantity { return t.cpu },
+	"memory":      func(t *rollupTotals) resource.Quantity { return t.memory },
+	"cpuRequests": func(t *rollupTotals) resource.Quantity { return t.cpuRequests },
+	"memRequests": func(t *rollupTotals) resource.Quantity { return t.memRequests },
+	"cpuLimits":   func(t *rollupTotals) resource.Quantity { return t.cpuLimits },
+	"memLimits":   func(t *rollupTotals) resource.Quantity { return t.memLimits },
+	"pods": func(t *rollupTotals) resource.Quantity {
+		return *resource.NewQuantity(int64(t.pods), resource.DecimalSI)
+	},
+}
+
+// rollupGroup - группа подов с ключом сортировки по имени
+type rollupGroup struct {
+	name   string
+	totals *rollupTotals
+}
+
+// sortRollups сортирует группы по убыванию величины sort (по имени при равенстве) и оставляет первые limit.
+// limit 0 возвращает все группы.
+func sortRollups(groups []rollupGroup, sortBy string, limit int) []rollupGroup {
+	value := rollupSortKeys[sortBy]
+	sort.SliceStable(groups, func(i, j int) bool {
+		if value != nil {
+			a, b := value(groups[i].totals), value(groups[j].totals)
+			if cmp := a.Cmp(b); cmp != 0 {
+				return cmp > 0
+			}
+		}
+		return groups[i].name < groups[j].name
+	})
+	if limit > 0 && len(groups) > limit {
+		groups = groups[:limit]
+	}
+	return groups
+}
+
+// parseRollupParams разбирает параметры sort (по умолчанию cpu) и limit (по умолчанию все)
+func parseRollupParams(req *http.Request) (string, int, error) {
+	sortBy := req.URL.Query().Get("sort")
+	if sortBy == "" {
+		sortBy = "cpu"
+	}
+	if _, ok := rollupSortKeys[sortBy]; !ok && sortBy != "name" {
+		return "", 0, fmt.Errorf("sort must be one of cpu, memory, cpuRequests, memRequests, cpuLimits, memLimits, pods, name")
+	}
+
+	limit := 0
+	if value := req.URL.Query().Get("limit"); value != "" {
+		var err error
+		if limit, err = strconv.Atoi(value); err != nil || limit < 0 {
+			return "", 0, fmt.Errorf("limit must be a non-negative integer")
+		}
+	}
+	return sortBy, limit, nil
+}
+
+// workloadOf возвращает контроллер верхнего уровня пода. Поды ReplicaSet относятся к его Deployment,
+// если ReplicaSet нет в кеше - Deployment определяется по метке pod-template-hash.
+func workloadOf(pod corev1.Pod, replicaSets map[string]appsv1.ReplicaSet) (kind, name string) {
+	owner := metav1.GetControllerOf(&pod)
+	if owner == nil {
+		return "Pod", pod.Name
+	}
+	if owner.Kind != "ReplicaSet" {
+		return owner.Kind, owner.Name
+	}
+
+	if replicaSet, ok := replicaSets[pod.Namespace+"/"+owner.Name]; ok {
+		if rsOwner := metav1.GetControllerOf(&replicaSet); rsOwner != nil {
+			return rsOwner.Kind, rsOwner.Name
+		}
+		return owner.Kind, owner.Name
+	}
+	if hash := pod.Labels[appsv1.DefaultDeploymentUniqueLabelKey]; hash != "" && strings.HasSuffix(owner.Name, "-"+hash) {
+		return "Deployment", strings.TrimSuffix(owner.Name, "-"+hash)
+	}
+	return owner.Kind, owner.Name
+}
+
+// replicaSetsByName возвращает ReplicaSet namespace ("" - всех) по ключу namespace/name
+func (r *KubedeckReconciler) replicaSetsByName(ctx context.Context, namespace string) (map[string]appsv1.ReplicaSet, error) {
+	replicaSetList := &appsv1.ReplicaSetList{}
+	if err := r.clientFor(ctx).List(ctx, replicaSetList, client.InNamespace(namespace)); err != nil {
+		return nil, fmt.Errorf("failed to list replicasets: %w", err)
+	}
+	replicaSets := make(map[string]appsv1.ReplicaSet, len(replicaSetList.Items))
+	for _, replicaSet := range replicaSetList.Items {
+		replicaSets[replicaSet.Namespace+"/"+replicaSet.Name] = replicaSet
+	}
+	return replicaSets, nil
+}
+
+// podUsageData возвращает работающие поды namespace ("" - всех) и их использование по ключу namespace/name
+func (r *KubedeckReconciler) podUsageData(ctx context.Context, namespace string) ([]corev1.Pod, map[string]corev1.ResourceList, error) {
+	allPods, err := r.podsSnapshot(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	snapshot, err := r.metricsSnapshot(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var pods []corev1.Pod
+	for _, pod := range podsInNamespace(allPods, namespace) {
+		if !isPodFinished(pod) {
+			pods = append(pods, pod)
+		}
+	}
+	return pods, snapshot.podUsage(), nil
+}
+
+// workloadMetrics суммирует поды по контроллерам
+func workloadMetrics(pods []corev1.Pod, usage map[string]corev1.ResourceList, replicaSets map[string]appsv1.ReplicaSet,
+	kind, sortBy string, limit int) []WorkloadMetrics {
+	type workload struct{ namespace, kind, name string }
+	totals := map[workload]*rollupTotals{}
+	for _, pod := range pods {
+		workloadKind, workloadName := workloadOf(pod, replicaSets)
+		if kind != "" && !strings.EqualFold(kind, workloadKind) {
+			continue
+		}
+		key := workload{pod.Namespace, workloadKind, workloadName}
+		if totals[key] == nil {
+			totals[key] = &rollupTotals{}
+		}
+		totals[key].addPod(pod, usage[pod.Namespace+"/"+pod.Name])
+	}
+
+	groups := make([]rollupGroup, 0, len(totals))
+	keys := make(map[string]workload, len(totals))
+	for key, total := range totals {
+		name := key.namespace + "/" + key.kind + "/" + key.name
+		groups = append(groups, rollupGroup{name: name, totals: total})
+		keys[name] = key
+	}
+
+	result := make([]WorkloadMetrics, 0, len(groups))
+	for _, group := range sortRollups(groups, sortBy, limit) {
+		key := keys[group.name]
+		result = append(result, WorkloadMetrics{
+			Namespace:      key.namespace,
+			Kind:           key.kind,
+			Name:           key.name,
+			ResourceRollup: group.totals.rollup(),
+		})
+	}
+	return result
+}
+
+// namespaceMetrics суммирует поды по namespace
+func namespaceMetrics(pods []corev1.Pod, usage map[string]corev1.ResourceList, sortBy string, limit int) []NamespaceMetrics {
+	totals := map[string]*rollupTotals{}
+	for _, pod := range pods {
+		if totals[pod.Namespace] == nil {
+			totals[pod.Namespace] = &rollupTotals{}
+		}
+		totals[pod.Namespace].addPod(pod, usage[pod.Namespace+"/"+pod.Name])
+	}
+
+	groups := make([]rollupGroup, 0, len(totals))
+	for namespace, total := range totals {
+		groups = append(groups, rollupGroup{name: namespace, totals: total})
+	}
+
+	result := make([]NamespaceMetrics, 0, len(groups))
+	for _, group := range sortRollups(groups, sortBy, limit) {
+		result = append(result, NamespaceMetrics{Namespace: group.name, ResourceRollup: group.totals.rollup()})
+	}
+	return result
+}
+
+// handleWorkloadMetricsRequest обрабатывает GET /metrics/workloads?namespace=&kind=&sort=&limit=
+func (r *KubedeckReconciler) handleWorkloadMetricsRequest(w http.ResponseWriter, req *http.Request) {
+	ctx := req.Context()
+	namespace := req.URL.Query().Get("namespace")
+	sortBy, limit, err := parseRollupParams(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	pods, usage, err := r.podUsageData(ctx, namespace)
+	if err != nil {
+		writeJsonResponse(w, nil, "workload metrics", err)
+		return
+	}
+
+	replicaSets, err := r.replicaSetsByName(ctx, namespace)
+	if err != nil {
+		writeJsonResponse(w, nil, "replicasets", err)
+		return
+	}
+
+	writeJsonResponse(w, workloadMetrics(pods, usage, replicaSets, req.URL.Query().Get("kind"), sortBy, limit),
+		"workload metrics", nil)
+}
+
+// handleNamespaceMetricsRequest обрабатывает GET /metrics/namespaces?sort=&limit=
+func (r *KubedeckReconciler) handleNamespaceMetricsRequest(w http.ResponseWriter, req *http.Request) {
+	sortBy, limit, err := parseRollupParams(req)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	pods, usage, err := r.podUsageData(req.Context(), corev1.NamespaceAll)
+	if err != nil {
+		writeJsonResponse(w, nil, "namespace metrics", err)
+		return
+	}
+	writeJsonResponse(w, namespaceMetrics(pods, usage, sortBy, limit), "namespace metrics", nil)
+}
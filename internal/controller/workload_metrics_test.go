@@ -0,0 +1,103 @@
+package controller
+
+import (
+	"net/http"
+	"net/http/httptest"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+var _ = Describe("Workload metrics", func() {
+	controller := func(kind, name string) []metav1.OwnerReference {
+		isController := true
+		return []metav1.OwnerReference{{Kind: kind, Name: name, Controller: &isController}}
+	}
+	pod := func(namespace, name, cpu string, owners []metav1.OwnerReference) corev1.Pod {
+		return corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Namespace: namespace, Name: name, OwnerReferences: owners},
+			Spec: corev1.PodSpec{Containers: []corev1.Container{{Name: "app", Resources: corev1.ResourceRequirements{
+				Requests: corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(cpu)},
+			}}}},
+		}
+	}
+	usage := func(cpu string) corev1.ResourceList {
+		return corev1.ResourceList{corev1.ResourceCPU: resource.MustParse(cpu)}
+	}
+
+	It("should resolve owner chains to top-level workloads", func() {
+		replicaSets := map[string]appsv1.ReplicaSet{
+			"default/web-5d8f": {ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web-5d8f",
+				OwnerReferences: controller("Deployment", "web")}},
+		}
+		orphan := pod("default", "api-7c9d-x", "100m", controller("ReplicaSet", "api-7c9d"))
+		orphan.Labels = map[string]string{appsv1.DefaultDeploymentUniqueLabelKey: "7c9d"}
+
+		for _, tc := range []struct {
+			pod        corev1.Pod
+			kind, name string
+		}{
+			{pod("default", "web-5d8f-a", "1", controller("ReplicaSet", "web-5d8f")), "Deployment", "web"},
+			{orphan, "Deployment", "api"},
+			{pod("default", "db-0", "1", controller("StatefulSet", "db")), "StatefulSet", "db"},
+			{pod("default", "bare", "1", nil), "Pod", "bare"},
+		} {
+			kind, name := workloadOf(tc.pod, replicaSets)
+			Expect(kind).To(Equal(tc.kind), tc.pod.Name)
+			Expect(name).To(Equal(tc.name), tc.pod.Name)
+		}
+	})
+
+	It("should roll up pods by workload and namespace with sort and limit", func() {
+		replicaSets := map[string]appsv1.ReplicaSet{
+			"default/web-1": {ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "web-1",
+				OwnerReferences: controller("Deployment", "web")}},
+		}
+		pods := []corev1.Pod{
+			pod("default", "web-1-a", "500m", controller("ReplicaSet", "web-1")),
+			pod("default", "web-1-b", "500m", controller("ReplicaSet", "web-1")),
+			pod("default", "db-0", "2", controller("StatefulSet", "db")),
+			pod("batch", "report-x", "100m", controller("Job", "report")),
+		}
+		podUsage := map[string]corev1.ResourceList{
+			"default/web-1-a": usage("300m"), "default/web-1-b": usage("200m"),
+			"default/db-0": usage("100m"), "batch/report-x": usage("50m"),
+		}
+
+		workloads := workloadMetrics(pods, podUsage, replicaSets, "", "cpu", 2)
+		Expect(workloads).To(HaveLen(2))
+		Expect(workloads[0].Kind).To(Equal("Deployment"))
+		Expect(workloads[0].Pods).To(Equal(2))
+		Expect(workloads[0].CPU).To(Equal("500m"))
+		Expect(workloads[0].CPURequests).To(Equal("1"))
+		Expect(workloads[0].CPURequestUtilization).To(BeNumerically("~", 50, 1e-9))
+		Expect(workloads[1].Name).To(Equal("db"))
+
+		byRequests := workloadMetrics(pods, podUsage, replicaSets, "", "cpuRequests", 0)
+		Expect(byRequests).To(HaveLen(3))
+		Expect(byRequests[0].Name).To(Equal("db"))
+
+		jobs := workloadMetrics(pods, podUsage, replicaSets, "job", "cpu", 0)
+		Expect(jobs).To(HaveLen(1))
+		Expect(jobs[0].Namespace).To(Equal("batch"))
+
+		namespaces := namespaceMetrics(pods, podUsage, "cpuRequests", 0)
+		Expect(namespaces).To(HaveLen(2))
+		Expect(namespaces[0].Namespace).To(Equal("default"))
+		Expect(namespaces[0].CPURequests).To(Equal("3"))
+		Expect(namespaces[0].CPU).To(Equal("600m"))
+	})
+
+	It("should reject unknown sort keys and invalid limits", func() {
+		reconciler := &KubedeckReconciler{}
+		for _, query := range []string{"sort=latency", "limit=-1", "limit=ten"} {
+			recorder := httptest.NewRecorder()
+			reconciler.handleNamespaceMetricsRequest(recorder, httptest.NewRequest(http.MethodGet, "/metrics/namespaces?"+query, nil))
+			Expect(recorder.Code).To(Equal(http.StatusBadRequest), query)
+		}
+	})
+})